@@ -11,13 +11,15 @@ import (
 )
 
 type AppConfig struct {
-	SystemDetails   SystemDetails
-	PlatformDetail  PlatformDetail
-	ImporterConfig  importer.Config
-	LogsFolder      string
-	TimingsLogFile  string
-	ChecksumLogFile string
-	DryRun          bool
+	SystemDetails    SystemDetails
+	PlatformDetail   PlatformDetail
+	ImporterConfig   importer.Config
+	LogsFolder       string
+	QuarantineFolder string
+	InvalidSources   []string
+	TimingsLogFile   string
+	ChecksumLogFile  string
+	DryRun           bool
 }
 
 type PlatformDetail struct {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/rockwell-uk/go-logger/logger"
 	"github.com/rockwell-uk/go-utils/fileutils"
+	"github.com/rockwell-uk/go-utils/osutils"
 	"github.com/rockwell-uk/go-utils/timeutils"
 
 	"go-uk-maps-import/database/engine"
@@ -193,7 +194,7 @@ func checkConfig(appConfig *AppConfig) (ConfigCheckResults, error) {
 		}
 
 		if !pgSQLDetails.ClientInstalled {
-			results.Warnings = append(results.Warnings, "The MySQL client does not appear to be available on this system")
+			results.Warnings = append(results.Warnings, "The PgSQL client does not appear to be available on this system")
 		}
 
 	case engine.EngineSQLite:
@@ -247,22 +248,63 @@ func checkConfig(appConfig *AppConfig) (ConfigCheckResults, error) {
 		results.Errors = append(results.Errors, "Datafolder Does Not Exist")
 	}
 
-	// Shapefiles Exist?
+	// Shapefiles or GeoPackages Exist?
 	var shapeFiles []string
 	shapeFiles, err = fileutils.Find(importerConfig.DataFolder, ".shp")
 	if err != nil {
 		return ConfigCheckResults{}, fmt.Errorf("%v: %v", funcName, err.Error())
 	}
-	if len(shapeFiles) == 0 {
-		results.Warnings = append(results.Warnings, "No shapefiles exist in the datafolder")
+	var geoPackages []string
+	geoPackages, err = fileutils.Find(importerConfig.DataFolder, ".gpkg")
+	if err != nil {
+		return ConfigCheckResults{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	if len(shapeFiles) == 0 && len(geoPackages) == 0 {
+		results.Warnings = append(results.Warnings, "No shapefiles or geopackages exist in the datafolder")
+	}
+
+	// Validate the sources, any that cannot be imported are left out and
+	// quarantined when the import runs
+	if len(importerConfig.ShapeFiles) > 0 {
+		valid, problems := importer.ValidateSources(importerConfig.ShapeFiles, importerConfig.Mapping)
+
+		for _, problem := range problems {
+			if problem.IsValid() {
+				results.Warnings = append(results.Warnings, fmt.Sprintf("Source has warnings %v", problem))
+				continue
+			}
+
+			appConfig.InvalidSources = append(appConfig.InvalidSources, problem.Source)
+
+			results.Warnings = append(results.Warnings, fmt.Sprintf("Source is invalid and will be quarantined %v", problem))
+		}
+
+		if len(valid) == 0 {
+			results.Errors = append(results.Errors, "No valid shapefiles or geopackages to import")
+		}
+
+		appConfig.ImporterConfig.ShapeFiles = valid
+		appConfig.ImporterConfig.NumShapeFiles = len(valid)
 	}
 
 	// If Usefiles is Selected
 	if importerConfig.UseFiles {
-		// If Not Skipping Inserts MySQL Client Must Be Available
-		if !importerConfig.SkipInserts && !mySQLDetails.ClientInstalled {
-			results.Errors = append(results.Errors, "UseFiles Option Will Fail If Not Skipping Inserts and MySQL Client Is Not Available")
+		// Files For Another Database Can Only Be Generated
+		err := importer.CheckSQLDialect(importerConfig)
+		if err != nil {
+			results.Errors = append(results.Errors, err.Error())
 		}
+
+		// If Not Skipping Inserts The Client Of The Dialect Must Be Available
+		var client string = engine.LoaderCommands[importer.GetSQLDialect(importerConfig)]
+		if !importerConfig.SkipInserts && !osutils.CommandExists(client) {
+			results.Errors = append(results.Errors, fmt.Sprintf("UseFiles Option Will Fail If Not Skipping Inserts and the %v Client Is Not Available", client))
+		}
+	}
+
+	// FlatGeobuf files are exported from the SQLite databases
+	if importerConfig.FlatGeobuf && *importerConfig.DB.Engine != engine.EngineSQLite {
+		results.Errors = append(results.Errors, "FlatGeobuf Option Needs The SQLite Engine")
 	}
 
 	return results, nil
@@ -51,6 +51,7 @@ func GetMySQLDetails(config importer.Config) MySQLDetails {
 	if driverInstalled && clientInstalled && canConnect {
 		config.DB.StorageEngine = &mysql.MySQL{
 			Config: cfg,
+			Schema: config.DB.Schema,
 		}
 
 		db, err := connectMySQL(cfg)
@@ -51,6 +51,7 @@ func GetPgSQLDetails(config importer.Config) PgSQLDetails {
 	if driverInstalled && clientInstalled && canConnect {
 		config.DB.StorageEngine = &pgsql.PgSQL{
 			Config: cfg,
+			Schema: config.DB.Schema,
 		}
 
 		db, err := connectPgSQL(cfg)
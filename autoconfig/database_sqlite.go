@@ -62,6 +62,7 @@ func GetSQLiteDetails(config importer.Config) SQLiteDetails {
 	if driverInstalled && spatialiteInstalled && folderWriteable && canConnect {
 		se := &sqlite.SQLite{
 			Config: cfg,
+			Schema: config.DB.Schema,
 		}
 
 		config.DB.StorageEngine = se
@@ -0,0 +1,44 @@
+package autoconfig
+
+import (
+	"fmt"
+
+	"go-uk-maps-import/database/engine"
+	"go-uk-maps-import/importer"
+)
+
+// EstimateImport predicts the cost of the configured import, layers that
+// have not been imported before are assumed to run at the benchmarked
+// insert rate of the storage engine
+func EstimateImport(appConfig *AppConfig) (importer.Estimate, error) {
+	var funcName string = "autoconfig.EstimateImport"
+
+	estimate, err := importer.EstimateImport(appConfig.ImporterConfig, getBenchmarkRate(appConfig.PlatformDetail))
+	if err != nil {
+		return importer.Estimate{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return estimate, nil
+}
+
+// getBenchmarkRate is the rows per second of the storage engine benchmark,
+// zero if it has not been run
+func getBenchmarkRate(platformDetail PlatformDetail) float64 {
+	var rows int
+	var seconds float64
+
+	switch platformDetail.RequestedEngine {
+	case engine.EngineMySQL:
+		rows, seconds = mysqlBenchNumRows, platformDetail.MySQLDetails.BenchmarkResult.Duration
+	case engine.EnginePostgres:
+		rows, seconds = pgsqlBenchNumRows, platformDetail.PgSQLDetails.BenchmarkResult.Duration
+	case engine.EngineSQLite:
+		rows, seconds = sqliteBenchNumRows, platformDetail.SQLiteDetails.BenchmarkResult.Duration
+	}
+
+	if seconds <= 0 {
+		return 0
+	}
+
+	return float64(rows) / seconds
+}
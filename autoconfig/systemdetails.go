@@ -115,7 +115,7 @@ func doDiskBench(systemDetails *SystemDetails) {
 	var took time.Duration
 	var absPath string
 
-	absPath, _ = filepath.Abs(sqlwriter.Folder)
+	absPath, _ = filepath.Abs(sqlwriter.DefaultFolder)
 	logger.Log(
 		logger.LVL_APP,
 		fmt.Sprintf("%v [path: %v]\n", taskName, absPath),
@@ -123,7 +123,7 @@ func doDiskBench(systemDetails *SystemDetails) {
 
 	benchResult, _ := diskbench.BenchDisk(
 		diskbench.DiskBench{
-			Folder:  sqlwriter.Folder,
+			Folder:  sqlwriter.DefaultFolder,
 			Seconds: 5,
 		},
 	)
@@ -148,6 +148,7 @@ func doStorageBench(config importer.Config, platformDetails *PlatformDetail) {
 
 		config.DB.StorageEngine = &mysql.MySQL{
 			Config: cfg,
+			Schema: config.DB.Schema,
 		}
 
 		db, err := connectMySQL(cfg)
@@ -169,6 +170,7 @@ func doStorageBench(config importer.Config, platformDetails *PlatformDetail) {
 
 		config.DB.StorageEngine = &pgsql.PgSQL{
 			Config: cfg,
+			Schema: config.DB.Schema,
 		}
 
 		db, err := connectPgSQL(cfg)
@@ -190,6 +192,7 @@ func doStorageBench(config importer.Config, platformDetails *PlatformDetail) {
 
 		se := &sqlite.SQLite{
 			Config: cfg,
+			Schema: config.DB.Schema,
 		}
 
 		config.DB.StorageEngine = se
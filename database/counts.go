@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/rockwell-uk/go-logger/logger"
@@ -12,6 +13,7 @@ import (
 
 	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/sqlwriter"
 )
 
 type TablesInfo map[string]int
@@ -46,7 +48,7 @@ func GetTableCounts(s engine.StorageEngine) (TableCountsResult, error) {
 
 	var start time.Time = time.Now()
 	var took time.Duration
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(s.GetSchema().MapLayers)
 
 	logger.Log(
 		logger.LVL_APP,
@@ -56,7 +58,7 @@ func GetTableCounts(s engine.StorageEngine) (TableCountsResult, error) {
 	// Table Counts Job
 	var j progress.ProgressJob = &TableCountsJob{}
 
-	job, err := j.Setup(jobName, s)
+	job, err := j.Setup(jobName, s.GetSchema())
 	if err != nil {
 		return TableCountsResult{}, fmt.Errorf("%v: %v", funcName, err.Error())
 	}
@@ -87,6 +89,17 @@ func GetGeneratedSQLFiles(folder string) ([]string, error) {
 		return []string{}, err
 	}
 
+	// Compressed files
+	gzFiles, err := fileutils.Find(folder, sqlwriter.CompressedExt)
+	if err != nil {
+		return []string{}, err
+	}
+	for _, gzFile := range gzFiles {
+		if strings.HasSuffix(gzFile, ".sql"+sqlwriter.CompressedExt) {
+			sqlFiles = append(sqlFiles, gzFile)
+		}
+	}
+
 	logger.Log(
 		logger.LVL_DEBUG,
 		fmt.Sprintf("%v SQL files generated\n", len(sqlFiles)),
@@ -94,3 +107,19 @@ func GetGeneratedSQLFiles(folder string) ([]string, error) {
 
 	return sqlFiles, nil
 }
+
+// ClearSquares empties the tables of the given squares for every OS layer
+func ClearSquares(s engine.StorageEngine, squares []string) error {
+	var funcName string = "database.ClearSquares"
+	var jobName string = "Clearing database squares"
+
+	var schema *types.Schema = s.GetSchema()
+	var magnitude int = (len(schema.MapLayers) - len(schema.CustomLayers)) * len(squares)
+
+	// Clear Squares Job
+	var job progress.ProgressJob = &ClearSquaresJob{
+		Squares: squares,
+	}
+
+	return progress.RunJob(jobName, funcName, job, magnitude, schema, s)
+}
@@ -29,7 +29,7 @@ func (j *DoInsertsJob) Setup(jobName string, input interface{}) (*progress.Job,
 }
 
 func (j *DoInsertsJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
-	if dbConfig, ok := input.(DBConfig); ok {
+	if loader, ok := input.(Loader); ok {
 		// Do the work
 		for _, task := range job.Tasks {
 			task.Start()
@@ -46,7 +46,7 @@ func (j *DoInsertsJob) Run(job *progress.Job, input interface{}) (interface{}, e
 				fmt.Sprintf("Processing %v [%v]\n", sqlFile, fileSizeMb),
 			)
 
-			stdout, stderr, err := RunSQLFile(dbConfig, sqlFile)
+			stdout, stderr, err := loader.Load(sqlFile)
 			if err != nil {
 				return struct{}{}, fmt.Errorf("%v: stdout [%v], stderr [%v]", err.Error(), stdout, stderr)
 			}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-utils/osutils"
+
+	"go-uk-maps-import/database/engine/pgsql"
+	"go-uk-maps-import/database/engine/sqlite"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/sqlwriter"
+)
+
+// Loader loads the .sql files written in the dialect of a database with its
+// command line client, so the files can be loaded anywhere the client is,
+// compressed files are decompressed as they are streamed to the client
+type Loader interface {
+	Load(sqlFile string) (string, string, error)
+}
+
+// The command line client of the loader of each dialect
+var LoaderCommands = map[string]string{
+	types.DialectMySQL:    "mysql",
+	types.DialectPostgres: "psql",
+	types.DialectSQLite:   "sqlite3",
+}
+
+// GetLoader returns the loader of a dialect
+//
+//nolint:ireturn,nolintlint
+func GetLoader(dialect string, config DBConfig) (Loader, error) {
+	switch dialect {
+	case types.DialectMySQL:
+		return MySQLLoader{config}, nil
+	case types.DialectPostgres:
+		return PgSQLLoader{config}, nil
+	case types.DialectSQLite:
+		return SQLiteLoader{sqlite.SQLiteStorageFolder}, nil
+	}
+
+	return nil, fmt.Errorf("no loader for SQL dialect %v", dialect)
+}
+
+// MySQLLoader runs the files with mysql
+type MySQLLoader struct {
+	Config DBConfig
+}
+
+func (l MySQLLoader) Load(sqlFile string) (string, string, error) {
+	if !sqlwriter.IsCompressed(sqlFile) {
+		return RunSQLFile(l.Config, sqlFile)
+	}
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Running command: mysql < %v", sqlFile),
+	)
+
+	return runLoader(sqlFile, LoaderCommands[types.DialectMySQL], mysqlArgs(l.Config))
+}
+
+// PgSQLLoader runs the files with psql, stopping at the first error
+type PgSQLLoader struct {
+	Config DBConfig
+}
+
+func (l PgSQLLoader) Load(sqlFile string) (string, string, error) {
+	command := LoaderCommands[types.DialectPostgres]
+
+	cfg := pgsql.PgSQLConfig{
+		Host:    deref(l.Config.Host),
+		Port:    deref(l.Config.Port),
+		User:    deref(l.Config.User),
+		Pass:    deref(l.Config.Pass),
+		Schema:  deref(l.Config.Schema),
+		Timeout: 10,
+	}
+
+	args := []string{
+		"-q",
+		"-v",
+		"ON_ERROR_STOP=1",
+		"-d",
+		cfg.DSN(),
+	}
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Running command: psql -f %v", sqlFile),
+	)
+
+	return runLoader(sqlFile, command, args, "-f", sqlFile)
+}
+
+// SQLiteLoader runs the files with sqlite3 against the database file of
+// their layer, which is the folder the file is in
+type SQLiteLoader struct {
+	Folder string
+}
+
+func (l SQLiteLoader) Load(sqlFile string) (string, string, error) {
+	command := LoaderCommands[types.DialectSQLite]
+
+	layerType := filepath.Base(filepath.Dir(sqlFile))
+
+	args := []string{
+		"-bail",
+		"-cmd",
+		".load mod_spatialite",
+		filepath.Join(l.Folder, layerType+".db"),
+	}
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Running command: %s %v", exec.Command(command, args...), sqlFile),
+	)
+
+	return runLoader(sqlFile, command, args, fmt.Sprintf(".read %v", sqlFile))
+}
+
+// runLoader runs a client with the file arguments that have it read the file,
+// a compressed file is instead decompressed as it is streamed to the stdin of
+// the client so it is never written out uncompressed
+func runLoader(sqlFile, command string, args []string, fileArgs ...string) (string, string, error) {
+	if !sqlwriter.IsCompressed(sqlFile) {
+		return osutils.RunCommandSilent(command, append(args, fileArgs...)...)
+	}
+
+	r, err := sqlwriter.OpenSQLFile(sqlFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(command, args...)
+
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+
+	return stdout.String(), stderr.String(), err
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
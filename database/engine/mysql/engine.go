@@ -54,6 +54,7 @@ func (c MySQLConfig) DSN() string {
 
 type MySQL struct {
 	Config MySQLConfig
+	Schema *types.Schema
 	DB     *sqlx.DB
 }
 
@@ -95,12 +96,12 @@ func (e MySQL) Cleardown() error {
 	var funcName string = "mysql.Cleardown"
 	var jobName string = "Cleardown MySQL Database"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Cleardown Job
 	var job progress.ProgressJob = &ClearDownJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e MySQL) Prepare() error {
@@ -111,6 +112,13 @@ func (e MySQL) Prepare() error {
 		return fmt.Errorf("%v %v", funcName, err.Error())
 	}
 
+	if e.Schema.HasReferenceTables() {
+		err = e.CreateReferenceTables()
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
 	err = e.CreateTables()
 	if err != nil {
 		return fmt.Errorf("%v %v", funcName, err.Error())
@@ -123,30 +131,35 @@ func (e MySQL) CreateDatabases() error {
 	var funcName string = "mysql.CreateDatabases"
 	var jobName string = "Creating MySQL Databases"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Create Databases Job
 	var job progress.ProgressJob = &CreateDatabasesJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e MySQL) CreateTables() error {
 	var funcName string = "mysql.CreateTables"
 	var jobName string = "Creating MySQL Tables"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Create Tables Job
 	var job progress.ProgressJob = &CreateTablesJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e MySQL) GetDB(layerType string) *sqlx.DB {
 	return e.DB
 }
 
+// GetSchema returns the layer definitions the tables are created from
+func (e MySQL) GetSchema() *types.Schema {
+	return e.Schema
+}
+
 func (e MySQL) Stop() error {
 	e.DB.Close()
 	return nil
@@ -160,14 +173,21 @@ func (e MySQL) GetTableSQL(fullTableName, tableParams string, fields []string) (
 	tableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", fullTableName)
 
 	for _, f := range fields {
-		if _, ok := types.FieldTypes[f]; !ok {
+		fieldType, ok := e.Schema.GetFieldType(f, types.DialectMySQL)
+		if !ok {
 			return "", fmt.Errorf("unknown field type (%v) %v", fullTableName, f)
 		}
 
-		tableSQL += fmt.Sprintf("`%s` %s,", f, types.FieldTypes[f])
+		tableSQL += fmt.Sprintf("`%s` %s,", f, fieldType)
+	}
+
+	tableSQL += "`ogc_geom` geometry DEFAULT NULL, PRIMARY KEY (`ID`, `GRIDREF`)"
+
+	if e.Schema.HasForeignKey(fields) {
+		tableSQL += fmt.Sprintf(", FOREIGN KEY (`FEATCODE`) REFERENCES `%s`.`%s` (`code`)", types.ReferenceSchema, types.FeatureCodeTable)
 	}
 
-	tableSQL += fmt.Sprintf("`ogc_geom` geometry DEFAULT NULL, PRIMARY KEY (`ID`, `GRIDREF`))%v;", tableParams)
+	tableSQL += fmt.Sprintf(")%v;", tableParams)
 
 	return tableSQL, nil
 }
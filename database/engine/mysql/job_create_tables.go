@@ -6,7 +6,6 @@ import (
 
 	"github.com/rockwell-uk/csync/waitgroup"
 	"github.com/rockwell-uk/go-logger/logger"
-	"github.com/rockwell-uk/go-nationalgrid"
 	"github.com/rockwell-uk/go-progress/progress"
 
 	"go-uk-maps-import/database/types"
@@ -15,9 +14,14 @@ import (
 type CreateTablesJob struct{}
 
 func (j *CreateTablesJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
 	var tasks []*progress.Task
-	for layerType := range types.MapLayers {
-		for square := range nationalgrid.NationalGridSquares {
+	for layerType := range schema.MapLayers {
+		for _, square := range schema.LayerSquares(layerType) {
 			tasks = append(tasks, &progress.Task{
 				ID:        fmt.Sprintf("%v_%v", layerType, square),
 				Magnitude: 1,
@@ -34,10 +38,10 @@ func (j *CreateTablesJob) Run(job *progress.Job, input interface{}) (interface{}
 	if e, ok := input.(MySQL); ok {
 		var wg *waitgroup.WaitGroup = waitgroup.New()
 
-		for _, layerType := range types.MapLayers.Ordered() {
-			fields := types.MapLayers[layerType]
+		for _, layerType := range e.Schema.MapLayers.Ordered() {
+			fields := e.Schema.MapLayers[layerType]
 
-			for square := range nationalgrid.NationalGridSquares {
+			for _, square := range e.Schema.LayerSquares(layerType) {
 				wg.Add(1)
 
 				c := make(chan error)
@@ -48,7 +52,7 @@ func (j *CreateTablesJob) Run(job *progress.Job, input interface{}) (interface{}
 					tableName := strings.ToLower(sq)
 					fullTableName := e.GetTableName(fmt.Sprintf("%v.%v", lt, tableName))
 
-					tableSQL, err := e.GetTableSQL(fullTableName, MySQLTableParams, f)
+					tableSQL, err := e.GetTableSQL(fullTableName, getTableParams(e.Schema), f)
 					if err != nil {
 						c <- err
 					}
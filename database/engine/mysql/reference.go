@@ -0,0 +1,73 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/rockwell-uk/go-logger/logger"
+
+	"go-uk-maps-import/database/types"
+)
+
+const (
+	// Foreign keys need InnoDB, MyISAM ignores them
+	MySQLForeignKeyTableParams string = " ENGINE=InnoDB DEFAULT CHARSET=utf8"
+)
+
+// CreateReferenceTables creates and fills the feature code and
+// classification lookup tables in the reference database
+func (e MySQL) CreateReferenceTables() error {
+	var funcName string = "mysql.CreateReferenceTables"
+
+	var statements = []string{
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", types.ReferenceSchema),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`.`%s` (`code` int NOT NULL, `description` varchar(255) NOT NULL, `layer` varchar(64) NOT NULL, `colour` varchar(7) DEFAULT NULL, `z_order` int DEFAULT NULL, PRIMARY KEY (`code`))%v;",
+			types.ReferenceSchema, types.FeatureCodeTable, MySQLForeignKeyTableParams),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`.`%s` (`layer` varchar(64) NOT NULL, `name` varchar(255) NOT NULL, `colour` varchar(7) DEFAULT NULL, `z_order` int DEFAULT NULL, PRIMARY KEY (`layer`, `name`))%v;",
+			types.ReferenceSchema, types.ClassificationTable, MySQLForeignKeyTableParams),
+	}
+
+	for _, statement := range statements {
+		logger.Log(
+			logger.LVL_DEBUG,
+			fmt.Sprintf("%+v\n", statement),
+		)
+
+		_, err := e.DB.Exec(statement)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	// Rows are updated in place as they may be referenced
+	var featureCodeSQL string = fmt.Sprintf("INSERT INTO `%s`.`%s` (`code`, `description`, `layer`, `colour`, `z_order`) VALUES (?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE `description` = VALUES(`description`), `layer` = VALUES(`layer`), `colour` = VALUES(`colour`), `z_order` = VALUES(`z_order`)",
+		types.ReferenceSchema, types.FeatureCodeTable)
+
+	for _, f := range types.FeatureCodes {
+		_, err := e.DB.Exec(featureCodeSQL, f.Code, f.Description, f.Layer, f.Colour, f.ZOrder)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	var classificationSQL string = fmt.Sprintf("INSERT INTO `%s`.`%s` (`layer`, `name`, `colour`, `z_order`) VALUES (?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE `colour` = VALUES(`colour`), `z_order` = VALUES(`z_order`)",
+		types.ReferenceSchema, types.ClassificationTable)
+
+	for _, c := range types.Classifications {
+		_, err := e.DB.Exec(classificationSQL, c.Layer, c.Name, c.Colour, c.ZOrder)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func getTableParams(schema *types.Schema) string {
+	if schema.ForeignKeys {
+		return MySQLForeignKeyTableParams
+	}
+
+	return MySQLTableParams
+}
@@ -56,6 +56,7 @@ func (c PgSQLConfig) DSN() string {
 
 type PgSQL struct {
 	Config PgSQLConfig
+	Schema *types.Schema
 	DB     *sqlx.DB
 }
 
@@ -97,12 +98,12 @@ func (e PgSQL) Cleardown() error {
 	var funcName string = "pgsql.Cleardown"
 	var jobName string = "Cleardown PgSQL Database"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Cleardown Job
 	var job progress.ProgressJob = &ClearDownJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e PgSQL) Prepare() error {
@@ -113,6 +114,13 @@ func (e PgSQL) Prepare() error {
 		return fmt.Errorf("%v %v", funcName, err.Error())
 	}
 
+	if e.Schema.HasReferenceTables() {
+		err = e.CreateReferenceTables()
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
 	err = e.CreateTables()
 	if err != nil {
 		return fmt.Errorf("%v %v", funcName, err.Error())
@@ -125,30 +133,35 @@ func (e PgSQL) CreateDatabases() error {
 	var funcName string = "pgsql.CreateDatabases"
 	var jobName string = "Creating PgSQL Databases"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Create Databases Job
 	var job progress.ProgressJob = &CreateDatabasesJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e PgSQL) CreateTables() error {
 	var funcName string = "pgsql.CreateTables"
 	var jobName string = "Creating PgSQL Tables"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Create Tables Job
 	var job progress.ProgressJob = &CreateTablesJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e PgSQL) GetDB(layerType string) *sqlx.DB {
 	return e.DB
 }
 
+// GetSchema returns the layer definitions the tables are created from
+func (e PgSQL) GetSchema() *types.Schema {
+	return e.Schema
+}
+
 func (e PgSQL) Stop() error {
 	e.DB.Close()
 	return nil
@@ -162,14 +175,21 @@ func (e PgSQL) GetTableSQL(fullTableName, tableParams string, fields []string) (
 	tableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", fullTableName)
 
 	for _, f := range fields {
-		if _, ok := types.FieldTypes[f]; !ok {
+		fieldType, ok := e.Schema.GetFieldType(f, types.DialectPostgres)
+		if !ok {
 			return "", fmt.Errorf("unknown field type (%v) %v", fullTableName, f)
 		}
 
-		tableSQL += fmt.Sprintf("%s %s,", f, types.FieldTypes[f])
+		tableSQL += fmt.Sprintf("%s %s,", f, fieldType)
+	}
+
+	tableSQL += "ogc_geom geometry DEFAULT NULL, UNIQUE (ID, GRIDREF)"
+
+	if e.Schema.HasForeignKey(fields) {
+		tableSQL += fmt.Sprintf(", FOREIGN KEY (FEATCODE) REFERENCES %v.%v (code)", types.ReferenceSchema, types.FeatureCodeTable)
 	}
 
-	tableSQL += fmt.Sprintf("ogc_geom geometry DEFAULT NULL, UNIQUE (ID, GRIDREF))%v;", tableParams)
+	tableSQL += fmt.Sprintf(")%v;", tableParams)
 
 	return tableSQL, nil
 }
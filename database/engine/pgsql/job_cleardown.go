@@ -1,6 +1,8 @@
 package pgsql
 
 import (
+	"fmt"
+
 	"github.com/rockwell-uk/go-progress/progress"
 
 	"go-uk-maps-import/database/types"
@@ -9,8 +11,13 @@ import (
 type ClearDownJob struct{}
 
 func (j *ClearDownJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
-	var tasks = make([]*progress.Task, len(types.MapLayers))
-	for i, layerType := range types.MapLayers.Ordered() {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks = make([]*progress.Task, len(schema.MapLayers))
+	for i, layerType := range schema.MapLayers.Ordered() {
 		tasks[i] = &progress.Task{
 			ID:        layerType,
 			Magnitude: 1,
@@ -0,0 +1,60 @@
+package pgsql
+
+import (
+	"fmt"
+
+	"github.com/rockwell-uk/go-logger/logger"
+
+	"go-uk-maps-import/database/types"
+)
+
+// CreateReferenceTables creates and fills the feature code and
+// classification lookup tables in the reference schema
+func (e PgSQL) CreateReferenceTables() error {
+	var funcName string = "pgsql.CreateReferenceTables"
+
+	var statements = []string{
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %v", types.ReferenceSchema),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v.%v (code integer NOT NULL, description varchar(255) NOT NULL, layer varchar(64) NOT NULL, colour varchar(7) DEFAULT NULL, z_order integer DEFAULT NULL, PRIMARY KEY (code))%v;",
+			types.ReferenceSchema, types.FeatureCodeTable, PgSQLTableParams),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v.%v (layer varchar(64) NOT NULL, name varchar(255) NOT NULL, colour varchar(7) DEFAULT NULL, z_order integer DEFAULT NULL, PRIMARY KEY (layer, name))%v;",
+			types.ReferenceSchema, types.ClassificationTable, PgSQLTableParams),
+	}
+
+	for _, statement := range statements {
+		logger.Log(
+			logger.LVL_DEBUG,
+			fmt.Sprintf("%+v\n", statement),
+		)
+
+		_, err := e.DB.Exec(statement)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	// Rows are updated in place as they may be referenced
+	var featureCodeSQL string = fmt.Sprintf("INSERT INTO %v.%v (code, description, layer, colour, z_order) VALUES ($1, $2, $3, $4, $5) "+
+		"ON CONFLICT (code) DO UPDATE SET description = EXCLUDED.description, layer = EXCLUDED.layer, colour = EXCLUDED.colour, z_order = EXCLUDED.z_order",
+		types.ReferenceSchema, types.FeatureCodeTable)
+
+	for _, f := range types.FeatureCodes {
+		_, err := e.DB.Exec(featureCodeSQL, f.Code, f.Description, f.Layer, f.Colour, f.ZOrder)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	var classificationSQL string = fmt.Sprintf("INSERT INTO %v.%v (layer, name, colour, z_order) VALUES ($1, $2, $3, $4) "+
+		"ON CONFLICT (layer, name) DO UPDATE SET colour = EXCLUDED.colour, z_order = EXCLUDED.z_order",
+		types.ReferenceSchema, types.ClassificationTable)
+
+	for _, c := range types.Classifications {
+		_, err := e.DB.Exec(classificationSQL, c.Layer, c.Name, c.Colour, c.ZOrder)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	return nil
+}
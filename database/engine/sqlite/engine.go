@@ -10,11 +10,11 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/mattn/go-sqlite3"
 	"github.com/rockwell-uk/go-logger/logger"
-	"github.com/rockwell-uk/go-nationalgrid"
 	"github.com/rockwell-uk/go-progress/progress"
 	"github.com/rockwell-uk/go-utils/fileutils"
 
 	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/flatgeobuf"
 )
 
 const (
@@ -23,8 +23,6 @@ const (
 
 var (
 	SQLiteStorageFolder = "db"
-	driverConns         = map[string]*sqlite3.SQLiteConn{}
-	driverName          string
 )
 
 type SQLiteConfig struct {
@@ -48,8 +46,11 @@ func (c SQLiteConfig) DSN() string {
 }
 
 type SQLite struct {
-	Config SQLiteConfig
-	dbs    map[string]*sqlx.DB
+	Config      SQLiteConfig
+	Schema      *types.Schema
+	dbs         map[string]*sqlx.DB
+	driverName  string
+	driverConns map[string]*sqlite3.SQLiteConn
 }
 
 func (e *SQLite) Connect() error {
@@ -61,10 +62,10 @@ func (e *SQLite) Connect() error {
 		fmt.Sprintf("%v\n", jobName),
 	)
 
-	driverName = fmt.Sprintf("sqlite3_with_spatialite_%v", time.Now().UnixNano())
+	e.driverName = fmt.Sprintf("sqlite3_with_spatialite_%v", time.Now().UnixNano())
 
 	var driverConn *sqlite3.SQLiteConn
-	sql.Register(driverName, &sqlite3.SQLiteDriver{
+	sql.Register(e.driverName, &sqlite3.SQLiteDriver{
 		Extensions: []string{"mod_spatialite"},
 		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
 			driverConn = conn
@@ -73,14 +74,15 @@ func (e *SQLite) Connect() error {
 	})
 
 	e.dbs = make(map[string]*sqlx.DB)
+	e.driverConns = make(map[string]*sqlite3.SQLiteConn)
 
 	err := fileutils.MkDir(SQLiteStorageFolder)
 	if err != nil {
 		return fmt.Errorf("%v %v", funcName, err.Error())
 	}
 
-	for _, layerType := range types.MapLayers.Ordered() {
-		db, err := sqlx.Connect(driverName, ":memory:")
+	for _, layerType := range e.Schema.MapLayers.Ordered() {
+		db, err := sqlx.Connect(e.driverName, ":memory:")
 		if err != nil {
 			return fmt.Errorf("%v %v", funcName, err.Error())
 		}
@@ -88,7 +90,7 @@ func (e *SQLite) Connect() error {
 		// See "Important settings" section.
 		db.SetMaxOpenConns(1)
 
-		driverConns[layerType] = driverConn
+		e.driverConns[layerType] = driverConn
 		e.dbs[layerType] = db
 	}
 
@@ -104,17 +106,24 @@ func (e SQLite) Cleardown() error {
 	var funcName string = "sqlite.Cleardown"
 	var jobName string = "Cleardown SQLite Database"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Cleardown Job
 	var job progress.ProgressJob = &ClearDownJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e SQLite) Prepare() error {
 	var funcName string = "sqlite.Prepare"
 
+	if e.Schema.HasReferenceTables() {
+		err := e.CreateReferenceTables()
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
 	err := e.CreateTables()
 	if err != nil {
 		return fmt.Errorf("%v %v", funcName, err.Error())
@@ -127,60 +136,71 @@ func (e SQLite) CreateTables() error {
 	var funcName string = "sqlite.CreateTables"
 	var jobName string = "Creating SQLite Tables"
 
-	var magnitude int = len(types.MapLayers) * len(nationalgrid.NationalGridSquares)
+	var magnitude int = e.Schema.NumTables()
 
 	// Create Tables Job
 	var job progress.ProgressJob = &CreateTablesJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
 func (e SQLite) GetDB(layerType string) *sqlx.DB {
 	return e.dbs[layerType]
 }
 
+// GetSchema returns the layer definitions the tables are created from
+func (e SQLite) GetSchema() *types.Schema {
+	return e.Schema
+}
+
 func (e SQLite) InMemoryToFiles() error {
 	var funcName string = "sqlite.InMemoryToFiles"
 	var jobName string = "Writing SQLite in memory databases to files"
 
-	var magnitude int = len(driverConns)
+	var magnitude int = len(e.driverConns)
 
 	// In Memory To Files Job
 	var job progress.ProgressJob = &InMemoryToFilesJob{}
 
-	if len(driverConns) == 0 {
+	if len(e.driverConns) == 0 {
 		return fmt.Errorf("%v: no driver connections were found", funcName)
 	}
 
 	logger.Log(
 		logger.LVL_INTERNAL,
-		fmt.Sprintf("Driver connections %v\n", driverConns),
+		fmt.Sprintf("Driver connections %v\n", e.driverConns),
 	)
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, e)
 }
 
+// SwitchToFiles connects each layer to its database file in place of its in
+// memory database
 func (e SQLite) SwitchToFiles() error {
 	var funcName string = "sqlite.SwitchToFiles"
 
-	e.dbs = make(map[string]*sqlx.DB)
-
 	err := fileutils.MkDir(SQLiteStorageFolder)
 	if err != nil {
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	for layerType := range types.MapLayers {
+	for layerType := range e.Schema.MapLayers {
 		dbFilePath := e.GetDatabasePath(layerType)
 		exists := fileutils.FileExists(dbFilePath)
 		if !exists {
 			return fmt.Errorf("%v: db file does not exist %v", funcName, dbFilePath)
 		}
 
-		db, err := sqlx.Connect(driverName, dbFilePath)
+		db, err := sqlx.Connect(e.driverName, dbFilePath)
 		if err != nil {
 			return fmt.Errorf("%v: %v", funcName, err.Error())
 		}
+		db.SetMaxOpenConns(1)
+
+		// The map is shared with the engine, so the swap outlives this copy
+		if old, exists := e.dbs[layerType]; exists {
+			old.Close()
+		}
 		e.dbs[layerType] = db
 	}
 
@@ -188,9 +208,9 @@ func (e SQLite) SwitchToFiles() error {
 }
 
 func (e SQLite) Stop() error {
-	for layerType := range types.MapLayers {
+	for layerType := range e.Schema.MapLayers {
 		// Delete the driver conn
-		delete(driverConns, layerType)
+		delete(e.driverConns, layerType)
 
 		// Close the database
 		e.dbs[layerType].Close()
@@ -208,18 +228,29 @@ func (e SQLite) GetDatabasePath(dbname string) string {
 	return fmt.Sprintf("%v/%v.db", SQLiteStorageFolder, dbname)
 }
 
+func (e SQLite) GetFlatGeobufPath(dbname string) string {
+	return fmt.Sprintf("%v/%v%v", flatgeobuf.DefaultFolder, dbname, flatgeobuf.Ext)
+}
+
 func (e SQLite) GetTableSQL(fullTableName, tableParams string, fields []string) (string, error) {
 	tableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", fullTableName)
 
 	for _, f := range fields {
-		if _, ok := types.FieldTypes[f]; !ok {
+		fieldType, ok := e.Schema.GetFieldType(f, types.DialectSQLite)
+		if !ok {
 			return "", fmt.Errorf("unknown field type (%v) %v", fullTableName, f)
 		}
 
-		tableSQL += fmt.Sprintf("`%s` %s,", f, types.FieldTypes[f])
+		tableSQL += fmt.Sprintf("`%s` %s,", f, fieldType)
+	}
+
+	tableSQL += "`ogc_geom` geometry DEFAULT NULL, PRIMARY KEY (`ID`, `GRIDREF`)"
+
+	if e.Schema.HasForeignKey(fields) {
+		tableSQL += fmt.Sprintf(", FOREIGN KEY (`FEATCODE`) REFERENCES `%s` (`code`)", types.FeatureCodeTable)
 	}
 
-	tableSQL += fmt.Sprintf("`ogc_geom` geometry DEFAULT NULL, PRIMARY KEY (`ID`, `GRIDREF`))%v;", tableParams)
+	tableSQL += fmt.Sprintf(")%v;", tableParams)
 
 	return tableSQL, nil
 }
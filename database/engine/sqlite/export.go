@@ -3,58 +3,136 @@ package sqlite
 import (
 	"fmt"
 
+	"github.com/rockwell-uk/go-logger/logger"
 	"github.com/rockwell-uk/go-progress/progress"
 	"github.com/rockwell-uk/go-utils/fileutils"
 
-	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/flatgeobuf"
+	"go-uk-maps-import/osdata"
+	"go-uk-maps-import/sqlwriter"
 )
 
 // ref: https://groups.google.com/g/spatialite-users/c/U2pxp3bwVnY
 
-var targetFolder string = "sql"
+type exportToSQLFilesInput struct {
+	e SQLite
+	w *sqlwriter.Writer
+	d sqlwriter.Dialect
+}
+
+type exportToSQLiteFilesInput struct {
+	e      SQLite
+	folder string
+}
+
+// ExportToMySQLFiles writes the databases as MySQL .sql files using a started
+// writer
+func (e SQLite) ExportToMySQLFiles(w *sqlwriter.Writer) error {
+	return e.ExportToSQLFiles(w, sqlwriter.MySQL{})
+}
+
+// ExportToSQLFiles writes the databases as .sql files in the SQL of a dialect
+// using a started writer
+func (e SQLite) ExportToSQLFiles(w *sqlwriter.Writer, d sqlwriter.Dialect) error {
+	var funcName string = "sqlite.ExportToSQLFiles"
+	var jobName string = fmt.Sprintf("Exporting SQLite databases to %v format files", d.Name())
 
-func (e SQLite) ExportToMySQLFiles() error {
-	var funcName string = "sqlite.ExportToMySQLFiles"
-	var jobName string = "Exporting SQLite databases to MySQL format files"
+	var magnitude int = len(e.Schema.MapLayers)
 
-	var magnitude int = len(types.MapLayers)
+	// ExportToSQLFiles Job
+	var job progress.ProgressJob = &ExportToSQLFilesJob{}
+
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, exportToSQLFilesInput{e, w, d})
+}
+
+// ExportToFlatGeobufFiles writes each database as a FlatGeobuf file with a
+// spatial index, returning the number of features in each file by layer
+func (e SQLite) ExportToFlatGeobufFiles() (map[string]int, error) {
+	var funcName string = "sqlite.ExportToFlatGeobufFiles"
+	var jobName string = "Exporting SQLite databases to FlatGeobuf files"
+
+	var magnitude int = len(e.Schema.MapLayers)
+
+	logger.Log(
+		logger.LVL_APP,
+		fmt.Sprintf("%v [%v]\n", jobName, magnitude),
+	)
 
 	// Pre flight
-	err := fileutils.MkDir(targetFolder)
+	err := fileutils.MkDir(flatgeobuf.DefaultFolder)
 	if err != nil {
-		return fmt.Errorf("%v: %v", funcName, err.Error())
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	err = fileutils.EmptyFolder(targetFolder)
+	err = fileutils.EmptyFolder(flatgeobuf.DefaultFolder)
 	if err != nil {
-		return fmt.Errorf("%v: %v", funcName, err.Error())
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	// ExportToFlatGeobufFiles Job, run directly for the feature counts
+	var j progress.ProgressJob = &ExportToFlatGeobufFilesJob{}
+
+	job, err := j.Setup(jobName, e.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer job.End(true)
+
+	res, err := j.Run(job, e)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	// ExportToMySQLFiles Job
-	var job progress.ProgressJob = &ExportToMySQLFilesJob{}
+	features, _ := res.(map[string]int)
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return features, nil
 }
 
-func (e SQLite) ExportToSQLiteFiles() error {
+// ExportToSQLiteFiles dumps the databases as SQLite .sql files in a folder,
+// emptying it first
+func (e SQLite) ExportToSQLiteFiles(folder string) error {
 	var funcName string = "mysql.ExportToSQLiteFiles"
 	var jobName string = "Exporting SQLite databases to SQLite format files"
 
-	var magnitude int = len(types.MapLayers)
+	var magnitude int = len(e.Schema.MapLayers)
 
 	// Pre flight
-	err := fileutils.MkDir(targetFolder)
+	err := fileutils.MkDir(folder)
 	if err != nil {
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	err = fileutils.EmptyFolder(targetFolder)
+	err = fileutils.EmptyFolder(folder)
 	if err != nil {
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	// ExportToMySQLFiles Job
+	// ExportToSQLiteFiles Job
 	var job progress.ProgressJob = &ExportToSQLiteFilesJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, struct{}{}, e)
+	return progress.RunJob(jobName, funcName, job, magnitude, e.Schema, exportToSQLiteFilesInput{e, folder})
+}
+
+// getFieldValues returns the values of the fields of a row, fixing the text
+// of "some" shapefiles
+func getFieldValues(result map[string]interface{}, fields []string) []interface{} {
+	var fieldValues = make([]interface{}, len(fields))
+	for n, fieldName := range fields {
+		value := result[fieldName]
+
+		if v, ok := value.(string); ok {
+			// Fix invalid UTF8 strings
+			v = osdata.InvalidUTF8Fix(v)
+			value = v
+
+			// Fix for FEATCODE in "some" shapefiles
+			if fieldName == "FEATCODE" {
+				value = osdata.FeatcodeFix(v)
+			}
+		}
+
+		fieldValues[n] = value
+	}
+
+	return fieldValues
 }
@@ -3,11 +3,10 @@ package sqlite
 import (
 	"fmt"
 	"strings"
+	"sync"
 
-	"github.com/rockwell-uk/csync/mutex"
 	"github.com/rockwell-uk/csync/waitgroup"
 	"github.com/rockwell-uk/go-logger/logger"
-	"github.com/rockwell-uk/go-nationalgrid"
 	"github.com/rockwell-uk/go-progress/progress"
 
 	"go-uk-maps-import/database/types"
@@ -16,9 +15,14 @@ import (
 type CreateTablesJob struct{}
 
 func (j *CreateTablesJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
 	var tasks []*progress.Task
-	for layerType := range types.MapLayers.Ordered() {
-		for square := range nationalgrid.NationalGridSquares {
+	for _, layerType := range schema.MapLayers.Ordered() {
+		for _, square := range schema.LayerSquares(layerType) {
 			tasks = append(tasks, &progress.Task{
 				ID:        fmt.Sprintf("%v_%v", layerType, square),
 				Magnitude: 1,
@@ -34,11 +38,12 @@ func (j *CreateTablesJob) Setup(jobName string, input interface{}) (*progress.Jo
 func (j *CreateTablesJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
 	if e, ok := input.(SQLite); ok {
 		var wg *waitgroup.WaitGroup = waitgroup.New()
+		var mu sync.Mutex
 
-		for _, layerType := range types.MapLayers.Ordered() {
-			fields := types.MapLayers[layerType]
+		for _, layerType := range e.Schema.MapLayers.Ordered() {
+			fields := e.Schema.MapLayers[layerType]
 
-			for square := range nationalgrid.NationalGridSquares {
+			for _, square := range e.Schema.LayerSquares(layerType) {
 				wg.Add(1)
 
 				c := make(chan error)
@@ -57,7 +62,7 @@ func (j *CreateTablesJob) Run(job *progress.Job, input interface{}) (interface{}
 						fmt.Sprintf("[%v] %+v\n", layerType, tableSQL),
 					)
 
-					mutex.Lock()
+					mu.Lock()
 					e.GetDB(lt).MustExec(tableSQL)
 
 					countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %v", tableName)
@@ -71,7 +76,7 @@ func (j *CreateTablesJob) Run(job *progress.Job, input interface{}) (interface{}
 						logger.LVL_DEBUG,
 						fmt.Sprintf("%v rows for table [%v.%v]\n", numRows, lt, tableName),
 					)
-					mutex.Unlock()
+					mu.Unlock()
 
 					task.End()
 					job.UpdateBar()
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-progress/progress"
+
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/flatgeobuf"
+)
+
+type ExportToFlatGeobufFilesJob struct{}
+
+func (j *ExportToFlatGeobufFilesJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks = make([]*progress.Task, len(schema.MapLayers))
+	for i, layerType := range schema.MapLayers.Ordered() {
+		tasks[i] = &progress.Task{
+			ID:        layerType,
+			Magnitude: 1,
+		}
+	}
+
+	job := progress.SetupJob(jobName, tasks)
+
+	return job, nil
+}
+
+// Run returns the number of features written for each layer
+func (j *ExportToFlatGeobufFilesJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
+	var features = make(map[string]int)
+
+	if e, ok := input.(SQLite); ok {
+		// Do the work
+		for layerType, task := range job.Tasks {
+			task.Start()
+
+			n, err := exportFlatGeobufFile(e, layerType)
+			if err != nil {
+				return features, err
+			}
+			features[layerType] = n
+
+			task.End()
+			job.UpdateBar()
+		}
+	}
+
+	return features, nil
+}
+
+// exportFlatGeobufFile writes the features of a layer to its .fgb file, a
+// file that cannot be finished is removed
+func exportFlatGeobufFile(e SQLite, layerType string) (int, error) {
+	db := e.GetDB(layerType)
+	layerTypeFields := e.Schema.MapLayers[layerType]
+	fgbFilePath := e.GetFlatGeobufPath(layerType)
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Writing %v\n", fgbFilePath),
+	)
+
+	var fields = make([]flatgeobuf.Field, len(layerTypeFields))
+	for n, field := range layerTypeFields {
+		fields[n] = flatgeobuf.Field{Name: field, Kind: e.Schema.FieldKind(field)}
+	}
+
+	w, err := flatgeobuf.Create(fgbFilePath, layerType, fields, e.Schema.LayerSRID(layerType))
+	if err != nil {
+		return 0, err
+	}
+
+	var closed bool
+	defer func() {
+		if !closed {
+			w.Discard()
+		}
+	}()
+
+	rows, err := db.Queryx(flatGeobufQuery(layerTypeFields, e.Schema.LayerSquares(layerType)))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := make(map[string]interface{})
+		err = rows.MapScan(result)
+		if err != nil {
+			return 0, err
+		}
+
+		ogc_geom, _ := result["ogc_geom"].([]byte)
+
+		err = w.Write(getFieldValues(result, layerTypeFields), ogc_geom)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return 0, err
+	}
+	closed = true
+
+	logger.Log(
+		logger.LVL_INTERNAL,
+		fmt.Sprintf("%v features in %v\n", w.NumFeatures(), fgbFilePath),
+	)
+
+	return w.NumFeatures(), nil
+}
+
+// flatGeobufQuery selects each feature of a layer once, the tables hold a row
+// per grid cell of a feature and a feature crossing squares is in the table
+// of each, the row of its first grid cell is used
+func flatGeobufQuery(layerTypeFields []string, squares []string) string {
+	var columns = make([]string, len(layerTypeFields))
+	for n, field := range layerTypeFields {
+		columns[n] = field
+		if field == "GRIDREF" {
+			// SQLite takes the other columns from the row of the MIN
+			columns[n] = "MIN(GRIDREF) AS GRIDREF"
+		}
+	}
+
+	var tables = make([]string, len(squares))
+	for n, square := range squares {
+		tables[n] = fmt.Sprintf("SELECT %v, ogc_geom FROM %v", strings.Join(layerTypeFields, ","), strings.ToLower(square))
+	}
+
+	return fmt.Sprintf("SELECT %v, AsBinary(ogc_geom) AS ogc_geom FROM (%v) GROUP BY ID",
+		strings.Join(columns, ","),
+		strings.Join(tables, " UNION ALL "),
+	)
+}
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// The geometries are stored as WKB so AsBinary returns them as they are
+func init() {
+	sql.Register("sqlite3_fgb", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("AsBinary", func(wkb []byte) []byte {
+				return wkb
+			}, true)
+		},
+	})
+}
+
+func TestFlatGeobufQuery(t *testing.T) {
+	db, err := sqlx.Open("sqlite3_fgb", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Every connection to :memory: is a new database
+	db.SetMaxOpenConns(1)
+
+	fields := []string{"ID", "GRIDREF", "FEATCODE"}
+	squares := []string{"SD", "SE"}
+
+	// A crosses the squares and is in three grid cells
+	inserts := map[string][][]interface{}{
+		"sd": {{"a", "SD1020", 2}, {"a", "SD1010", 1}},
+		"se": {{"a", "SE0010", 2}, {"b", "SE0020", 3}},
+	}
+
+	for _, square := range squares {
+		_, err := db.Exec("CREATE TABLE " + square + " (ID text, GRIDREF text, FEATCODE integer, ogc_geom blob)")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for table, rows := range inserts {
+		for _, r := range rows {
+			_, err := db.Exec("INSERT INTO "+table+" VALUES (?, ?, ?, ?)", append(r, []byte(r[0].(string)))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	rows, err := db.Queryx(flatGeobufQuery(fields, squares))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var actual [][]interface{}
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, values)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]interface{}{
+		{"a", "SD1010", int64(1), []byte("a")},
+		{"b", "SE0020", int64(3), []byte("b")},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
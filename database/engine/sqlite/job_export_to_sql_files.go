@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-progress/progress"
+
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/sqlwriter"
+)
+
+// ref: https://groups.google.com/g/spatialite-users/c/U2pxp3bwVnY
+
+type ExportToSQLFilesJob struct{}
+
+func (j *ExportToSQLFilesJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks = make([]*progress.Task, len(schema.MapLayers))
+	for i, layerType := range schema.MapLayers.Ordered() {
+		tasks[i] = &progress.Task{
+			ID:        layerType,
+			Magnitude: 1,
+		}
+	}
+
+	job := progress.SetupJob(jobName, tasks)
+
+	return job, nil
+}
+
+func (j *ExportToSQLFilesJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
+	if in, ok := input.(exportToSQLFilesInput); ok {
+		e, w, d := in.e, in.w, in.d
+		// Do the work
+		for layerType, task := range job.Tasks {
+			task.Start()
+
+			db := e.GetDB(layerType)
+			layerTypeFields := e.Schema.MapLayers[layerType]
+
+			logger.Log(
+				logger.LVL_DEBUG,
+				fmt.Sprintf("Writing files for %v\n", layerType),
+			)
+
+			for _, square := range e.Schema.LayerSquares(layerType) {
+				tableName := strings.ToLower(square)
+
+				// Only do the queries if we're going to log the result
+				if logger.Vbs == logger.LVL_INTERNAL {
+					countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %v", tableName)
+					var numRows int
+					row := db.QueryRow(countQuery)
+					err := row.Scan(&numRows)
+					if err != nil {
+						return struct{}{}, err
+					}
+					logger.Log(
+						logger.LVL_INTERNAL,
+						fmt.Sprintf("%v rows for square %v\n", numRows, square),
+					)
+				}
+
+				query := fmt.Sprintf("SELECT %v, AsBinary(ogc_geom) AS ogc_geom FROM %v", strings.Join(layerTypeFields, ","), tableName)
+				rows, err := db.Queryx(query)
+				if err != nil {
+					return struct{}{}, err
+				}
+				defer rows.Close()
+
+				for rows.Next() {
+					result := make(map[string]interface{})
+					err = rows.MapScan(result)
+					if err != nil {
+						return struct{}{}, err
+					}
+
+					s := result["GRIDREF"]
+
+					fieldValues := getFieldValues(result, layerTypeFields)
+
+					sqlFileName := fmt.Sprintf("%s%02d", tableName, s)
+
+					w.WriteHeader(
+						sqlwriter.SQLLine{
+							DBName: layerType,
+							Table:  sqlFileName,
+							Line:   d.Insert(d.Table(layerType, tableName), layerTypeFields),
+						},
+					)
+
+					if ogc_geom, ok := result["ogc_geom"].([]byte); ok {
+						w.Write(
+							sqlwriter.SQLLine{
+								DBName: layerType,
+								Table:  sqlFileName,
+								Line:   d.Row(fieldValues, ogc_geom, e.Schema.LayerSRID(layerType)),
+							},
+						)
+					}
+				}
+			}
+
+			task.End()
+			job.UpdateBar()
+		}
+	}
+
+	return struct{}{}, nil
+}
@@ -18,8 +18,13 @@ import (
 type ExportToSQLiteFilesJob struct{}
 
 func (j *ExportToSQLiteFilesJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
-	var tasks = make([]*progress.Task, len(types.MapLayers))
-	for i, layerType := range types.MapLayers.Ordered() {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks = make([]*progress.Task, len(schema.MapLayers))
+	for i, layerType := range schema.MapLayers.Ordered() {
 		tasks[i] = &progress.Task{
 			ID:        layerType,
 			Magnitude: 1,
@@ -32,7 +37,7 @@ func (j *ExportToSQLiteFilesJob) Setup(jobName string, input interface{}) (*prog
 }
 
 func (j *ExportToSQLiteFilesJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
-	if _, ok := input.(SQLite); ok {
+	if in, ok := input.(exportToSQLiteFilesInput); ok {
 		// Do the work
 		for layerType, task := range job.Tasks {
 			task.Start()
@@ -51,7 +56,7 @@ func (j *ExportToSQLiteFilesJob) Run(job *progress.Job, input interface{}) (inte
 				return struct{}{}, err
 			}
 
-			sqlFilePath := fmt.Sprintf("%v/%v.sql", targetFolder, layerType)
+			sqlFilePath := fmt.Sprintf("%v/%v.sql", in.folder, layerType)
 
 			logger.Log(
 				logger.LVL_DEBUG,
@@ -14,8 +14,13 @@ import (
 type InMemoryToFilesJob struct{}
 
 func (j *InMemoryToFilesJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
-	var tasks = make([]*progress.Task, len(types.MapLayers))
-	for i, layerType := range types.MapLayers.Ordered() {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks = make([]*progress.Task, len(schema.MapLayers))
+	for i, layerType := range schema.MapLayers.Ordered() {
 		tasks[i] = &progress.Task{
 			ID:        layerType,
 			Magnitude: 1,
@@ -31,7 +36,7 @@ func (j *InMemoryToFilesJob) Run(job *progress.Job, input interface{}) (interfac
 	if e, ok := input.(SQLite); ok {
 		i := 0
 		// Write the in memory databases to files
-		for layerType, dbConn := range driverConns {
+		for layerType, dbConn := range e.driverConns {
 			task, _ := job.GetTask(layerType)
 			task.Start()
 
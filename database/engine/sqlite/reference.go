@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/rockwell-uk/go-logger/logger"
+
+	"go-uk-maps-import/database/types"
+)
+
+// CreateReferenceTables creates and fills the feature code and
+// classification lookup tables in each layer database, so the layer files
+// can be joined to them on their own
+func (e SQLite) CreateReferenceTables() error {
+	var funcName string = "sqlite.CreateReferenceTables"
+
+	var statements = []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (`code` integer NOT NULL, `description` text NOT NULL, `layer` text NOT NULL, `colour` text DEFAULT NULL, `z_order` integer DEFAULT NULL, PRIMARY KEY (`code`))%v;",
+			types.FeatureCodeTable, SQLiteTableParams),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (`layer` text NOT NULL, `name` text NOT NULL, `colour` text DEFAULT NULL, `z_order` integer DEFAULT NULL, PRIMARY KEY (`layer`, `name`))%v;",
+			types.ClassificationTable, SQLiteTableParams),
+	}
+
+	// SQLite only enforces foreign keys when asked to
+	if e.Schema.ForeignKeys {
+		statements = append([]string{"PRAGMA foreign_keys = ON"}, statements...)
+	}
+
+	var featureCodeSQL string = fmt.Sprintf("INSERT OR REPLACE INTO `%s` (`code`, `description`, `layer`, `colour`, `z_order`) VALUES (?, ?, ?, ?, ?)", types.FeatureCodeTable)
+	var classificationSQL string = fmt.Sprintf("INSERT OR REPLACE INTO `%s` (`layer`, `name`, `colour`, `z_order`) VALUES (?, ?, ?, ?)", types.ClassificationTable)
+
+	for _, layerType := range e.Schema.MapLayers.Ordered() {
+		db := e.GetDB(layerType)
+
+		for _, statement := range statements {
+			logger.Log(
+				logger.LVL_DEBUG,
+				fmt.Sprintf("[%v] %+v\n", layerType, statement),
+			)
+
+			_, err := db.Exec(statement)
+			if err != nil {
+				return fmt.Errorf("%v %v", funcName, err.Error())
+			}
+		}
+
+		for _, f := range types.FeatureCodes {
+			_, err := db.Exec(featureCodeSQL, f.Code, f.Description, f.Layer, f.Colour, f.ZOrder)
+			if err != nil {
+				return fmt.Errorf("%v %v", funcName, err.Error())
+			}
+		}
+
+		for _, c := range types.Classifications {
+			_, err := db.Exec(classificationSQL, c.Layer, c.Name, c.Colour, c.ZOrder)
+			if err != nil {
+				return fmt.Errorf("%v %v", funcName, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
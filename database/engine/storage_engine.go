@@ -2,7 +2,7 @@ package engine
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
 	"strings"
 
@@ -16,6 +16,8 @@ import (
 	"go-uk-maps-import/database/engine/mysql"
 	"go-uk-maps-import/database/engine/pgsql"
 	"go-uk-maps-import/database/engine/sqlite"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/sqlwriter"
 )
 
 var (
@@ -30,6 +32,7 @@ type StorageEngine interface {
 	Prepare() error
 	Stop() error
 	GetDB(layerType string) *sqlx.DB
+	GetSchema() *types.Schema
 	GetTableName(batchInsertsKey string) string
 	GetTableSQL(fullTableName, tableParams string, fields []string) (string, error)
 }
@@ -101,6 +104,9 @@ type SEConfig struct {
 	ClearDown     bool
 	CountsOnly    bool
 	StorageEngine StorageEngine
+
+	// The layer definitions, the OS layers when not set
+	Schema *types.Schema
 }
 
 func (c SEConfig) String() string {
@@ -150,6 +156,10 @@ func start(config *SEConfig) (StorageEngine, error) {
 	var funcName string = "engine.start"
 	var e StorageEngine
 
+	if config.Schema == nil {
+		config.Schema = types.NewSchema()
+	}
+
 	switch *config.Engine {
 	case EngineMySQL:
 		e = &mysql.MySQL{
@@ -161,6 +171,7 @@ func start(config *SEConfig) (StorageEngine, error) {
 				Schema:  *config.DBConfig.Schema,
 				Timeout: *config.DBConfig.Timeout,
 			},
+			Schema: config.Schema,
 		}
 
 	case EnginePostgres:
@@ -173,11 +184,13 @@ func start(config *SEConfig) (StorageEngine, error) {
 				Schema:  *config.DBConfig.Schema,
 				Timeout: *config.DBConfig.Timeout,
 			},
+			Schema: config.Schema,
 		}
 
 	case EngineSQLite:
 		e = &sqlite.SQLite{
 			Config: sqlite.SQLiteConfig{},
+			Schema: config.Schema,
 		}
 
 	default:
@@ -209,7 +222,8 @@ func start(config *SEConfig) (StorageEngine, error) {
 	return e, nil
 }
 
-func DoInserts(dbConfig DBConfig, sqlFiles []string) error {
+// DoInserts loads the SQL files with the loader of their dialect
+func DoInserts(loader Loader, sqlFiles []string) error {
 	var funcName string = "engine.DoInserts"
 	var jobName string = "Inserting data to the db"
 
@@ -218,7 +232,7 @@ func DoInserts(dbConfig DBConfig, sqlFiles []string) error {
 	// Do Inserts Job
 	var job progress.ProgressJob = &DoInsertsJob{}
 
-	return progress.RunJob(jobName, funcName, job, magnitude, sqlFiles, dbConfig)
+	return progress.RunJob(jobName, funcName, job, magnitude, sqlFiles, loader)
 }
 
 func RunSQLFileDirect(config SEConfig, sqlFile string) (string, error) {
@@ -233,7 +247,13 @@ func RunSQLFileDirect(config SEConfig, sqlFile string) (string, error) {
 
 	db := config.StorageEngine.GetDB(layerType)
 
-	c, err := os.ReadFile(sqlFile)
+	r, err := sqlwriter.OpenSQLFile(sqlFile)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer r.Close()
+
+	c, err := io.ReadAll(r)
 	if err != nil {
 		return "", fmt.Errorf("%v: %v", funcName, err.Error())
 	}
@@ -255,17 +275,11 @@ func GetLayerType(sqlFileName string) string {
 func RunSQLFile(config DBConfig, sqlFile string) (string, string, error) {
 	command := "mysql"
 
-	args := []string{
-		"--connect-timeout",
-		"10",
-		fmt.Sprintf("-h%v", config.Host),
-		fmt.Sprintf("-P%v", config.Port),
-		fmt.Sprintf("-u%v", config.User),
-		fmt.Sprintf("-p%v", config.Pass),
-		fmt.Sprintf("-D%v", config.Schema),
+	args := append(
+		mysqlArgs(config),
 		"-e",
 		fmt.Sprintf("source %v", sqlFile),
-	}
+	)
 
 	logger.Log(
 		logger.LVL_DEBUG,
@@ -275,13 +289,25 @@ func RunSQLFile(config DBConfig, sqlFile string) (string, string, error) {
 	return osutils.RunCommandSilent(command, args...)
 }
 
+func mysqlArgs(config DBConfig) []string {
+	return []string{
+		"--connect-timeout",
+		"10",
+		fmt.Sprintf("-h%v", deref(config.Host)),
+		fmt.Sprintf("-P%v", deref(config.Port)),
+		fmt.Sprintf("-u%v", deref(config.User)),
+		fmt.Sprintf("-p%v", deref(config.Pass)),
+		fmt.Sprintf("-D%v", deref(config.Schema)),
+	}
+}
+
 func RunSQLFileWithShell(shell string, config DBConfig, sqlFile string) (string, string, error) {
 	command := fmt.Sprintf("mysql --connect-timeout 10 -h%v -P %v -u %v -p%v %v < %v",
-		config.Host,
-		config.Port,
-		config.User,
-		config.Pass,
-		config.Schema,
+		deref(config.Host),
+		deref(config.Port),
+		deref(config.User),
+		deref(config.Pass),
+		deref(config.Schema),
 		sqlFile,
 	)
 
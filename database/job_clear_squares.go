@@ -0,0 +1,79 @@
+//nolint:gci
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"go-uk-maps-import/database/engine"
+	"go-uk-maps-import/database/types"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-progress/progress"
+)
+
+type ClearSquaresJob struct {
+	Squares []string
+}
+
+func (j *ClearSquaresJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks []*progress.Task
+	for _, layerType := range schema.MapLayers.Ordered() {
+		// Custom layers are not part of the OS releases so do not change
+		if schema.CustomLayers[layerType] {
+			continue
+		}
+
+		for _, square := range j.Squares {
+			tasks = append(tasks, &progress.Task{
+				ID:        fmt.Sprintf("%v_%v", layerType, square),
+				Magnitude: 1,
+			})
+		}
+	}
+
+	job := progress.SetupJob(jobName, tasks)
+
+	return job, nil
+}
+
+func (j *ClearSquaresJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
+	if s, ok := input.(engine.StorageEngine); ok {
+		schema := s.GetSchema()
+
+		for _, layerType := range schema.MapLayers.Ordered() {
+			if schema.CustomLayers[layerType] {
+				continue
+			}
+
+			for _, square := range j.Squares {
+				task, _ := job.GetTask(fmt.Sprintf("%v_%v", layerType, square))
+				task.Start()
+
+				tableName := strings.ToLower(square)
+				deleteSQL := fmt.Sprintf("DELETE FROM %s", s.GetTableName(fmt.Sprintf("%v.%v", layerType, tableName)))
+				logger.Log(
+					logger.LVL_INTERNAL,
+					fmt.Sprintf("deleteSQL %v %v\n", layerType, deleteSQL),
+				)
+
+				_, err := s.GetDB(layerType).Exec(deleteSQL)
+				if err != nil {
+					return struct{}{}, err
+				}
+
+				task.End()
+				job.UpdateBar()
+			}
+		}
+
+		return struct{}{}, nil
+	}
+
+	return struct{}{}, nil
+}
@@ -0,0 +1,62 @@
+//nolint:gci
+package database
+
+import (
+	"fmt"
+
+	"go-uk-maps-import/database/engine"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/geoparquet"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-progress/progress"
+)
+
+type ExportGeoParquetJob struct {
+	Exporter *geoparquet.Exporter
+}
+
+func (j *ExportGeoParquetJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
+	var tasks = make([]*progress.Task, len(schema.MapLayers))
+	for i, layerType := range schema.MapLayers.Ordered() {
+		tasks[i] = &progress.Task{
+			ID:        layerType,
+			Magnitude: 1,
+		}
+	}
+
+	job := progress.SetupJob(jobName, tasks)
+
+	return job, nil
+}
+
+func (j *ExportGeoParquetJob) Run(job *progress.Job, input interface{}) (interface{}, error) {
+	if s, ok := input.(engine.StorageEngine); ok {
+		for _, layerType := range s.GetSchema().MapLayers.Ordered() {
+			task, _ := job.GetTask(layerType)
+			task.Start()
+
+			logger.Log(
+				logger.LVL_DEBUG,
+				fmt.Sprintf("Exporting %v\n", layerType),
+			)
+
+			err := ReadBack(s, layerType, func(square string, rec map[string]interface{}, wkb []byte) error {
+				return j.Exporter.Write(layerType, square, rec, wkb)
+			})
+			if err != nil {
+				return struct{}{}, err
+			}
+
+			task.End()
+			job.UpdateBar()
+		}
+	}
+
+	return struct{}{}, nil
+}
@@ -10,16 +10,20 @@ import (
 
 	"github.com/rockwell-uk/csync/waitgroup"
 	"github.com/rockwell-uk/go-logger/logger"
-	"github.com/rockwell-uk/go-nationalgrid"
 	"github.com/rockwell-uk/go-progress/progress"
 )
 
 type TableCountsJob struct{}
 
 func (j *TableCountsJob) Setup(jobName string, input interface{}) (*progress.Job, error) {
+	schema, ok := input.(*types.Schema)
+	if !ok {
+		return nil, fmt.Errorf("expected *types.Schema got %T", input)
+	}
+
 	var tasks []*progress.Task
-	for layerType := range types.MapLayers.Ordered() {
-		for square := range nationalgrid.NationalGridSquares {
+	for _, layerType := range schema.MapLayers.Ordered() {
+		for _, square := range schema.LayerSquares(layerType) {
 			tasks = append(tasks, &progress.Task{
 				ID:        fmt.Sprintf("%v_%v", layerType, square),
 				Magnitude: 1,
@@ -38,10 +42,11 @@ func (j *TableCountsJob) Run(job *progress.Job, input interface{}) (interface{},
 
 	if s, ok := input.(engine.StorageEngine); ok {
 		wg := waitgroup.New()
+		schema := s.GetSchema()
 
 		// Do the work
-		for _, layerType := range types.MapLayers.Ordered() {
-			for square := range nationalgrid.NationalGridSquares {
+		for _, layerType := range schema.MapLayers.Ordered() {
+			for _, square := range schema.LayerSquares(layerType) {
 				task, _ := job.GetTask(fmt.Sprintf("%v_%v", layerType, square))
 				task.Start()
 
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rockwell-uk/go-progress/progress"
+
+	"go-uk-maps-import/database/engine"
+	"go-uk-maps-import/database/engine/sqlite"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/geoparquet"
+)
+
+// RowFunc is given each row read back from a layer, rec holds the values of
+// the fields of the layer and wkb the geometry
+type RowFunc func(square string, rec map[string]interface{}, wkb []byte) error
+
+// ReadBack reads every row of a layer from a storage engine, square by square
+func ReadBack(s engine.StorageEngine, layerType string, fn RowFunc) error {
+	var funcName string = "database.ReadBack"
+
+	var schema *types.Schema = s.GetSchema()
+
+	layerTypeFields, exists := schema.MapLayers[layerType]
+	if !exists {
+		return fmt.Errorf("%v: unknown layer %v", funcName, layerType)
+	}
+
+	var asBinary string = "ST_AsBinary"
+	if _, ok := s.(*sqlite.SQLite); ok {
+		asBinary = "AsBinary"
+	}
+
+	db := s.GetDB(layerType)
+
+	for _, square := range schema.LayerSquares(layerType) {
+		tableName := strings.ToLower(square)
+		query := fmt.Sprintf("SELECT %v, %v(ogc_geom) FROM %v",
+			strings.Join(layerTypeFields, ","),
+			asBinary,
+			s.GetTableName(fmt.Sprintf("%v.%v", layerType, tableName)),
+		)
+
+		rows, err := db.Queryx(query)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		for rows.Next() {
+			// By position, Postgres folds the column names to lower case
+			values, err := rows.SliceScan()
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("%v: %v", funcName, err.Error())
+			}
+
+			var rec = make(map[string]interface{}, len(layerTypeFields))
+			for n, field := range layerTypeFields {
+				rec[field] = values[n]
+			}
+
+			wkb, _ := values[len(layerTypeFields)].([]byte)
+
+			err = fn(square, rec, wkb)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("%v: %v", funcName, err.Error())
+			}
+		}
+
+		err = rows.Close()
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ExportGeoParquet reads every layer back from a storage engine and writes
+// it with a started exporter, SQLite is read from its database files
+func ExportGeoParquet(s engine.StorageEngine, e *geoparquet.Exporter) error {
+	var funcName string = "database.ExportGeoParquet"
+	var jobName string = "Exporting the databases to GeoParquet files"
+
+	var magnitude int = len(s.GetSchema().MapLayers)
+
+	if se, ok := s.(*sqlite.SQLite); ok {
+		err := se.SwitchToFiles()
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	// Export GeoParquet Job
+	var job progress.ProgressJob = &ExportGeoParquetJob{
+		Exporter: e,
+	}
+
+	return progress.RunJob(jobName, funcName, job, magnitude, s.GetSchema(), s)
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+
+	"go-uk-maps-import/database/types"
+)
+
+// The geometries are stored as WKB so ST_AsBinary returns them as they are
+func init() {
+	sql.Register("sqlite3_readback", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("ST_AsBinary", func(wkb []byte) []byte {
+				return wkb
+			}, true)
+		},
+	})
+}
+
+// testEngine is a storage engine over an in memory database
+type testEngine struct {
+	db     *sqlx.DB
+	schema *types.Schema
+}
+
+func (e testEngine) Connect() error                  { return nil }
+func (e testEngine) Cleardown() error                { return nil }
+func (e testEngine) Prepare() error                  { return nil }
+func (e testEngine) Stop() error                     { return e.db.Close() }
+func (e testEngine) GetDB(layerType string) *sqlx.DB { return e.db }
+func (e testEngine) GetSchema() *types.Schema        { return e.schema }
+func (e testEngine) GetTableName(batchInsertsKey string) string {
+	return fmt.Sprintf("`%v`", batchInsertsKey)
+}
+func (e testEngine) GetTableSQL(fullTableName, tableParams string, fields []string) (string, error) {
+	return "", nil
+}
+
+func TestReadBack(t *testing.T) {
+	db, err := sqlx.Open("sqlite3_readback", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a new database
+	db.SetMaxOpenConns(1)
+
+	s := testEngine{db, types.NewSchema()}
+	defer s.Stop()
+
+	layerType := "motorway_junction"
+	fields := s.schema.MapLayers[layerType]
+
+	for _, square := range s.schema.LayerSquares(layerType) {
+		_, err := db.Exec(fmt.Sprintf("CREATE TABLE %v (%v text, ogc_geom blob)",
+			s.GetTableName(fmt.Sprintf("%v.%v", layerType, strings.ToLower(square))),
+			strings.Join(fields, " text, "),
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inserts := map[string][]string{
+		"sd": {"a", "b"},
+		"se": {"c"},
+	}
+	for square, ids := range inserts {
+		for _, id := range ids {
+			_, err := db.Exec(fmt.Sprintf("INSERT INTO %v (ID, ogc_geom) VALUES (?, ?)", s.GetTableName(layerType+"."+square)), id, []byte(id))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var actual = make(map[string][]string)
+	err = ReadBack(s, layerType, func(square string, rec map[string]interface{}, wkb []byte) error {
+		if len(rec) != len(fields) {
+			t.Errorf("expected %v fields, got %v", len(fields), rec)
+		}
+
+		id := fmt.Sprintf("%s", rec["ID"])
+		if string(wkb) != id {
+			t.Errorf("%v: expected the geometry %v, got %v", id, id, wkb)
+		}
+
+		actual[strings.ToLower(square)] = append(actual[strings.ToLower(square)], id)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(inserts, actual) {
+		t.Errorf("expected %v, got %v", inserts, actual)
+	}
+
+	err = ReadBack(s, "unknown", func(square string, rec map[string]interface{}, wkb []byte) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error for an unknown layer")
+	}
+}
@@ -15,9 +15,22 @@ import (
 	"github.com/rockwell-uk/go-utils/sliceutils"
 )
 
+// Range is the rows expected in a table, a feature read from more than one
+// tile is written to the same rows by each so, unless the features that cross
+// tiles were merged, a table holds between the most rows generated by one
+// source and the sum of them
 type Range struct {
-	Min int
-	Max int
+	Min   int
+	Max   int
+	Exact bool
+}
+
+func (r Range) String() string {
+	if r.Exact {
+		return fmt.Sprintf("%v", r.Max)
+	}
+
+	return fmt.Sprintf("[Min:%v, Max:%v]", r.Min, r.Max)
 }
 
 func RowCountsCheck(dataFolder string, ratesInfo []rates.RateInfo, dbCounts map[string]int, getTableNameFunc func(string) string) []string {
@@ -26,7 +39,7 @@ func RowCountsCheck(dataFolder string, ratesInfo []rates.RateInfo, dbCounts map[
 
 	for _, rateInfo := range ratesInfo {
 		shapeFileShortName := strings.ReplaceAll(rateInfo.ShapeFile, dataFolder, "")
-		var dbName = GetDBNameFromFilename(rateInfo.ShapeFile)
+		var dbName = getLayerName(rateInfo)
 
 		for sq := range rateInfo.Rows {
 			fullTableName := getTableNameFunc(fmt.Sprintf("%v.%v", dbName, sq))
@@ -35,11 +48,10 @@ func RowCountsCheck(dataFolder string, ratesInfo []rates.RateInfo, dbCounts map[
 
 			if actual < targetRange.Min || actual > targetRange.Max {
 				message := fmt.Sprintf(
-					"%v: %v expected [Min:%v, Max:%v], actual %v",
+					"%v: %v expected %v, actual %v",
 					shapeFileShortName,
 					fullTableName,
-					targetRange.Min,
-					targetRange.Max,
+					targetRange,
 					actual,
 				)
 
@@ -85,31 +97,48 @@ func LogRowCountChecks(se engine.StorageEngine, datafolder string, rateInfo []ra
 	)
 }
 
+// getExpectedRange returns the rows expected in each table, the count is
+// exact when every source of the table had its features merged across tiles
 func getExpectedRange(ratesInfo []rates.RateInfo) map[string]Range {
 	var allrows = map[string][]int{}
+	var unmerged = map[string]bool{}
 	var ranges = map[string]Range{}
 
 	for _, rateInfo := range ratesInfo {
-		var dbName = GetDBNameFromFilename(rateInfo.ShapeFile)
+		var dbName = getLayerName(rateInfo)
 
 		for sq, rows := range rateInfo.Rows {
 			fullTableName := fmt.Sprintf("%v.%v", dbName, sq)
 
-			_, ok := allrows[fullTableName]
-			if !ok {
-				allrows[fullTableName] = []int{rows}
-			} else {
-				allrows[fullTableName] = append(allrows[fullTableName], rows)
+			allrows[fullTableName] = append(allrows[fullTableName], rows)
+			if !rateInfo.Merged {
+				unmerged[fullTableName] = true
 			}
 		}
 	}
 
 	for fullTableName, rows := range allrows {
-		ranges[fullTableName] = Range{
+		var r = Range{
 			Min: sliceutils.MaxInt(rows),
 			Max: sliceutils.SumInt(rows),
 		}
+		if !unmerged[fullTableName] {
+			r.Min = r.Max
+			r.Exact = true
+		}
+
+		ranges[fullTableName] = r
 	}
 
 	return ranges
 }
+
+// getLayerName is the layer a source was imported into, sources imported
+// through a layer mapping record their layer
+func getLayerName(rateInfo rates.RateInfo) string {
+	if rateInfo.Layer != "" {
+		return rateInfo.Layer
+	}
+
+	return GetDBNameFromFilename(rateInfo.ShapeFile)
+}
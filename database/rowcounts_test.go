@@ -45,6 +45,43 @@ func TestGetExpectedRange(t *testing.T) {
 				},
 			},
 		},
+		"TFTG AdministrativeBoundary Merged": {
+			// Imported with dedup on, the features crossing the tiles are counted
+			// against TF, the first tile they were read from
+			ratesInfo: mergedAdministrativeBoundary,
+			expected: map[string]Range{
+				"administrative_boundary.sk": {
+					Min:   58,
+					Max:   58,
+					Exact: true,
+				},
+				"administrative_boundary.ta": {
+					Min:   22,
+					Max:   22,
+					Exact: true,
+				},
+				"administrative_boundary.tf": {
+					Min:   17448,
+					Max:   17448,
+					Exact: true,
+				},
+				"administrative_boundary.tg": {
+					Min:   8081,
+					Max:   8081,
+					Exact: true,
+				},
+				"administrative_boundary.tl": {
+					Min:   40,
+					Max:   40,
+					Exact: true,
+				},
+				"administrative_boundary.tm": {
+					Min:   30,
+					Max:   30,
+					Exact: true,
+				},
+			},
+		},
 	}
 
 	for tname, tt := range tests {
@@ -1416,6 +1453,32 @@ func TestRowCountsCheck(t *testing.T) {
 			},
 			expected: []string{},
 		},
+		"TFTG AdministrativeBoundary Merged Complete": {
+			ratesInfo: mergedAdministrativeBoundary,
+			dbCounts: map[string]int{
+				"administrative_boundary.sk": 58,
+				"administrative_boundary.ta": 22,
+				"administrative_boundary.tf": 17448,
+				"administrative_boundary.tg": 8081,
+				"administrative_boundary.tl": 40,
+				"administrative_boundary.tm": 30,
+			},
+			expected: []string{},
+		},
+		"TFTG AdministrativeBoundary Merged Incomplete": {
+			ratesInfo: mergedAdministrativeBoundary,
+			dbCounts: map[string]int{
+				"administrative_boundary.sk": 58,
+				"administrative_boundary.ta": 22,
+				"administrative_boundary.tf": 17447,
+				"administrative_boundary.tg": 8081,
+				"administrative_boundary.tl": 40,
+				"administrative_boundary.tm": 30,
+			},
+			expected: []string{
+				"TF_AdministrativeBoundary.shp: administrative_boundary.tf expected 17448, actual 17447",
+			},
+		},
 	}
 
 	for tname, tt := range tests {
@@ -1427,6 +1490,12 @@ func TestRowCountsCheck(t *testing.T) {
 	}
 }
 
+// The rows of the TFTG administrative boundaries imported with dedup on
+var mergedAdministrativeBoundary = []rates.RateInfo{
+	{ShapeFile: "TF_AdministrativeBoundary.shp", Records: 16929, Rows: map[string]int{"sk": 58, "ta": 22, "tf": 17448, "tg": 27, "tl": 40}, Duration: 20990686702, Merged: true},
+	{ShapeFile: "TG_AdministrativeBoundary.shp", Records: 7884, Rows: map[string]int{"tg": 8054, "tm": 30}, Duration: 10264350555, Merged: true},
+}
+
 func prettyPrint(data interface{}) string {
 	var p []byte
 
@@ -0,0 +1,381 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The SQL dialects of the storage engines
+const (
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "pgsql"
+)
+
+// The logical types of a column
+const (
+	KindText    = "text"
+	KindInteger = "integer"
+	KindDecimal = "decimal"
+	KindFloat   = "float"
+	KindBoolean = "boolean"
+	KindDate    = "date"
+	KindEnum    = "enum"
+)
+
+// Column is the logical type of a field, its values are parsed when they are
+// read and, unlike the FieldTypes, it is written in the dialect of each engine
+type Column struct {
+	Kind     string
+	Length   int
+	Decimals int
+	Values   []string
+}
+
+// The typed OS fields
+var osColumns = map[string]Column{
+	"FEATCODE":   {Kind: KindInteger, Length: 5},
+	"HEIGHT":     {Kind: KindDecimal, Length: 8, Decimals: 2},
+	"FONTHEIGHT": {Kind: KindDecimal, Length: 6, Decimals: 2},
+	"ORIENTATIO": {Kind: KindDecimal, Length: 6, Decimals: 2},
+	"DRAWLEVEL":  {Kind: KindInteger, Length: 2},
+}
+
+// ColumnFromDBF maps a DBF field type C/N/F/L/D to a column
+func ColumnFromDBF(fieldType byte, length, decimals int) (Column, error) {
+	switch fieldType {
+	case 'C':
+		return Column{Kind: KindText, Length: length}, nil
+	case 'N':
+		if decimals > 0 {
+			return Column{Kind: KindDecimal, Length: length, Decimals: decimals}, nil
+		}
+		return Column{Kind: KindInteger, Length: length}, nil
+	case 'F':
+		return Column{Kind: KindFloat, Length: length, Decimals: decimals}, nil
+	case 'L':
+		return Column{Kind: KindBoolean}, nil
+	case 'D':
+		return Column{Kind: KindDate}, nil
+	}
+
+	return Column{}, fmt.Errorf("unsupported DBF field type %q", fieldType)
+}
+
+// SQLType is the column type in the dialect of an engine
+func (c Column) SQLType(dialect string) string {
+	var sqlType string
+
+	switch c.Kind {
+	case KindInteger:
+		switch {
+		case dialect == DialectSQLite:
+			sqlType = "integer"
+		case c.Length > 9:
+			sqlType = "bigint"
+		case dialect == DialectPostgres:
+			sqlType = "integer"
+		default:
+			sqlType = "int"
+		}
+	case KindDecimal:
+		if dialect == DialectSQLite {
+			sqlType = "real"
+		} else {
+			sqlType = fmt.Sprintf("decimal(%v,%v)", c.Length, c.Decimals)
+		}
+	case KindFloat:
+		switch dialect {
+		case DialectSQLite:
+			sqlType = "real"
+		case DialectPostgres:
+			sqlType = "double precision"
+		default:
+			sqlType = "double"
+		}
+	case KindBoolean:
+		switch dialect {
+		case DialectSQLite:
+			sqlType = "integer"
+		case DialectPostgres:
+			sqlType = "boolean"
+		default:
+			sqlType = "tinyint(1)"
+		}
+	case KindDate:
+		if dialect == DialectSQLite {
+			sqlType = "text"
+		} else {
+			sqlType = "date"
+		}
+	case KindEnum:
+		switch dialect {
+		case DialectSQLite:
+			sqlType = "text"
+		case DialectPostgres:
+			sqlType = fmt.Sprintf("varchar(%v)", c.maxValueLength())
+		default:
+			var values []string
+			for _, v := range c.Values {
+				values = append(values, "'"+enumEscaper.Replace(v)+"'")
+			}
+			sqlType = fmt.Sprintf("enum(%v)", strings.Join(values, ","))
+		}
+	default:
+		if dialect == DialectSQLite {
+			sqlType = "text"
+		} else {
+			sqlType = fmt.Sprintf("varchar(%v)", c.Length)
+		}
+	}
+
+	return sqlType + " DEFAULT NULL"
+}
+
+// Backslashes are escapes in MySQL strings
+var enumEscaper = strings.NewReplacer(`\`, `\\`, `'`, `''`)
+
+func (c Column) maxValueLength() int {
+	var length int = 1
+	for _, v := range c.Values {
+		if len(v) > length {
+			length = len(v)
+		}
+	}
+
+	return length
+}
+
+// AddColumn adds a typed field, each importer adds the fields of its mapping,
+// a field added again with the same kind is widened to hold the values of
+// both, a field cannot change kind
+func (s *Schema) AddColumn(field string, c Column) error {
+	if _, exists := s.FieldTypes[field]; exists {
+		return fmt.Errorf("field %v is already defined as %v", field, s.FieldTypes[field])
+	}
+
+	existing, exists := s.Columns[field]
+	if !exists {
+		s.Columns[field] = c
+		return nil
+	}
+
+	if existing.Kind != c.Kind {
+		return fmt.Errorf("field %v is already defined as %v", field, existing.Kind)
+	}
+
+	s.Columns[field] = widenColumn(existing, c)
+
+	return nil
+}
+
+func widenColumn(a, b Column) Column {
+	if b.Length > a.Length {
+		a.Length = b.Length
+	}
+	if b.Decimals > a.Decimals {
+		a.Decimals = b.Decimals
+	}
+
+	for _, v := range b.Values {
+		if !containsValue(a.Values, v) {
+			a.Values = append(append([]string{}, a.Values...), v)
+		}
+	}
+
+	return a
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasFieldType reports whether the type of a field is known
+func (s *Schema) HasFieldType(field string) bool {
+	_, typed := s.Columns[field]
+	_, exists := s.FieldTypes[field]
+
+	return typed || exists
+}
+
+// GetFieldType is the column type of a field in the dialect of an engine
+func (s *Schema) GetFieldType(field, dialect string) (string, bool) {
+	if c, exists := s.Columns[field]; exists {
+		return c.SQLType(dialect), true
+	}
+
+	fieldType, exists := s.FieldTypes[field]
+
+	return fieldType, exists
+}
+
+// FieldKind is the logical type of a field, the SQL type of a field that is
+// not typed is mapped to the nearest kind
+func (s *Schema) FieldKind(field string) string {
+	if c, exists := s.Columns[field]; exists {
+		return c.Kind
+	}
+
+	var sqlType string = strings.ToLower(strings.TrimSpace(s.FieldTypes[field]))
+	if strings.HasPrefix(sqlType, "tinyint(1)") {
+		return KindBoolean
+	}
+
+	name := strings.FieldsFunc(sqlType, func(r rune) bool {
+		return r == '(' || r == ' '
+	})
+	if len(name) == 0 {
+		return KindText
+	}
+
+	switch name[0] {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return KindInteger
+	case "decimal", "numeric":
+		return KindDecimal
+	case "float", "double", "real":
+		return KindFloat
+	case "bool", "boolean":
+		return KindBoolean
+	case "date":
+		return KindDate
+	}
+
+	return KindText
+}
+
+// ParseColumn reads a logical type written as kind or kind(args) e.g.
+// integer, decimal(10,2), text(50) or enum(Small,Medium,Large), false is
+// returned for anything else
+func ParseColumn(s string) (Column, bool) {
+	s = strings.TrimSpace(s)
+
+	var kind, args string = strings.ToLower(s), ""
+	if open := strings.Index(s, "("); open > 0 && strings.HasSuffix(s, ")") {
+		kind, args = strings.ToLower(s[:open]), s[open+1:len(s)-1]
+	}
+
+	var params []string
+	if args != "" {
+		params = strings.Split(args, ",")
+		for n := range params {
+			params[n] = strings.TrimSpace(params[n])
+		}
+	}
+
+	var c = Column{Kind: kind}
+	var err error
+
+	switch kind {
+	case KindText, KindInteger:
+		if len(params) > 1 {
+			return Column{}, false
+		}
+		if len(params) == 1 {
+			c.Length, err = strconv.Atoi(params[0])
+		} else if kind == KindText {
+			c.Length = 255
+		}
+	case KindDecimal:
+		if len(params) != 2 {
+			return Column{}, false
+		}
+		c.Length, err = strconv.Atoi(params[0])
+		if err == nil {
+			c.Decimals, err = strconv.Atoi(params[1])
+		}
+	case KindFloat, KindBoolean, KindDate:
+		if len(params) > 0 {
+			return Column{}, false
+		}
+	case KindEnum:
+		if len(params) == 0 {
+			return Column{}, false
+		}
+		c.Values = params
+	default:
+		return Column{}, false
+	}
+
+	if err != nil {
+		return Column{}, false
+	}
+
+	return c, true
+}
+
+// Parse converts a value read from a source to the type of the column, empty
+// values are NULL, an invalid value is returned as NULL with an error, text
+// longer than the column is truncated
+func (c Column) Parse(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	var s string = strings.TrimSpace(fmt.Sprintf("%v", value))
+	if s == "" {
+		return nil, nil
+	}
+
+	switch c.Kind {
+	case KindInteger:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		// Some sources write whole numbers with decimals e.g. 25200.0000
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+		return nil, fmt.Errorf("%q is not an integer", s)
+
+	case KindDecimal, KindFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return f, nil
+
+	case KindBoolean:
+		switch strings.ToUpper(s) {
+		case "T", "Y", "TRUE", "YES", "1":
+			return true, nil
+		case "F", "N", "FALSE", "NO", "0":
+			return false, nil
+		case "?":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", s)
+
+	case KindDate:
+		for _, layout := range []string{"20060102", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format("2006-01-02"), nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a date", s)
+
+	case KindEnum:
+		for _, v := range c.Values {
+			if v == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %v", s, c.Values)
+	}
+
+	if c.Length > 0 && utf8.RuneCountInString(s) > c.Length {
+		return string([]rune(s)[:c.Length]), fmt.Errorf("%q is longer than %v", s, c.Length)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"fmt"
+)
+
+// AddDerivedField extends the schema of a layer, and its generalised
+// companions, with a column that is not in the source files
+func (s *Schema) AddDerivedField(layerType, field, fieldType string) error {
+	if _, exists := s.MapLayers[layerType]; !exists {
+		return fmt.Errorf("unknown layer %v", layerType)
+	}
+
+	if existing, exists := s.FieldTypes[field]; exists && existing != fieldType {
+		return fmt.Errorf("field %v is already defined as %v", field, existing)
+	}
+
+	// Transformers may declare the same field for a layer
+	if s.IsDerivedField(layerType, field) {
+		return nil
+	}
+
+	err := s.addDerived(layerType, field)
+	if err != nil {
+		return err
+	}
+
+	s.FieldTypes[field] = fieldType
+
+	return nil
+}
+
+// AddDerivedColumn is AddDerivedField for a typed field, which is written in
+// the dialect of each engine
+func (s *Schema) AddDerivedColumn(layerType, field string, c Column) error {
+	if _, exists := s.MapLayers[layerType]; !exists {
+		return fmt.Errorf("unknown layer %v", layerType)
+	}
+
+	err := s.AddColumn(field, c)
+	if err != nil {
+		return err
+	}
+
+	if s.IsDerivedField(layerType, field) {
+		return nil
+	}
+
+	return s.addDerived(layerType, field)
+}
+
+func (s *Schema) addDerived(layerType, field string) error {
+	for _, f := range s.MapLayers[layerType] {
+		if f == field {
+			return fmt.Errorf("field %v already exists in layer %v", field, layerType)
+		}
+	}
+
+	s.DerivedFields[layerType] = append(s.DerivedFields[layerType], field)
+
+	s.MapLayers[layerType] = append(s.MapLayers[layerType], field)
+	for companion, base := range s.GeneralisedLayers {
+		if base == layerType {
+			s.MapLayers[companion] = append(s.MapLayers[companion], field)
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) IsDerivedField(layerType, field string) bool {
+	for _, f := range s.DerivedFields[layerType] {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"fmt"
+)
+
+// The area and line layers worth generalising for small scale maps
+var GeneralisableLayers = []string{
+	"administrative_boundary",
+	"foreshore",
+	"railway_track",
+	"road",
+	"surface_water_area",
+	"surface_water_line",
+	"tidal_boundary",
+	"tidal_water",
+	"woodland",
+}
+
+// GeneralisedLayerName is the companion layer of a layer at a tolerance in metres e.g. woodland_g25
+func GeneralisedLayerName(layerType string, tolerance int) string {
+	return fmt.Sprintf("%v_g%v", layerType, tolerance)
+}
+
+// AddGeneralisedLayers registers the companion layers so every engine creates
+// their databases and tables alongside the full detail layers
+func (s *Schema) AddGeneralisedLayers(tolerances []int) {
+	for _, layerType := range GeneralisableLayers {
+		for _, tolerance := range tolerances {
+			companion := GeneralisedLayerName(layerType, tolerance)
+
+			s.MapLayers[companion] = append(LayerType{}, s.MapLayers[layerType]...)
+			s.GeneralisedLayers[companion] = layerType
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"fmt"
+)
+
+// AddLayer registers a layer that is not part of the OS data, fieldTypes
+// holds the SQL type of each field, the ID and GRIDREF fields are added
+func (s *Schema) AddLayer(layerType string, fields []string, fieldTypes map[string]string, partitioned bool) error {
+	var layer = LayerType{"ID", "GRIDREF"}
+	for _, field := range fields {
+		if field != "ID" && field != "GRIDREF" {
+			layer = append(layer, field)
+		}
+	}
+
+	// A layer may be registered again with the same fields
+	if existing, exists := s.MapLayers[layerType]; exists {
+		if !s.CustomLayers[layerType] || !sameFields(s.withoutDerived(layerType, existing), layer) || s.UnpartitionedLayers[layerType] == partitioned {
+			return fmt.Errorf("layer %v already exists", layerType)
+		}
+		return nil
+	}
+
+	for _, field := range layer {
+		existing, exists := s.FieldTypes[field]
+		_, inferred := s.Columns[field]
+		if fieldType, typed := fieldTypes[field]; typed && exists && existing != fieldType {
+			return fmt.Errorf("field %v is already defined as %v", field, existing)
+		}
+		if fieldTypes[field] != "" && inferred {
+			return fmt.Errorf("field %v is already inferred from a DBF", field)
+		}
+		if !exists && !inferred && fieldTypes[field] == "" {
+			return fmt.Errorf("field %v has no type", field)
+		}
+	}
+
+	for _, field := range layer {
+		if _, exists := s.FieldTypes[field]; !exists && fieldTypes[field] != "" {
+			s.FieldTypes[field] = fieldTypes[field]
+		}
+	}
+
+	s.MapLayers[layerType] = layer
+	s.CustomLayers[layerType] = true
+	if !partitioned {
+		s.UnpartitionedLayers[layerType] = true
+	}
+
+	return nil
+}
+
+func (s *Schema) withoutDerived(layerType string, fields LayerType) LayerType {
+	var layer LayerType
+	for _, field := range fields {
+		if !s.IsDerivedField(layerType, field) {
+			layer = append(layer, field)
+		}
+	}
+
+	return layer
+}
+
+func sameFields(a, b LayerType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -20,7 +20,8 @@ func (l LayerTypes) Ordered() []string {
 	return keys
 }
 
-var MapLayers = LayerTypes{
+// The fields of each OS layer
+var osMapLayers = LayerTypes{
 	"administrative_boundary":       {"ID", "GRIDREF", "CLASSIFICA", "FEATCODE"},
 	"building":                      {"ID", "GRIDREF", "FEATCODE"},
 	"electricity_transmission_line": {"ID", "GRIDREF", "FEATCODE"},
@@ -43,3 +44,10 @@ var MapLayers = LayerTypes{
 	"tidal_water":                   {"ID", "GRIDREF", "FEATCODE"},
 	"woodland":                      {"ID", "GRIDREF", "FEATCODE"},
 }
+
+// OSLayer returns the fields of an OS layer as they are in its sources
+func OSLayer(layerType string) (LayerType, bool) {
+	fields, exists := osMapLayers[layerType]
+
+	return fields, exists
+}
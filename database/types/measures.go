@@ -0,0 +1,131 @@
+package types
+
+import (
+	"strings"
+)
+
+// The columns of the geometry measures, in metres as the OS layers are in
+// British National Grid
+const (
+	AreaField      = "area_m2"
+	LengthField    = "length_m"
+	CentroidXField = "centroid_x"
+	CentroidYField = "centroid_y"
+	LabelXField    = "label_x"
+	LabelYField    = "label_y"
+)
+
+// The dimension of the geometries of a layer
+const (
+	DimensionUnknown = -1
+	DimensionPoint   = 0
+	DimensionLine    = 1
+	DimensionPolygon = 2
+)
+
+var (
+	AreaColumn       = Column{Kind: KindDecimal, Length: 14, Decimals: 2}
+	LengthColumn     = Column{Kind: KindDecimal, Length: 12, Decimals: 2}
+	CoordinateColumn = Column{Kind: KindDecimal, Length: 9, Decimals: 2}
+)
+
+// The dimension of the geometries of each OS layer
+var osLayerDimensions = map[string]int{
+	"administrative_boundary":       DimensionLine,
+	"building":                      DimensionPolygon,
+	"electricity_transmission_line": DimensionLine,
+	"foreshore":                     DimensionPolygon,
+	"functional_site":               DimensionPolygon,
+	"glasshouse":                    DimensionPolygon,
+	"motorway_junction":             DimensionPoint,
+	"named_place":                   DimensionPoint,
+	"ornament":                      DimensionPolygon,
+	"railway_station":               DimensionPoint,
+	"railway_track":                 DimensionLine,
+	"railway_tunnel":                DimensionLine,
+	"road_tunnel":                   DimensionLine,
+	"road":                          DimensionLine,
+	"roundabout":                    DimensionPoint,
+	"spot_height":                   DimensionPoint,
+	"surface_water_area":            DimensionPolygon,
+	"surface_water_line":            DimensionLine,
+	"tidal_boundary":                DimensionLine,
+	"tidal_water":                   DimensionPolygon,
+	"woodland":                      DimensionPolygon,
+}
+
+// The SRID of British National Grid
+const BNGSRID = 27700
+
+// LayerSRID is the SRID of the geometries of a layer, 0 when it is not known
+func (s *Schema) LayerSRID(layerType string) int {
+	if s.UnmeasuredLayers[layerType] {
+		return 0
+	}
+
+	return BNGSRID
+}
+
+// LayerDimension is the dimension of the geometries of a layer, custom
+// layers have the geometry type read from their .shp headers
+func (s *Schema) LayerDimension(layerType string) int {
+	if base, isCompanion := s.GeneralisedLayers[layerType]; isCompanion {
+		layerType = base
+	}
+
+	if dimension, exists := osLayerDimensions[layerType]; exists {
+		return dimension
+	}
+
+	geometryType := s.GeometryTypes[layerType]
+	switch {
+	case strings.HasPrefix(geometryType, "Polygon"):
+		return DimensionPolygon
+	case strings.HasPrefix(geometryType, "PolyLine"):
+		return DimensionLine
+	case strings.HasPrefix(geometryType, "Point"), strings.HasPrefix(geometryType, "MultiPoint"):
+		return DimensionPoint
+	}
+
+	return DimensionUnknown
+}
+
+// MeasureFields are the measure columns of a layer, the area of polygons,
+// the length of lines and the centroid and label point of both, points are
+// not measured
+func (s *Schema) MeasureFields(layerType string) []string {
+	if s.UnmeasuredLayers[layerType] {
+		return nil
+	}
+
+	switch s.LayerDimension(layerType) {
+	case DimensionPolygon:
+		return []string{AreaField, CentroidXField, CentroidYField, LabelXField, LabelYField}
+	case DimensionLine:
+		return []string{LengthField, CentroidXField, CentroidYField, LabelXField, LabelYField}
+	}
+
+	return nil
+}
+
+// IsMeasureField reports whether a field is one of the measure columns
+func IsMeasureField(field string) bool {
+	switch field {
+	case AreaField, LengthField, CentroidXField, CentroidYField, LabelXField, LabelYField:
+		return true
+	}
+
+	return false
+}
+
+// MeasureColumn is the column type of a measure field
+func MeasureColumn(field string) Column {
+	switch field {
+	case AreaField:
+		return AreaColumn
+	case LengthField:
+		return LengthColumn
+	}
+
+	return CoordinateColumn
+}
@@ -0,0 +1,149 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// The schema of the lookup tables, SQLite has them in each layer database
+	ReferenceSchema     = "reference"
+	FeatureCodeTable    = "featcode"
+	ClassificationTable = "classification"
+)
+
+// FeatureCode describes a VMD feature code, the colour and z order are style
+// hints for rendering, features are drawn in ascending z order
+type FeatureCode struct {
+	Code        int
+	Description string
+	Layer       string
+	Colour      string
+	ZOrder      int
+}
+
+// Classification describes a CLASSIFICA value of a layer
+type Classification struct {
+	Layer  string
+	Name   string
+	Colour string
+	ZOrder int
+}
+
+// The VMD feature code catalogue, by layer
+var FeatureCodes = []FeatureCode{
+	{25014, "Building", "building", "#e6d5c3", 40},
+	{25016, "Glasshouse", "glasshouse", "#d9e6ef", 41},
+	{25102, "Electricity Transmission Line", "electricity_transmission_line", "#8c8c8c", 65},
+	{25200, "National", "administrative_boundary", "#7b3294", 95},
+	{25201, "District Or London Borough", "administrative_boundary", "#a66fbf", 93},
+	{25202, "County, Region Or Island", "administrative_boundary", "#9354ad", 94},
+	{25203, "Parish Or Community", "administrative_boundary", "#c2a5cf", 92},
+	{25204, "Mean High Water", "tidal_boundary", "#6baed6", 12},
+	{25205, "Mean Low Water", "tidal_boundary", "#9ecae1", 12},
+	{25240, "Ornament", "ornament", "#b3b3b3", 30},
+	{25250, "Air Transport", "functional_site", "#dadaeb", 35},
+	{25251, "Education Facility", "functional_site", "#f2e6b8", 35},
+	{25252, "Medical Care Accommodation", "functional_site", "#f4cccc", 35},
+	{25253, "Road User Services", "functional_site", "#e5e5e5", 35},
+	{25254, "Water Transport", "functional_site", "#c6dbef", 35},
+	{25300, "Multi Track", "railway_track", "#5a5a5a", 60},
+	{25301, "Single Track", "railway_track", "#5a5a5a", 60},
+	{25302, "Narrow Gauge Or Light Rapid Transit Track", "railway_track", "#7a7a7a", 59},
+	{25303, "Railway Tunnel", "railway_tunnel", "#9a9a9a", 58},
+	{25420, "Railway Station", "railway_station", "#d52b1e", 80},
+	{25422, "Light Rapid Transit Station", "railway_station", "#0098d4", 80},
+	{25423, "London Underground Station", "railway_station", "#dc241f", 80},
+	{25560, "Spot Height", "spot_height", "#6b4f2a", 85},
+	{25600, "Surface Water Line", "surface_water_line", "#9fc7e5", 20},
+	{25608, "Tidal Water", "tidal_water", "#9fc7e5", 10},
+	{25609, "Surface Water Area", "surface_water_area", "#9fc7e5", 20},
+	{25612, "Foreshore", "foreshore", "#e8e4c9", 11},
+	{25710, "Motorway", "road", "#4f81bd", 79},
+	{25719, "Motorway, Collapsed Dual Carriageway", "road", "#4f81bd", 79},
+	{25723, "Primary Road", "road", "#4e9a47", 78},
+	{25724, "Primary Road, Collapsed Dual Carriageway", "road", "#4e9a47", 78},
+	{25729, "A Road", "road", "#e0474c", 77},
+	{25730, "A Road, Collapsed Dual Carriageway", "road", "#e0474c", 77},
+	{25743, "B Road", "road", "#f2a93b", 76},
+	{25744, "B Road, Collapsed Dual Carriageway", "road", "#f2a93b", 76},
+	{25750, "Minor Road", "road", "#fbe08b", 75},
+	{25751, "Minor Road, Collapsed Dual Carriageway", "road", "#fbe08b", 75},
+	{25760, "Local Street", "road", "#ffffff", 74},
+	{25780, "Private Road Publicly Accessible", "road", "#f5f5f5", 73},
+	{25790, "Pedestrianised Street", "road", "#efe7dc", 72},
+	{25792, "Road Tunnel", "road_tunnel", "#bdbdbd", 71},
+	{25796, "Motorway Junction", "motorway_junction", "#4f81bd", 90},
+	{25797, "Roundabout", "roundabout", "#fbe08b", 75},
+	{25801, "Populated Place", "named_place", "#000000", 100},
+	{25802, "Landform", "named_place", "#6b4f2a", 100},
+	{25803, "Woodland Or Forest", "named_place", "#31a354", 100},
+	{25804, "Hydrography", "named_place", "#2171b5", 100},
+	{25805, "Landcover", "named_place", "#636363", 100},
+	{25999, "Woodland", "woodland", "#c8dfb4", 15},
+}
+
+// The CLASSIFICA values of the layers that have a fixed set of them
+var Classifications = []Classification{
+	{"road", "Motorway", "#4f81bd", 79},
+	{"road", "Primary Road", "#4e9a47", 78},
+	{"road", "A Road", "#e0474c", 77},
+	{"road", "B Road", "#f2a93b", 76},
+	{"road", "Minor Road", "#fbe08b", 75},
+	{"road", "Local Street", "#ffffff", 74},
+	{"road", "Private Road Publicly Accessible", "#f5f5f5", 73},
+	{"road", "Pedestrianised Street", "#efe7dc", 72},
+	{"railway_track", "Multi Track", "#5a5a5a", 60},
+	{"railway_track", "Single Track", "#5a5a5a", 60},
+	{"railway_track", "Narrow Gauge Or Light Rapid Transit Track", "#7a7a7a", 59},
+	{"railway_station", "Railway Station", "#d52b1e", 80},
+	{"railway_station", "Light Rapid Transit Station", "#0098d4", 80},
+	{"railway_station", "London Underground Station", "#dc241f", 80},
+}
+
+// HasReferenceTables reports whether the lookup tables are created, the
+// foreign keys reference them
+func (s *Schema) HasReferenceTables() bool {
+	return s.ForeignKeys || s.LookupTables
+}
+
+// IsFeatureCode reports whether a code is in the catalogue
+func IsFeatureCode(code int) bool {
+	for _, f := range FeatureCodes {
+		if f.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckReference returns an error for a value its column cannot reference,
+// only the FEATCODE is checked and only when foreign keys are enabled
+func (s *Schema) CheckReference(field string, value interface{}) error {
+	if !s.ForeignKeys || field != "FEATCODE" || value == nil {
+		return nil
+	}
+
+	code, err := strconv.Atoi(fmt.Sprintf("%v", value))
+	if err != nil || !IsFeatureCode(code) {
+		return fmt.Errorf("%v is not in the feature code catalogue", value)
+	}
+
+	return nil
+}
+
+// HasForeignKey reports whether a layer table references the feature codes
+func (s *Schema) HasForeignKey(fields []string) bool {
+	if !s.ForeignKeys {
+		return false
+	}
+
+	for _, field := range fields {
+		if field == "FEATCODE" {
+			return true
+		}
+	}
+
+	return false
+}
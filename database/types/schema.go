@@ -0,0 +1,73 @@
+package types
+
+// Schema is the layer definitions, each importer extends its own schema and
+// the storage engine creates its tables from it
+type Schema struct {
+	// The fields of each layer
+	MapLayers LayerTypes
+
+	// The fields added to each layer that do not come from the source files
+	DerivedFields LayerTypes
+
+	// The SQL type of the fields that are not typed
+	FieldTypes map[string]string
+
+	// The typed fields, including those inferred from the DBF of sources
+	// that are not OS data, the other fields are in FieldTypes
+	Columns map[string]Column
+
+	// The geometry type of each layer read from its .shp headers e.g. Polygon
+	GeometryTypes map[string]string
+
+	// The generalised companion layers and the layer they are generalised from
+	GeneralisedLayers map[string]string
+
+	// The layers registered by AddLayer, they are not part of the OS releases
+	CustomLayers map[string]bool
+
+	// The custom layers whose sources are not in British National Grid, their
+	// coordinates are not in metres so they are not measured
+	UnmeasuredLayers map[string]bool
+
+	// The layers stored in a single table rather than a table per 100km square
+	UnpartitionedLayers map[string]bool
+
+	// Whether the FEATCODE of each layer table references the feature codes
+	ForeignKeys bool
+
+	// Whether the lookup tables are created without foreign keys
+	LookupTables bool
+}
+
+// NewSchema returns the layer definitions of the OS releases
+func NewSchema() *Schema {
+	return &Schema{
+		MapLayers:           copyLayerTypes(osMapLayers),
+		DerivedFields:       LayerTypes{},
+		FieldTypes:          copyMap(osFieldTypes),
+		Columns:             copyMap(osColumns),
+		GeometryTypes:       map[string]string{},
+		GeneralisedLayers:   map[string]string{},
+		CustomLayers:        map[string]bool{},
+		UnmeasuredLayers:    map[string]bool{},
+		UnpartitionedLayers: map[string]bool{},
+	}
+}
+
+func copyLayerTypes(l LayerTypes) LayerTypes {
+	var c = make(LayerTypes, len(l))
+	for layerType, fields := range l {
+		c[layerType] = append(LayerType{}, fields...)
+	}
+
+	return c
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	var c = make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// An SQL column type e.g. varchar(50) DEFAULT NULL or int unsigned NOT NULL
+var sqlTypeRegex = regexp.MustCompile(`(?i)^([a-z][a-z0-9 ]*?)\s*(\(\s*\d+\s*(?:,\s*\d+\s*)?\))?(\s+unsigned)?(\s+(?:not\s+)?null|\s+default\s+null)?$`)
+
+// The column types each dialect accepts, by type name
+var sqlTypeNames = map[string]map[string]bool{
+	DialectMySQL: {
+		"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true,
+		"decimal": true, "numeric": true, "float": true, "double": true, "real": true, "bit": true,
+		"bool": true, "boolean": true,
+		"char": true, "varchar": true, "tinytext": true, "text": true, "mediumtext": true, "longtext": true,
+		"binary": true, "varbinary": true, "tinyblob": true, "blob": true, "mediumblob": true, "longblob": true,
+		"date": true, "time": true, "datetime": true, "timestamp": true, "year": true, "json": true,
+	},
+	DialectPostgres: {
+		"smallint": true, "int": true, "integer": true, "bigint": true, "int2": true, "int4": true, "int8": true,
+		"decimal": true, "numeric": true, "real": true, "float": true, "float4": true, "float8": true,
+		"double precision": true, "bool": true, "boolean": true,
+		"char": true, "character": true, "varchar": true, "character varying": true, "text": true, "bytea": true,
+		"date": true, "time": true, "timestamp": true, "timestamptz": true, "json": true, "jsonb": true, "uuid": true,
+	},
+	DialectSQLite: {
+		"tinyint": true, "smallint": true, "int": true, "integer": true, "bigint": true,
+		"decimal": true, "numeric": true, "real": true, "float": true, "double": true, "boolean": true,
+		"char": true, "varchar": true, "text": true, "blob": true, "date": true, "datetime": true,
+	},
+}
+
+// ValidateSQLType checks that a column type given as SQL is one the dialect
+// of the storage engine accepts, the engines use it as it is
+func ValidateSQLType(sqlType, dialect string) error {
+	names, exists := sqlTypeNames[dialect]
+	if !exists {
+		return fmt.Errorf("unknown dialect %v", dialect)
+	}
+
+	m := sqlTypeRegex.FindStringSubmatch(strings.TrimSpace(sqlType))
+	if m == nil {
+		return fmt.Errorf("%q is not an SQL column type", sqlType)
+	}
+
+	name := strings.ToLower(strings.Join(strings.Fields(m[1]), " "))
+	if !names[name] || (m[3] != "" && dialect != DialectMySQL) {
+		return fmt.Errorf("%q is not a %v column type", sqlType, dialect)
+	}
+
+	return nil
+}
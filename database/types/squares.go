@@ -0,0 +1,35 @@
+package types
+
+import (
+	"sort"
+
+	"github.com/rockwell-uk/go-nationalgrid"
+)
+
+// The square of the single table of a layer that is not partitioned
+const UnpartitionedSquare = "GB"
+
+// LayerSquares returns the (upper case) squares a layer has a table for
+func (s *Schema) LayerSquares(layerType string) []string {
+	if s.UnpartitionedLayers[layerType] {
+		return []string{UnpartitionedSquare}
+	}
+
+	squares := make([]string, 0, len(nationalgrid.NationalGridSquares))
+	for square := range nationalgrid.NationalGridSquares {
+		squares = append(squares, square)
+	}
+	sort.Strings(squares)
+
+	return squares
+}
+
+// NumTables is the number of tables across every layer
+func (s *Schema) NumTables() int {
+	var n int
+	for layerType := range s.MapLayers {
+		n += len(s.LayerSquares(layerType))
+	}
+
+	return n
+}
@@ -0,0 +1,75 @@
+package flatgeobuf
+
+import (
+	"fmt"
+	"time"
+
+	"go-uk-maps-import/database/types"
+)
+
+// columnType is the column type of a kind of field, dates are DateTime
+// strings
+func columnType(kind string) byte {
+	switch kind {
+	case types.KindInteger:
+		return columnLong
+	case types.KindDecimal, types.KindFloat:
+		return columnDouble
+	case types.KindBoolean:
+		return columnBool
+	case types.KindDate:
+		return columnDateTime
+	}
+
+	return columnString
+}
+
+// convert returns a value as the Go type written for its kind, values of
+// other types e.g. numbers read back as text are parsed as the kind, nil is
+// returned for an empty value
+func convert(kind string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		value = string(v)
+	case time.Time:
+		value = v.Format("2006-01-02")
+	}
+
+	switch kind {
+	case types.KindInteger:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		}
+
+	case types.KindDecimal, types.KindFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		}
+
+	case types.KindBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		}
+
+	case types.KindDate:
+
+	default:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(value), nil
+	}
+
+	return types.Column{Kind: kind}.Parse(value)
+}
@@ -0,0 +1,363 @@
+package flatgeobuf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// A FlatGeobuf file is the magic bytes, the header, the packed Hilbert
+// R-tree index and the features in the order of the index
+// ref: https://flatgeobuf.org/
+
+const (
+	DefaultFolder = "fgb"
+
+	Ext = ".fgb"
+
+	tmpExt     = ".tmp"
+	bufferSize = 64 * 1024
+)
+
+var magicBytes = []byte{0x66, 0x67, 0x62, 0x03, 0x66, 0x67, 0x62, 0x00}
+
+// The column types used for the kinds of field
+const (
+	columnBool     = 2
+	columnLong     = 7
+	columnDouble   = 10
+	columnString   = 11
+	columnDateTime = 13
+)
+
+// Field is a property of the features, its kind is one of the types.Kind
+// logical types
+type Field struct {
+	Name string
+	Kind string
+}
+
+// Writer writes the features of a layer to a FlatGeobuf file, the features
+// are written to a temporary file until Close sorts them for the index
+type Writer struct {
+	NodeSize int
+
+	path      string
+	name      string
+	fields    []Field
+	srid      int
+	tmp       *os.File
+	tw        *bufio.Writer
+	tmpSize   int64
+	items     []item
+	b         *flatbuffers.Builder
+	props     bytes.Buffer
+	geomTypes map[byte]bool
+	hasZ      bool
+}
+
+// item is a feature in the temporary file
+type item struct {
+	bbox    nodeItem
+	offset  int64
+	size    uint32
+	hilbert uint32
+}
+
+// Create starts the file of a layer, srid is the EPSG code of the CRS of the
+// geometries, 0 if it is not known
+func Create(path, name string, fields []Field, srid int) (*Writer, error) {
+	var funcName string = "flatgeobuf.Create"
+
+	tmp, err := os.Create(path + tmpExt)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return &Writer{
+		NodeSize:  DefaultNodeSize,
+		path:      path,
+		name:      name,
+		fields:    fields,
+		srid:      srid,
+		tmp:       tmp,
+		tw:        bufio.NewWriterSize(tmp, bufferSize),
+		b:         flatbuffers.NewBuilder(1024),
+		geomTypes: make(map[byte]bool),
+	}, nil
+}
+
+// Write adds a feature, values are in the order of the fields, a feature
+// without a geometry cannot be indexed so is left out
+func (w *Writer) Write(values []interface{}, wkb []byte) error {
+	var funcName string = "flatgeobuf.Write"
+
+	if len(values) != len(w.fields) {
+		return fmt.Errorf("%v: %v values for %v fields", funcName, len(values), len(w.fields))
+	}
+
+	if wkb == nil {
+		return nil
+	}
+
+	g, err := parseWKB(wkb)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	bbox, ok := g.bounds()
+	if !ok {
+		return nil
+	}
+
+	err = w.properties(values)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	w.b.Reset()
+	geom := g.build(w.b)
+	var props flatbuffers.UOffsetT
+	if w.props.Len() > 0 {
+		props = w.b.CreateByteVector(w.props.Bytes())
+	}
+	w.b.StartObject(3)
+	w.b.PrependUOffsetTSlot(0, geom, 0)
+	if props != 0 {
+		w.b.PrependUOffsetTSlot(1, props, 0)
+	}
+	w.b.FinishSizePrefixed(w.b.EndObject())
+	feature := w.b.FinishedBytes()
+
+	_, err = w.tw.Write(feature)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	w.items = append(w.items, item{
+		bbox:   bbox,
+		offset: w.tmpSize,
+		size:   uint32(len(feature)),
+	})
+	w.tmpSize += int64(len(feature))
+
+	w.geomTypes[g.geomType] = true
+	w.hasZ = w.hasZ || g.hasZ()
+
+	return nil
+}
+
+// properties encodes the values as the index of their column followed by
+// the value, nulls are left out
+func (w *Writer) properties(values []interface{}) error {
+	w.props.Reset()
+
+	for n, value := range values {
+		v, err := convert(w.fields[n].Kind, value)
+		if err != nil {
+			return fmt.Errorf("%v %v", w.fields[n].Name, err.Error())
+		}
+		if v == nil {
+			continue
+		}
+
+		var b [8]byte
+		binary.LittleEndian.PutUint16(b[:], uint16(n))
+		w.props.Write(b[:2])
+
+		switch v := v.(type) {
+		case bool:
+			if v {
+				w.props.WriteByte(1)
+			} else {
+				w.props.WriteByte(0)
+			}
+		case int64:
+			binary.LittleEndian.PutUint64(b[:], uint64(v))
+			w.props.Write(b[:])
+		case float64:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+			w.props.Write(b[:])
+		case string:
+			binary.LittleEndian.PutUint32(b[:], uint32(len(v)))
+			w.props.Write(b[:4])
+			w.props.WriteString(v)
+		}
+	}
+
+	return nil
+}
+
+// NumFeatures is the number of features written
+func (w *Writer) NumFeatures() int {
+	return len(w.items)
+}
+
+// Close sorts the features along a Hilbert curve and writes the file with
+// its index
+func (w *Writer) Close() error {
+	var funcName string = "flatgeobuf.Close"
+
+	defer os.Remove(w.tmp.Name())
+	defer w.tmp.Close()
+
+	err := w.tw.Flush()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var extent nodeItem = emptyNode()
+	for _, it := range w.items {
+		extent.expand(it.bbox)
+	}
+
+	var nodes []nodeItem
+	if len(w.items) > 0 {
+		hilbertSort(w.items, extent)
+
+		var leaves = make([]nodeItem, len(w.items))
+		var offset uint64
+		for i, it := range w.items {
+			leaves[i] = it.bbox
+			leaves[i].offset = offset
+			offset += uint64(it.size)
+		}
+
+		nodes = packedTree(leaves, w.NodeSize)
+	}
+
+	f, err := os.Create(w.path)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriterSize(f, bufferSize)
+
+	_, err = bw.Write(magicBytes)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	_, err = bw.Write(w.header(extent))
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	for _, node := range nodes {
+		err := node.write(bw)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	var feature []byte
+	for _, it := range w.items {
+		if cap(feature) < int(it.size) {
+			feature = make([]byte, it.size)
+		}
+		feature = feature[:it.size]
+
+		_, err := w.tmp.ReadAt(feature, it.offset)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		_, err = bw.Write(feature)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return f.Close()
+}
+
+// Discard drops a file that could not be finished, removing the temporary
+// features and any part of the file written by Close
+func (w *Writer) Discard() error {
+	var funcName string = "flatgeobuf.Discard"
+
+	w.tmp.Close()
+
+	for _, path := range []string{w.tmp.Name(), w.path} {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// header is the size prefixed Header table, the geometry type is unknown
+// when the features have more than one
+func (w *Writer) header(extent nodeItem) []byte {
+	b := flatbuffers.NewBuilder(1024)
+
+	name := b.CreateString(w.name)
+
+	var columns = make([]flatbuffers.UOffsetT, len(w.fields))
+	for i, f := range w.fields {
+		columnName := b.CreateString(f.Name)
+		b.StartObject(11)
+		b.PrependUOffsetTSlot(0, columnName, 0)
+		b.PrependByteSlot(1, columnType(f.Kind), 0)
+		columns[i] = b.EndObject()
+	}
+	columnsVector := b.CreateVectorOfTables(columns)
+
+	var envelope flatbuffers.UOffsetT
+	if len(w.items) > 0 {
+		envelope = float64Vector(b, []float64{extent.minX, extent.minY, extent.maxX, extent.maxY})
+	}
+
+	var crs flatbuffers.UOffsetT
+	if w.srid != 0 {
+		org := b.CreateString("EPSG")
+		b.StartObject(6)
+		b.PrependUOffsetTSlot(0, org, 0)
+		b.PrependInt32Slot(1, int32(w.srid), 0)
+		crs = b.EndObject()
+	}
+
+	var geomType byte = geometryUnknown
+	if len(w.geomTypes) == 1 {
+		for t := range w.geomTypes {
+			geomType = t
+		}
+	}
+
+	// There is no index without features
+	var nodeSize uint16 = uint16(w.NodeSize)
+	if len(w.items) == 0 {
+		nodeSize = 0
+	}
+
+	b.StartObject(14)
+	b.PrependUOffsetTSlot(0, name, 0)
+	if envelope != 0 {
+		b.PrependUOffsetTSlot(1, envelope, 0)
+	}
+	b.PrependByteSlot(2, geomType, geometryUnknown)
+	b.PrependBoolSlot(3, w.hasZ, false)
+	b.PrependUOffsetTSlot(7, columnsVector, 0)
+	b.PrependUint64Slot(8, uint64(len(w.items)), 0)
+	b.PrependUint16Slot(9, nodeSize, DefaultNodeSize)
+	if crs != 0 {
+		b.PrependUOffsetTSlot(10, crs, 0)
+	}
+	b.FinishSizePrefixed(b.EndObject())
+
+	return b.FinishedBytes()
+}
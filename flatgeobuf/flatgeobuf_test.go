@@ -0,0 +1,321 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+
+	"go-uk-maps-import/database/types"
+)
+
+func TestLevelBounds(t *testing.T) {
+	tests := map[string]struct {
+		numItems int
+		expected [][2]int
+	}{
+		"one": {
+			numItems: 1,
+			expected: [][2]int{{1, 2}, {0, 1}},
+		},
+		"one node": {
+			numItems: 16,
+			expected: [][2]int{{1, 17}, {0, 1}},
+		},
+		"two levels": {
+			numItems: 17,
+			expected: [][2]int{{3, 20}, {1, 3}, {0, 1}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := levelBounds(tt.numItems, DefaultNodeSize)
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	fields := []Field{
+		{Name: "ID", Kind: types.KindText},
+		{Name: "HEIGHT", Kind: types.KindInteger},
+	}
+
+	features := []struct {
+		values []interface{}
+		wkb    []byte
+	}{
+		{[]interface{}{"A", int64(10)}, wkbPoint(1, 2)},
+		{[]interface{}{"B", nil}, wkbPolygon([]float64{10, 10, 12, 10, 12, 12, 10, 10})},
+		{[]interface{}{[]byte("C"), "7"}, wkbPoint(5, -3)},
+		{[]interface{}{"D", int64(1)}, nil},
+	}
+
+	expected := map[string]struct {
+		height   interface{}
+		geomType byte
+		xy       []float64
+	}{
+		"A": {int64(10), geometryPoint, []float64{1, 2}},
+		"B": {nil, geometryPolygon, []float64{10, 10, 12, 10, 12, 12, 10, 10}},
+		"C": {int64(7), geometryPoint, []float64{5, -3}},
+	}
+
+	path := filepath.Join(t.TempDir(), "road"+Ext)
+
+	w, err := Create(path, "road", fields, types.BNGSRID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range features {
+		err := w.Write(f.values, f.wkb)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + tmpExt); !os.IsNotExist(err) {
+		t.Errorf("the temporary file was not removed")
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(file[:8]) != string(magicBytes) {
+		t.Fatalf("the file does not start with the magic bytes")
+	}
+
+	// Header
+	header, size := root(file[8:])
+
+	if s := str(header, 0); s != "road" {
+		t.Errorf("name %v", s)
+	}
+	if n := header.GetUint64Slot(slot(8), 0); n != uint64(len(expected)) {
+		t.Errorf("features_count %v, expected %v", n, len(expected))
+	}
+	if g := header.GetByteSlot(slot(2), 0); g != geometryUnknown {
+		t.Errorf("geometry_type %v, expected unknown for mixed types", g)
+	}
+	if n := header.GetUint16Slot(slot(9), DefaultNodeSize); n != DefaultNodeSize {
+		t.Errorf("index_node_size %v", n)
+	}
+	if code := subTable(header, 10).GetInt32Slot(slot(1), 0); code != types.BNGSRID {
+		t.Errorf("crs %v", code)
+	}
+	if env := float64s(header, 1); !reflect.DeepEqual(env, []float64{1, -3, 12, 12}) {
+		t.Errorf("envelope %v", env)
+	}
+
+	var columns []string
+	for _, c := range tables(header, 7) {
+		columns = append(columns, str(c, 0))
+	}
+	if !reflect.DeepEqual(columns, []string{"ID", "HEIGHT"}) {
+		t.Errorf("columns %v", columns)
+	}
+
+	// Index, the root and a leaf per feature
+	var index []byte = file[8+size:]
+	var numNodes int = len(expected) + 1
+	var features0 []byte = index[numNodes*nodeItemSize:]
+
+	rootNode := readNode(index, 0)
+	if rootNode.minX != 1 || rootNode.minY != -3 || rootNode.maxX != 12 || rootNode.maxY != 12 || rootNode.offset != 1 {
+		t.Errorf("root %+v", rootNode)
+	}
+
+	var offset uint64
+	for i := 1; i < numNodes; i++ {
+		leaf := readNode(index, i)
+		if leaf.offset != offset {
+			t.Errorf("leaf %v offset %v, expected %v", i, leaf.offset, offset)
+		}
+
+		feature, size := root(features0[leaf.offset:])
+		offset += uint64(size)
+
+		props := properties(feature)
+		id, _ := props[0].(string)
+		e, ok := expected[id]
+		if !ok {
+			t.Fatalf("unexpected feature %v", props)
+		}
+
+		if props[1] != e.height {
+			t.Errorf("%v height %v, expected %v", id, props[1], e.height)
+		}
+
+		geom := subTable(feature, 0)
+		if g := geom.GetByteSlot(slot(6), 0); g != e.geomType {
+			t.Errorf("%v geometry type %v, expected %v", id, g, e.geomType)
+		}
+
+		xy := float64s(geom, 1)
+		if !reflect.DeepEqual(xy, e.xy) {
+			t.Errorf("%v xy %v, expected %v", id, xy, e.xy)
+		}
+
+		bbox, _ := (&geometry{xy: xy}).bounds()
+		if bbox.minX != leaf.minX || bbox.minY != leaf.minY || bbox.maxX != leaf.maxX || bbox.maxY != leaf.maxY {
+			t.Errorf("%v leaf %+v does not bound %v", id, leaf, xy)
+		}
+	}
+
+	if int(offset) != len(features0) {
+		t.Errorf("features are %v bytes, the index covers %v", len(features0), offset)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "road"+Ext)
+
+	w, err := Create(path, "road", []Field{{Name: "ID", Kind: types.KindText}}, types.BNGSRID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Write([]interface{}{"A"}, wkbPoint(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.Discard()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{path, path + tmpExt} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%v was not removed", p)
+		}
+	}
+}
+
+func wkbPoint(x, y float64) []byte {
+	return appendFloat64s([]byte{1, 1, 0, 0, 0}, x, y)
+}
+
+func wkbPolygon(ring []float64) []byte {
+	var b = []byte{1, 3, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(b[9:], uint32(len(ring)/2))
+
+	return appendFloat64s(b, ring...)
+}
+
+func appendFloat64s(b []byte, vs ...float64) []byte {
+	for _, v := range vs {
+		var f [8]byte
+		binary.LittleEndian.PutUint64(f[:], math.Float64bits(v))
+		b = append(b, f[:]...)
+	}
+
+	return b
+}
+
+// root is the root table of a size prefixed buffer and its size with the
+// prefix
+func root(buf []byte) (*flatbuffers.Table, int) {
+	size := binary.LittleEndian.Uint32(buf)
+	b := buf[:4+size]
+
+	return &flatbuffers.Table{Bytes: b, Pos: 4 + flatbuffers.GetUOffsetT(b[4:])}, int(4 + size)
+}
+
+func slot(n int) flatbuffers.VOffsetT {
+	return flatbuffers.VOffsetT(4 + 2*n)
+}
+
+func str(t *flatbuffers.Table, n int) string {
+	o := flatbuffers.UOffsetT(t.Offset(slot(n)))
+	if o == 0 {
+		return ""
+	}
+
+	return t.String(t.Pos + o)
+}
+
+func float64s(t *flatbuffers.Table, n int) []float64 {
+	o := flatbuffers.UOffsetT(t.Offset(slot(n)))
+	if o == 0 {
+		return nil
+	}
+
+	var vs []float64
+	start := t.Vector(o)
+	for i := 0; i < t.VectorLen(o); i++ {
+		vs = append(vs, t.GetFloat64(start+flatbuffers.UOffsetT(8*i)))
+	}
+
+	return vs
+}
+
+func subTable(t *flatbuffers.Table, n int) *flatbuffers.Table {
+	o := flatbuffers.UOffsetT(t.Offset(slot(n)))
+
+	return &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(t.Pos + o)}
+}
+
+func tables(t *flatbuffers.Table, n int) []*flatbuffers.Table {
+	o := flatbuffers.UOffsetT(t.Offset(slot(n)))
+
+	var ts []*flatbuffers.Table
+	start := t.Vector(o)
+	for i := 0; i < t.VectorLen(o); i++ {
+		ts = append(ts, &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(start + flatbuffers.UOffsetT(4*i))})
+	}
+
+	return ts
+}
+
+func readNode(index []byte, i int) nodeItem {
+	b := index[i*nodeItemSize:]
+
+	return nodeItem{
+		minX:   math.Float64frombits(binary.LittleEndian.Uint64(b[0:])),
+		minY:   math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		maxX:   math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+		maxY:   math.Float64frombits(binary.LittleEndian.Uint64(b[24:])),
+		offset: binary.LittleEndian.Uint64(b[32:]),
+	}
+}
+
+// properties decodes the text and long properties of a feature by column
+func properties(feature *flatbuffers.Table) map[uint16]interface{} {
+	var props = make(map[uint16]interface{})
+
+	o := flatbuffers.UOffsetT(feature.Offset(slot(1)))
+	b := feature.ByteVector(feature.Pos + o)
+
+	for len(b) > 0 {
+		column := binary.LittleEndian.Uint16(b)
+		b = b[2:]
+
+		switch column {
+		case 0:
+			n := binary.LittleEndian.Uint32(b)
+			props[column] = string(b[4 : 4+n])
+			b = b[4+n:]
+		case 1:
+			props[column] = int64(binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		}
+	}
+
+	return props
+}
@@ -0,0 +1,311 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// The FlatGeobuf geometry types, the same numbers as WKB
+const (
+	geometryUnknown            = 0
+	geometryPoint              = 1
+	geometryLineString         = 2
+	geometryPolygon            = 3
+	geometryMultiPoint         = 4
+	geometryMultiLineString    = 5
+	geometryMultiPolygon       = 6
+	geometryGeometryCollection = 7
+)
+
+// The WKB type flags of EWKB
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// geometry is a WKB geometry laid out as a FlatGeobuf Geometry table, the
+// coordinates are flat with ends marking the end of each ring or line, a
+// multi polygon or collection is made of parts
+type geometry struct {
+	geomType byte
+	xy       []float64
+	z        []float64
+	ends     []uint32
+	parts    []*geometry
+}
+
+type wkbReader struct {
+	b     []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+// parseWKB reads a WKB or EWKB geometry, M values are dropped
+func parseWKB(wkb []byte) (*geometry, error) {
+	r := &wkbReader{b: wkb}
+
+	g, err := r.geometry()
+	if err != nil {
+		return nil, fmt.Errorf("invalid wkb: %v", err.Error())
+	}
+
+	return g, nil
+}
+
+func (r *wkbReader) geometry() (*geometry, error) {
+	if r.pos+5 > len(r.b) {
+		return nil, fmt.Errorf("%v bytes", len(r.b))
+	}
+
+	r.order = binary.ByteOrder(binary.LittleEndian)
+	if r.b[r.pos] == 0 {
+		r.order = binary.BigEndian
+	}
+	r.pos++
+
+	t, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+
+	var hasZ bool = t&ewkbZ != 0
+	var hasM bool = t&ewkbM != 0
+	if t&ewkbSRID != 0 {
+		r.pos += 4
+	}
+
+	// ISO WKB adds 1000 for Z, 2000 for M and 3000 for both
+	var base uint32 = t & 0xffff
+	switch base / 1000 {
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	}
+	base %= 1000
+
+	var dims int = 2
+	if hasZ {
+		dims++
+	}
+	if hasM {
+		dims++
+	}
+
+	g := &geometry{geomType: byte(base)}
+
+	switch base {
+	case geometryPoint:
+		err = r.points(g, 1, hasZ, dims)
+
+	case geometryLineString:
+		err = r.line(g, hasZ, dims)
+
+	case geometryPolygon:
+		err = r.polygon(g, hasZ, dims)
+
+	case geometryMultiPoint, geometryMultiLineString:
+		var n uint32
+		n, err = r.uint32()
+		for i := uint32(0); err == nil && i < n; i++ {
+			var part *geometry
+			part, err = r.geometry()
+			if err == nil {
+				g.add(part, base == geometryMultiLineString && n > 1)
+			}
+		}
+
+	case geometryMultiPolygon, geometryGeometryCollection:
+		var n uint32
+		n, err = r.uint32()
+		for i := uint32(0); err == nil && i < n; i++ {
+			var part *geometry
+			part, err = r.geometry()
+			if err == nil {
+				g.parts = append(g.parts, part)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %v", t)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// add appends the coordinates of a point or line, marking its end
+func (g *geometry) add(part *geometry, end bool) {
+	g.xy = append(g.xy, part.xy...)
+	g.z = append(g.z, part.z...)
+	if end {
+		g.ends = append(g.ends, uint32(len(g.xy)/2))
+	}
+}
+
+func (r *wkbReader) line(g *geometry, hasZ bool, dims int) error {
+	n, err := r.uint32()
+	if err != nil {
+		return err
+	}
+
+	return r.points(g, int(n), hasZ, dims)
+}
+
+// polygon reads the rings, ends are only needed for more than one
+func (r *wkbReader) polygon(g *geometry, hasZ bool, dims int) error {
+	n, err := r.uint32()
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < n; i++ {
+		err := r.line(g, hasZ, dims)
+		if err != nil {
+			return err
+		}
+		if n > 1 {
+			g.ends = append(g.ends, uint32(len(g.xy)/2))
+		}
+	}
+
+	return nil
+}
+
+func (r *wkbReader) points(g *geometry, n int, hasZ bool, dims int) error {
+	if r.pos+n*dims*8 > len(r.b) {
+		return fmt.Errorf("%v points overrun %v bytes", n, len(r.b))
+	}
+
+	for i := 0; i < n; i++ {
+		g.xy = append(g.xy, r.float64(), r.float64())
+		if hasZ {
+			g.z = append(g.z, r.float64())
+		}
+		if dims == 4 || (dims == 3 && !hasZ) {
+			r.pos += 8
+		}
+	}
+
+	return nil
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.b) {
+		return 0, fmt.Errorf("%v bytes", len(r.b))
+	}
+
+	v := r.order.Uint32(r.b[r.pos:])
+	r.pos += 4
+
+	return v, nil
+}
+
+func (r *wkbReader) float64() float64 {
+	v := math.Float64frombits(r.order.Uint64(r.b[r.pos:]))
+	r.pos += 8
+
+	return v
+}
+
+// bounds returns the envelope of the geometry, false if it has no
+// coordinates
+func (g *geometry) bounds() (nodeItem, bool) {
+	var n nodeItem = emptyNode()
+	var found bool
+
+	g.walk(func(x, y float64) {
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return
+		}
+		n.expand(nodeItem{minX: x, minY: y, maxX: x, maxY: y})
+		found = true
+	})
+
+	return n, found
+}
+
+func (g *geometry) walk(fn func(x, y float64)) {
+	for i := 0; i+1 < len(g.xy); i += 2 {
+		fn(g.xy[i], g.xy[i+1])
+	}
+	for _, part := range g.parts {
+		part.walk(fn)
+	}
+}
+
+// hasZ is true if any coordinate has a z
+func (g *geometry) hasZ() bool {
+	if len(g.z) > 0 {
+		return true
+	}
+	for _, part := range g.parts {
+		if part.hasZ() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// build writes the Geometry table, parts first as tables cannot be nested
+func (g *geometry) build(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+	var parts flatbuffers.UOffsetT
+	if len(g.parts) > 0 {
+		var offsets = make([]flatbuffers.UOffsetT, len(g.parts))
+		for i, part := range g.parts {
+			offsets[i] = part.build(b)
+		}
+		parts = b.CreateVectorOfTables(offsets)
+	}
+
+	var ends, xy, z flatbuffers.UOffsetT
+	if len(g.ends) > 0 {
+		b.StartVector(4, len(g.ends), 4)
+		for i := len(g.ends) - 1; i >= 0; i-- {
+			b.PrependUint32(g.ends[i])
+		}
+		ends = b.EndVector(len(g.ends))
+	}
+	if len(g.xy) > 0 {
+		xy = float64Vector(b, g.xy)
+	}
+	if len(g.z) > 0 {
+		z = float64Vector(b, g.z)
+	}
+
+	b.StartObject(8)
+	if ends != 0 {
+		b.PrependUOffsetTSlot(0, ends, 0)
+	}
+	if xy != 0 {
+		b.PrependUOffsetTSlot(1, xy, 0)
+	}
+	if z != 0 {
+		b.PrependUOffsetTSlot(2, z, 0)
+	}
+	b.PrependByteSlot(6, g.geomType, geometryUnknown)
+	if parts != 0 {
+		b.PrependUOffsetTSlot(7, parts, 0)
+	}
+
+	return b.EndObject()
+}
+
+func float64Vector(b *flatbuffers.Builder, vs []float64) flatbuffers.UOffsetT {
+	b.StartVector(8, len(vs), 8)
+	for i := len(vs) - 1; i >= 0; i-- {
+		b.PrependFloat64(vs[i])
+	}
+
+	return b.EndVector(len(vs))
+}
@@ -0,0 +1,181 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+)
+
+// The packed Hilbert R-tree of a FlatGeobuf file, the features are sorted
+// along a Hilbert curve and the tree is built bottom up from them, it is
+// written root first with the leaves last
+// ref: https://github.com/flatgeobuf/flatgeobuf/blob/master/src/cpp/packedrtree.cpp
+
+const (
+	// DefaultNodeSize is the number of children of each node of the index
+	DefaultNodeSize = 16
+
+	nodeItemSize = 40
+
+	hilbertMax = (1 << 16) - 1
+)
+
+// nodeItem is the envelope of a node, the offset of a leaf is that of its
+// feature in the features and the offset of any other node is the index of
+// its first child
+type nodeItem struct {
+	minX, minY, maxX, maxY float64
+	offset                 uint64
+}
+
+func emptyNode() nodeItem {
+	return nodeItem{
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+	}
+}
+
+func (n *nodeItem) expand(r nodeItem) {
+	n.minX = math.Min(n.minX, r.minX)
+	n.minY = math.Min(n.minY, r.minY)
+	n.maxX = math.Max(n.maxX, r.maxX)
+	n.maxY = math.Max(n.maxY, r.maxY)
+}
+
+func (n nodeItem) write(w io.Writer) error {
+	var b [nodeItemSize]byte
+	binary.LittleEndian.PutUint64(b[0:], math.Float64bits(n.minX))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(n.minY))
+	binary.LittleEndian.PutUint64(b[16:], math.Float64bits(n.maxX))
+	binary.LittleEndian.PutUint64(b[24:], math.Float64bits(n.maxY))
+	binary.LittleEndian.PutUint64(b[32:], n.offset)
+
+	_, err := w.Write(b[:])
+
+	return err
+}
+
+// hilbertSort orders the items by the Hilbert value of their centres within
+// the extent, as the reference implementation does
+func hilbertSort(items []item, extent nodeItem) {
+	var width float64 = extent.maxX - extent.minX
+	var height float64 = extent.maxY - extent.minY
+
+	for i := range items {
+		var x, y uint32
+		if width != 0 {
+			x = uint32(math.Floor(hilbertMax * ((items[i].bbox.minX+items[i].bbox.maxX)/2 - extent.minX) / width))
+		}
+		if height != 0 {
+			y = uint32(math.Floor(hilbertMax * ((items[i].bbox.minY+items[i].bbox.maxY)/2 - extent.minY) / height))
+		}
+		items[i].hilbert = hilbert(x, y)
+	}
+
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].hilbert > items[b].hilbert
+	})
+}
+
+// hilbert is the position of x,y along a Hilbert curve of order 16
+// ref: https://github.com/rawrunprotected/hilbert_curves
+func hilbert(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+
+	return (i1 << 1) | i0
+}
+
+// levelBounds are the first and last+1 node of each level of a tree of
+// numItems leaves, leaves first, a single leaf still has a root above it as
+// readers size the index that way
+func levelBounds(numItems, nodeSize int) [][2]int {
+	var n int = numItems
+	var numNodes int = n
+	var levelNumNodes = []int{n}
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		numNodes += n
+		levelNumNodes = append(levelNumNodes, n)
+		if n == 1 {
+			break
+		}
+	}
+
+	var bounds = make([][2]int, len(levelNumNodes))
+	n = numNodes
+	for i, size := range levelNumNodes {
+		bounds[i] = [2]int{n - size, n}
+		n -= size
+	}
+
+	return bounds
+}
+
+// packedTree builds the nodes of the index from the sorted leaves
+func packedTree(leaves []nodeItem, nodeSize int) []nodeItem {
+	var bounds [][2]int = levelBounds(len(leaves), nodeSize)
+	var nodes = make([]nodeItem, bounds[0][1])
+
+	copy(nodes[bounds[0][0]:], leaves)
+
+	for i := 0; i < len(bounds)-1; i++ {
+		pos, end := bounds[i][0], bounds[i][1]
+		parent := bounds[i+1][0]
+
+		for pos < end {
+			node := emptyNode()
+			node.offset = uint64(pos)
+			for j := 0; j < nodeSize && pos < end; j++ {
+				node.expand(nodes[pos])
+				pos++
+			}
+			nodes[parent] = node
+			parent++
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,93 @@
+package geoparquet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+
+	"go-uk-maps-import/database/types"
+)
+
+var epoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// convert returns a value as the Go type written for a physical type, values
+// of other types e.g. numbers read back as text are parsed as the kind of
+// the column, nil is returned for an empty value
+func convert(kind string, physical parquet.Type, value interface{}) (interface{}, error) {
+	switch physical {
+	case parquet.Type_BYTE_ARRAY:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(value), nil
+
+	case parquet.Type_INT64:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int16:
+			return int64(v), nil
+		case int8:
+			return int64(v), nil
+		case uint32:
+			return int64(v), nil
+		case uint16:
+			return int64(v), nil
+		case uint8:
+			return int64(v), nil
+		}
+
+	case parquet.Type_DOUBLE:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		}
+
+	case parquet.Type_BOOLEAN:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		}
+
+	case parquet.Type_INT32:
+		switch v := value.(type) {
+		case time.Time:
+			return days(v), nil
+		case string:
+			if t, err := time.Parse("2006-01-02", v); err == nil {
+				return days(t), nil
+			}
+		}
+	}
+
+	parsed, err := types.Column{Kind: kind}.Parse(value)
+	if err != nil || parsed == nil {
+		return nil, err
+	}
+
+	if _, isString := parsed.(string); isString && physical != parquet.Type_INT32 {
+		return nil, fmt.Errorf("%q is not a %v", parsed, kind)
+	}
+
+	return convert(kind, physical, parsed)
+}
+
+// days is a DATE, the days since the epoch
+func days(t time.Time) int32 {
+	var d time.Time = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int32(d.Sub(epoch).Hours() / 24)
+}
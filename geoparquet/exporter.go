@@ -0,0 +1,298 @@
+package geoparquet
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-utils/fileutils"
+
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/manifest"
+)
+
+const (
+	DefaultFolder = "geoparquet"
+
+	// The rows buffered across every file, when there are more the row group
+	// of the biggest file is written early
+	DefaultMaxBuffered = 256 * 1024 * 1024
+
+	ext        = ".parquet"
+	bufferSize = 64 * 1024
+)
+
+// Exporter writes the rows of each layer to a GeoParquet file in its folder,
+// or with Partition a file per national grid square of the layer, and lists
+// them in a manifest when it is closed, it is safe for concurrent use. Like
+// the tables a feature has a row per 10km grid cell it is in, the columns
+// of each layer are those of the schema
+type Exporter struct {
+	Folder       string
+	Partition    bool
+	RowGroupRows int
+	MaxBuffered  int
+
+	// Sources lists the source files of the rows of a layer in a square, or
+	// in any square if square is empty, for the manifest
+	Sources func(layer, square string) ([]manifest.Source, error)
+
+	schema   *types.Schema
+	mu       sync.Mutex
+	files    map[string]*layerFile
+	buffered int
+}
+
+type layerFile struct {
+	path   string
+	layer  string
+	square string
+	fields []string
+	bw     *bufio.Writer
+	w      *Writer
+}
+
+// appendFile opens its file for each write, so a file for every square of
+// every layer can be written without holding a handle for each
+type appendFile struct {
+	path string
+}
+
+func (a appendFile) Write(b []byte) (int, error) {
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := f.Write(b)
+	if err != nil {
+		f.Close()
+		return n, err
+	}
+
+	return n, f.Close()
+}
+
+func New(folder string, schema *types.Schema) *Exporter {
+	if folder == "" {
+		folder = DefaultFolder
+	}
+
+	return &Exporter{
+		Folder: folder,
+		schema: schema,
+	}
+}
+
+// Start empties the folder
+func (e *Exporter) Start() error {
+	var funcName string = "geoparquet.Start"
+
+	if e.RowGroupRows <= 0 {
+		e.RowGroupRows = DefaultRowGroupRows
+	}
+	if e.MaxBuffered <= 0 {
+		e.MaxBuffered = DefaultMaxBuffered
+	}
+
+	e.files = make(map[string]*layerFile)
+	e.buffered = 0
+
+	err := fileutils.MkDir(e.Folder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = fileutils.EmptyFolder(e.Folder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// Write adds a row of a layer, rec holds the values of the fields of the
+// layer and square is the 100km square of the row
+func (e *Exporter) Write(layer, square string, rec map[string]interface{}, wkb []byte) error {
+	var funcName string = "geoparquet.Write"
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	lf, err := e.getFile(layer, strings.ToLower(square))
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var values = make([]interface{}, len(lf.fields))
+	for n, field := range lf.fields {
+		values[n] = rec[field]
+	}
+
+	before := lf.w.Buffered()
+
+	err = lf.w.Write(values, wkb)
+	if err != nil {
+		return fmt.Errorf("%v: %v %v", funcName, lf.path, err.Error())
+	}
+
+	e.buffered += lf.w.Buffered() - before
+
+	if e.buffered > e.MaxBuffered {
+		err := e.flushBiggest()
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Close finishes the files and writes the manifest
+func (e *Exporter) Close() error {
+	var funcName string = "geoparquet.Close"
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var m *manifest.Manifest = manifest.New()
+
+	for _, lf := range e.files {
+		rows := lf.w.NumRows()
+
+		err := lf.close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		var sources []manifest.Source
+		if e.Sources != nil {
+			sources, err = e.Sources(lf.layer, lf.square)
+			if err != nil {
+				return fmt.Errorf("%v: %v", funcName, err.Error())
+			}
+		}
+
+		err = m.Add(e.Folder, lf.path, manifest.Artefact{
+			Rows:    int(rows),
+			Layer:   lf.layer,
+			Square:  lf.square,
+			Sources: sources,
+		})
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Wrote %v GeoParquet files to %v\n", len(e.files), e.Folder),
+	)
+
+	e.files = make(map[string]*layerFile)
+	e.buffered = 0
+
+	err := m.Save(e.Folder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// getFile returns the file of a layer, or of the square of a layer when
+// partitioned, creating it with the columns of the layer
+func (e *Exporter) getFile(layer, square string) (*layerFile, error) {
+	var path string = filepath.Join(e.Folder, layer+ext)
+	if !e.Partition {
+		square = ""
+	} else {
+		path = filepath.Join(e.Folder, layer, square+ext)
+	}
+
+	if lf, exists := e.files[path]; exists {
+		return lf, nil
+	}
+
+	layerFields, exists := e.schema.MapLayers[layer]
+	if !exists {
+		return nil, fmt.Errorf("unknown layer %v", layer)
+	}
+
+	err := fileutils.MkDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields = make([]Field, len(layerFields))
+	for n, field := range layerFields {
+		fields[n] = Field{Name: field, Kind: e.schema.FieldKind(field)}
+	}
+
+	bw := bufio.NewWriterSize(appendFile{path}, bufferSize)
+
+	w, err := NewWriter(bw, fields, e.schema.LayerSRID(layer))
+	if err != nil {
+		return nil, err
+	}
+	w.RowGroupRows = e.RowGroupRows
+
+	lf := &layerFile{
+		path:   path,
+		layer:  layer,
+		square: square,
+		fields: layerFields,
+		bw:     bw,
+		w:      w,
+	}
+	e.files[path] = lf
+
+	return lf, nil
+}
+
+// flushBiggest writes the row group of the file buffering the most rows
+func (e *Exporter) flushBiggest() error {
+	var biggest *layerFile
+	var size int
+
+	for _, lf := range e.files {
+		if b := lf.w.Buffered(); biggest == nil || b > size {
+			biggest, size = lf, b
+		}
+	}
+
+	if biggest == nil {
+		return nil
+	}
+
+	err := biggest.w.Flush()
+	if err != nil {
+		return err
+	}
+
+	e.buffered -= size
+
+	return nil
+}
+
+func (lf *layerFile) close() error {
+	err := lf.w.Close()
+	if err != nil {
+		return err
+	}
+
+	return lf.bw.Flush()
+}
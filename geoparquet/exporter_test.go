@@ -0,0 +1,190 @@
+package geoparquet
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/manifest"
+)
+
+func TestExporter(t *testing.T) {
+	rows := []struct {
+		square string
+		rec    map[string]interface{}
+	}{
+		{"SD", map[string]interface{}{"ID": "a", "GRIDREF": 1, "JUNCTNUM": "1", "FEATCODE": int64(25796)}},
+		{"SD", map[string]interface{}{"ID": "a", "GRIDREF": 2, "JUNCTNUM": "1", "FEATCODE": int64(25796)}},
+		{"SE", map[string]interface{}{"ID": "b", "GRIDREF": 1, "JUNCTNUM": "2", "FEATCODE": int64(25796)}},
+	}
+
+	tests := map[string]struct {
+		partition bool
+		expected  map[string]manifest.Artefact
+		ids       map[string][]interface{}
+	}{
+		"layer": {
+			expected: map[string]manifest.Artefact{
+				"motorway_junction.parquet": {Rows: 3, Layer: "motorway_junction"},
+			},
+			ids: map[string][]interface{}{
+				"motorway_junction.parquet": {"a", "a", "b"},
+			},
+		},
+		"partitioned": {
+			partition: true,
+			expected: map[string]manifest.Artefact{
+				"motorway_junction/sd.parquet": {Rows: 2, Layer: "motorway_junction", Square: "sd"},
+				"motorway_junction/se.parquet": {Rows: 1, Layer: "motorway_junction", Square: "se"},
+			},
+			ids: map[string][]interface{}{
+				"motorway_junction/sd.parquet": {"a", "a"},
+				"motorway_junction/se.parquet": {"b"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := New(t.TempDir(), types.NewSchema())
+			e.Partition = tt.partition
+			e.Sources = func(layer, square string) ([]manifest.Source, error) {
+				return []manifest.Source{{File: layer + square}}, nil
+			}
+
+			err := e.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, r := range rows {
+				err := e.Write("motorway_junction", r.square, r.rec, point)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = e.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := manifest.Load(e.Folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual = make(map[string]manifest.Artefact)
+			for _, a := range m.Artefacts {
+				if a.SHA256 == "" || a.Size == 0 {
+					t.Errorf("%v: expected a checksum and size, got %+v", a.Path, a)
+				}
+				if !reflect.DeepEqual(a.Sources, []manifest.Source{{File: a.Layer + a.Square}}) {
+					t.Errorf("%v: unexpected sources %v", a.Path, a.Sources)
+				}
+
+				actual[filepath.ToSlash(a.Path)] = manifest.Artefact{Rows: a.Rows, Layer: a.Layer, Square: a.Square}
+			}
+
+			if !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+
+			// The rows mirror the tables, a row per grid cell of a feature
+			for path, expected := range tt.ids {
+				ids := readColumn(t, filepath.Join(e.Folder, path), 0)
+				if !reflect.DeepEqual(expected, ids) {
+					t.Errorf("%v: expected IDs %v, got %v", path, expected, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestExporterFlushBiggest(t *testing.T) {
+	e := New(t.TempDir(), types.NewSchema())
+	e.Partition = true
+
+	err := e.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, square := range []string{"sd", "sd", "sd", "se"} {
+		rec := map[string]interface{}{"ID": string(rune('a' + n)), "GRIDREF": n, "FEATCODE": int64(25796)}
+		err := e.Write("motorway_junction", square, rec, point)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sd := e.files[filepath.Join(e.Folder, "motorway_junction", "sd"+ext)]
+	se := e.files[filepath.Join(e.Folder, "motorway_junction", "se"+ext)]
+
+	if e.buffered != sd.w.Buffered()+se.w.Buffered() {
+		t.Fatalf("expected %v buffered, got %v", sd.w.Buffered()+se.w.Buffered(), e.buffered)
+	}
+
+	buffered := se.w.Buffered()
+
+	err = e.flushBiggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sd.w.Buffered() != 0 || se.w.Buffered() != buffered || e.buffered != buffered {
+		t.Errorf("expected only sd to be flushed, sd %v, se %v, total %v", sd.w.Buffered(), se.w.Buffered(), e.buffered)
+	}
+
+	// Writing past the limit flushes the biggest file again
+	e.MaxBuffered = buffered
+
+	err = e.Write("motorway_junction", "se", map[string]interface{}{"ID": "e", "GRIDREF": 4}, point)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if se.w.Buffered() != 0 || e.buffered != 0 {
+		t.Errorf("expected se to be flushed, se %v, total %v", se.w.Buffered(), e.buffered)
+	}
+
+	err = e.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := readColumn(t, filepath.Join(e.Folder, "motorway_junction", "se"+ext), 0)
+	sort.Slice(ids, func(i, j int) bool { return ids[i].(string) < ids[j].(string) })
+	if !reflect.DeepEqual([]interface{}{"d", "e"}, ids) {
+		t.Errorf("expected IDs [d e], got %v", ids)
+	}
+}
+
+// readColumn reads the values of a column across the row groups of a file
+func readColumn(t *testing.T, path string, n int) []interface{} {
+	t.Helper()
+
+	file, err := local.NewLocalFileReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	pr, err := reader.NewParquetColumnReader(file, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+
+	values, _, _, err := pr.ReadColumnByIndex(int64(n), pr.GetNumRows())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
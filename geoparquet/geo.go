@@ -0,0 +1,138 @@
+package geoparquet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+
+	"go-uk-maps-import/database/types"
+)
+
+// ref: https://geoparquet.org/releases/v1.0.0/
+const geoParquetVersion = "1.0.0"
+
+// geoMetadata is the geo key of the file metadata
+type geoMetadata struct {
+	Version       string               `json:"version"`
+	PrimaryColumn string               `json:"primary_column"`
+	Columns       map[string]geoColumn `json:"columns"`
+}
+
+// geoColumn describes a geometry column, a null CRS is an unknown one
+type geoColumn struct {
+	Encoding      string          `json:"encoding"`
+	GeometryTypes []string        `json:"geometry_types"`
+	CRS           json.RawMessage `json:"crs"`
+}
+
+func newGeoColumn(srid int) geoColumn {
+	var crs json.RawMessage = json.RawMessage("null")
+	if srid == types.BNGSRID {
+		crs = json.RawMessage(britishNationalGrid)
+	}
+
+	return geoColumn{
+		Encoding: "WKB",
+		CRS:      crs,
+	}
+}
+
+// The WKB geometry types, ISO WKB adds 1000 for Z and EWKB sets a flag
+var wkbTypes = map[uint32]string{
+	1: "Point",
+	2: "LineString",
+	3: "Polygon",
+	4: "MultiPoint",
+	5: "MultiLineString",
+	6: "MultiPolygon",
+	7: "GeometryCollection",
+}
+
+const ewkbZ = 0x80000000
+
+// wkbType reads the GeoParquet geometry type of a WKB geometry e.g. Polygon Z
+func wkbType(wkb []byte) (string, bool) {
+	if len(wkb) < 5 {
+		return "", false
+	}
+
+	var t uint32
+	if wkb[0] == 0 {
+		t = binary.BigEndian.Uint32(wkb[1:5])
+	} else {
+		t = binary.LittleEndian.Uint32(wkb[1:5])
+	}
+
+	var base uint32 = t & 0xffff
+	var z bool = t&ewkbZ != 0 || base/1000 == 1
+
+	name, ok := wkbTypes[base%1000]
+	if !ok {
+		return "", false
+	}
+
+	if z {
+		name += " Z"
+	}
+
+	return name, true
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys = []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// britishNationalGrid is EPSG:27700 as PROJJSON
+const britishNationalGrid = `{
+  "$schema": "https://proj.org/schemas/v0.7/projjson.schema.json",
+  "type": "ProjectedCRS",
+  "name": "OSGB36 / British National Grid",
+  "base_crs": {
+    "name": "OSGB36",
+    "datum": {
+      "type": "GeodeticReferenceFrame",
+      "name": "Ordnance Survey of Great Britain 1936",
+      "ellipsoid": {
+        "name": "Airy 1830",
+        "semi_major_axis": 6377563.396,
+        "inverse_flattening": 299.3249646
+      }
+    },
+    "coordinate_system": {
+      "subtype": "ellipsoidal",
+      "axis": [
+        {"name": "Geodetic latitude", "abbreviation": "Lat", "direction": "north", "unit": "degree"},
+        {"name": "Geodetic longitude", "abbreviation": "Lon", "direction": "east", "unit": "degree"}
+      ]
+    },
+    "id": {"authority": "EPSG", "code": 4277}
+  },
+  "conversion": {
+    "name": "British National Grid",
+    "method": {
+      "name": "Transverse Mercator",
+      "id": {"authority": "EPSG", "code": 9807}
+    },
+    "parameters": [
+      {"name": "Latitude of natural origin", "value": 49, "unit": "degree", "id": {"authority": "EPSG", "code": 8801}},
+      {"name": "Longitude of natural origin", "value": -2, "unit": "degree", "id": {"authority": "EPSG", "code": 8802}},
+      {"name": "Scale factor at natural origin", "value": 0.9996012717, "unit": "unity", "id": {"authority": "EPSG", "code": 8805}},
+      {"name": "False easting", "value": 400000, "unit": "metre", "id": {"authority": "EPSG", "code": 8806}},
+      {"name": "False northing", "value": -100000, "unit": "metre", "id": {"authority": "EPSG", "code": 8807}}
+    ]
+  },
+  "coordinate_system": {
+    "subtype": "Cartesian",
+    "axis": [
+      {"name": "Easting", "abbreviation": "E", "direction": "east", "unit": "metre"},
+      {"name": "Northing", "abbreviation": "N", "direction": "north", "unit": "metre"}
+    ]
+  },
+  "id": {"authority": "EPSG", "code": 27700}
+}`
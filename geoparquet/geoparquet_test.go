@@ -0,0 +1,179 @@
+package geoparquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"go-uk-maps-import/database/types"
+)
+
+// A point at 1,2 in little endian WKB
+var point = []byte{
+	1, 1, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0xf0, 0x3f,
+	0, 0, 0, 0, 0, 0, 0, 0x40,
+}
+
+func TestWriter(t *testing.T) {
+	fields := []Field{
+		{Name: "ID", Kind: types.KindText},
+		{Name: "HEIGHT", Kind: types.KindInteger},
+		{Name: "AREA", Kind: types.KindDecimal},
+		{Name: "OPEN", Kind: types.KindBoolean},
+		{Name: "SURVEYED", Kind: types.KindDate},
+	}
+
+	tests := map[string]struct {
+		srid     int
+		rows     [][]interface{}
+		wkbs     [][]byte
+		expected [][]interface{}
+		crs      int
+	}{
+		"british national grid": {
+			srid: types.BNGSRID,
+			rows: [][]interface{}{
+				{"A", int64(10), 1.5, true, "2022-01-02"},
+				{"B", nil, nil, false, nil},
+				{[]byte("C"), "12", "2.25", int64(1), "1970-01-01"},
+			},
+			wkbs: [][]byte{point, nil, point},
+			expected: [][]interface{}{
+				{"A", "B", "C"},
+				{int64(10), nil, int64(12)},
+				{1.5, nil, 2.25},
+				{true, false, true},
+				{int32(18994), nil, int32(0)},
+				{string(point), nil, string(point)},
+			},
+			crs: types.BNGSRID,
+		},
+		"unknown crs": {
+			srid: 0,
+			rows: [][]interface{}{
+				{"A", int64(1), 0.5, false, nil},
+			},
+			wkbs: [][]byte{point},
+			expected: [][]interface{}{
+				{"A"},
+				{int64(1)},
+				{0.5},
+				{false},
+				{nil},
+				{string(point)},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			w, err := NewWriter(&buf, fields, tt.srid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.RowGroupRows = 2
+
+			for n, row := range tt.rows {
+				err := w.Write(row, tt.wkbs[n])
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := buffer.NewBufferFile(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pr, err := reader.NewParquetColumnReader(file, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pr.ReadStop()
+
+			if pr.GetNumRows() != int64(len(tt.rows)) {
+				t.Errorf("num_rows %v, expected %v", pr.GetNumRows(), len(tt.rows))
+			}
+
+			var names []string
+			for _, info := range pr.SchemaHandler.Infos[1:] {
+				names = append(names, info.ExName)
+			}
+			if !reflect.DeepEqual(names, []string{"ID", "HEIGHT", "AREA", "OPEN", "SURVEYED", GeometryColumn}) {
+				t.Errorf("columns %v", names)
+			}
+
+			// The geo metadata
+			var kv *parquet.KeyValue
+			for _, m := range pr.Footer.KeyValueMetadata {
+				if m.Key == "geo" {
+					kv = m
+				}
+			}
+			if kv == nil || kv.Value == nil {
+				t.Fatalf("key_value_metadata %v, expected geo", pr.Footer.KeyValueMetadata)
+			}
+
+			var geo struct {
+				Version       string `json:"version"`
+				PrimaryColumn string `json:"primary_column"`
+				Columns       map[string]struct {
+					Encoding      string   `json:"encoding"`
+					GeometryTypes []string `json:"geometry_types"`
+					CRS           *struct {
+						ID struct {
+							Code int `json:"code"`
+						} `json:"id"`
+					} `json:"crs"`
+				} `json:"columns"`
+			}
+			err = json.Unmarshal([]byte(*kv.Value), &geo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			column := geo.Columns[geo.PrimaryColumn]
+			if geo.Version != geoParquetVersion || geo.PrimaryColumn != GeometryColumn || column.Encoding != "WKB" {
+				t.Errorf("geo %+v", geo)
+			}
+			if !reflect.DeepEqual(column.GeometryTypes, []string{"Point"}) {
+				t.Errorf("geometry_types %v", column.GeometryTypes)
+			}
+
+			var crs int
+			if column.CRS != nil {
+				crs = column.CRS.ID.Code
+			}
+			if crs != tt.crs {
+				t.Errorf("crs %v, expected %v", crs, tt.crs)
+			}
+
+			// The values of each column across the row groups
+			var actual = make([][]interface{}, len(fields)+1)
+			for n := range actual {
+				values, _, _, err := pr.ReadColumnByIndex(int64(n), pr.GetNumRows())
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual[n] = values
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package geoparquet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"go-uk-maps-import/database/types"
+)
+
+// The Parquet encoding is left to parquet-go, every column is optional and
+// gzipped, the geo metadata is added to the footer
+// ref: https://github.com/xitongsys/parquet-go
+
+const (
+	createdBy = "go-uk-maps-import"
+
+	// DefaultRowGroupRows keeps row groups big enough to read quickly and
+	// small enough that many files can be written at once
+	DefaultRowGroupRows = 50000
+)
+
+// GeometryColumn is the WKB column of every file
+const GeometryColumn = "geometry"
+
+// Field is an attribute column of a file, its kind is one of the types.Kind
+// logical types
+type Field struct {
+	Name string
+	Kind string
+}
+
+// Writer writes the rows of a layer as a GeoParquet file, rows are buffered
+// until there are RowGroupRows of them
+type Writer struct {
+	RowGroupRows int
+
+	pw        *writer.CSVWriter
+	fields    []Field
+	physical  []parquet.Type
+	rows      int
+	geo       geoColumn
+	geomTypes map[string]bool
+}
+
+// NewWriter starts a file with the attribute fields of a layer, srid is the
+// CRS of the geometries, 0 if it is not known
+func NewWriter(w io.Writer, fields []Field, srid int) (*Writer, error) {
+	var funcName string = "geoparquet.NewWriter"
+
+	var md []string
+	var physical []parquet.Type
+	for _, f := range fields {
+		t, tag := physicalType(f.Kind)
+		md = append(md, fmt.Sprintf("name=%v, %v, repetitiontype=OPTIONAL", f.Name, tag))
+		physical = append(physical, t)
+	}
+	md = append(md, fmt.Sprintf("name=%v, type=BYTE_ARRAY, repetitiontype=OPTIONAL", GeometryColumn))
+
+	pw, err := writer.NewCSVWriterFromWriter(md, w, 1)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	pw.CompressionType = parquet.CompressionCodec_GZIP
+
+	var by string = createdBy
+	pw.Footer.CreatedBy = &by
+
+	return &Writer{
+		RowGroupRows: DefaultRowGroupRows,
+		pw:           pw,
+		fields:       fields,
+		physical:     physical,
+		geo:          newGeoColumn(srid),
+		geomTypes:    make(map[string]bool),
+	}, nil
+}
+
+// physicalType is the Parquet type of a kind of column, decimals are written
+// as doubles which every reader handles
+func physicalType(kind string) (parquet.Type, string) {
+	switch kind {
+	case types.KindInteger:
+		return parquet.Type_INT64, "type=INT64"
+	case types.KindDecimal, types.KindFloat:
+		return parquet.Type_DOUBLE, "type=DOUBLE"
+	case types.KindBoolean:
+		return parquet.Type_BOOLEAN, "type=BOOLEAN"
+	case types.KindDate:
+		return parquet.Type_INT32, "type=INT32, convertedtype=DATE"
+	}
+
+	return parquet.Type_BYTE_ARRAY, "type=BYTE_ARRAY, convertedtype=UTF8"
+}
+
+// Write adds a row, values are in the order of the fields
+func (w *Writer) Write(values []interface{}, wkb []byte) error {
+	var funcName string = "geoparquet.Write"
+
+	if len(values) != len(w.fields) {
+		return fmt.Errorf("%v: %v values for %v fields", funcName, len(values), len(w.fields))
+	}
+
+	var row = make([]interface{}, len(values)+1)
+	for n, value := range values {
+		v, err := w.value(n, value)
+		if err != nil {
+			return fmt.Errorf("%v: %v %v", funcName, w.fields[n].Name, err.Error())
+		}
+		row[n] = v
+	}
+
+	// A missing geometry is a null, byte arrays are written from strings
+	if wkb != nil {
+		row[len(values)] = string(wkb)
+		if geomType, ok := wkbType(wkb); ok {
+			w.geomTypes[geomType] = true
+		}
+	}
+
+	err := w.pw.Write(row)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	w.rows++
+	if w.rows >= w.RowGroupRows {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// value converts a value to the type of its column
+func (w *Writer) value(n int, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	// Values read back from MySQL are bytes
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+
+	return convert(w.fields[n].Kind, w.physical[n], value)
+}
+
+// Buffered is the size of the rows not yet written
+func (w *Writer) Buffered() int {
+	return int(w.pw.Size + w.pw.ObjsSize)
+}
+
+// Flush writes the buffered rows as a row group
+func (w *Writer) Flush() error {
+	var funcName string = "geoparquet.Flush"
+
+	if w.rows == 0 {
+		return nil
+	}
+
+	err := w.pw.Flush(true)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	w.rows = 0
+
+	return nil
+}
+
+// Close writes any buffered rows and the footer, the underlying writer is
+// not closed
+func (w *Writer) Close() error {
+	var funcName string = "geoparquet.Close"
+
+	geo, err := w.geoMetadata()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var value string = string(geo)
+	w.pw.Footer.KeyValueMetadata = append(w.pw.Footer.KeyValueMetadata, &parquet.KeyValue{
+		Key:   "geo",
+		Value: &value,
+	})
+
+	err = w.pw.WriteStop()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// NumRows is the number of rows written
+func (w *Writer) NumRows() int64 {
+	return w.pw.Footer.NumRows + int64(len(w.pw.Objs))
+}
+
+func (w *Writer) geoMetadata() ([]byte, error) {
+	var geo = w.geo
+	geo.GeometryTypes = sortedKeys(w.geomTypes)
+
+	return json.Marshal(geoMetadata{
+		Version:       geoParquetVersion,
+		PrimaryColumn: GeometryColumn,
+		Columns:       map[string]geoColumn{GeometryColumn: geo},
+	})
+}
@@ -1,21 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/rockwell-uk/go-logger/logger"
 	"github.com/rockwell-uk/go-utils/fileutils"
+	"github.com/rockwell-uk/go-utils/sliceutils"
 	"github.com/rockwell-uk/go-utils/timeutils"
 
 	"go-uk-maps-import/autoconfig"
 	"go-uk-maps-import/database"
 	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/filelogger"
+	"go-uk-maps-import/geoparquet"
 	"go-uk-maps-import/importer"
+	"go-uk-maps-import/manifest"
 	"go-uk-maps-import/osdata"
 )
 
@@ -33,6 +39,31 @@ var (
 	lowmemory   bool   = false
 	countsonly  bool   = false
 	dryrun      bool   = false
+	format      string = osdata.FormatShapefile
+	source      string = ""
+	mirror      string = ""
+	verify      string = ""
+	statedir    string = "./resources/state"
+	geomqa      bool   = false
+	generalise  string = ""
+	decoders    int    = 0
+	splitters   int    = 0
+	segments    int    = 0
+	fileworkers int    = 0
+	mapping     string = ""
+	foreignkeys bool   = false
+	lookups     bool   = false
+	measures    bool   = false
+	sqlwriters  int    = 0
+	openfiles   int    = 0
+	sqldialect  string = ""
+	sqlrows     int    = 0
+	sqlbytes    int    = 0
+	sqlcompress bool   = false
+	parquetdir  string = ""
+	geosquares  bool   = false
+	exportdb    bool   = false
+	flatgeobuf  bool   = false
 
 	dbengine  *string
 	dbhost    *string
@@ -52,9 +83,13 @@ var (
 )
 
 const (
-	logFolder   string = "logs"
-	timingsLog  string = "timings.log"
-	checksumLog string = "checksum.log"
+	logFolder        string = "logs"
+	quarantineFolder string = "./resources/quarantine"
+	timingsLog       string = "timings.log"
+	checksumLog      string = "checksum.log"
+	geometryQALog    string = "geometry_qa.log"
+	dedupLog         string = "dedup.log"
+	attributeLog     string = "attributes.log"
 )
 
 func isFlagPassed(name string) bool {
@@ -85,11 +120,40 @@ func main() {
 	// Download osdata source files?
 	flag.BoolVar(&download, "download", download, "download the osdata source files?")
 
+	// Source format to download
+	flag.StringVar(&format, "format", format, "the osdata source format to download shp/gpkg")
+
+	// Download from a mirror instead of the OS Data Hub
+	flag.StringVar(&source, "source", source, "a mirror to download the osdata source files from file:///path or http://host/path")
+
+	// Build a mirror?
+	flag.StringVar(&mirror, "mirror", mirror, "build a mirror of the osdata source files in this folder and exit")
+
+	// Verify a folder of artefacts?
+	flag.StringVar(&verify, "verify", verify, "check the files in this folder against its manifest and exit")
+
+	// Where to keep the release state of the last import
+	flag.StringVar(&statedir, "statedir", statedir, "the folder to keep the state of the last successful import in")
+
 	// Cleardown the database?
 	flag.BoolVar(&cleardown, "cleardown", cleardown, "clear down the database?")
 
 	// Use intermediate SQL files?
 	flag.BoolVar(&usefiles, "usefiles", usefiles, "use intermediate SQL files?")
+	flag.IntVar(&sqlwriters, "sqlwriters", sqlwriters, "the number of goroutines writing the SQL files, each layer is written by one, 0 for one")
+	flag.StringVar(&sqldialect, "sqldialect", sqldialect, "the database the SQL files are written for mysql/pgsql/sqlite, by default the dbengine")
+	flag.IntVar(&openfiles, "sqlopenfiles", openfiles, "the number of SQL files kept open, 0 for a quarter of the file handle limit")
+	flag.IntVar(&sqlrows, "sqlrows", sqlrows, "the most rows in an insert statement of the SQL files, 0 for 1000")
+	flag.IntVar(&sqlbytes, "sqlbytes", sqlbytes, "the most bytes in an insert statement of the SQL files, 0 for 1MiB")
+	flag.BoolVar(&sqlcompress, "sqlcompress", sqlcompress, "gzip the SQL files as they are written?")
+
+	// GeoParquet files
+	flag.StringVar(&parquetdir, "geoparquet", parquetdir, "also write the layers as GeoParquet files in this folder")
+	flag.BoolVar(&geosquares, "geoparquetsquares", geosquares, "write a GeoParquet file per 100km square of each layer?")
+	flag.BoolVar(&exportdb, "exportdb", exportdb, "export the database to the GeoParquet folder instead of importing?")
+
+	// FlatGeobuf files
+	flag.BoolVar(&flatgeobuf, "flatgeobuf", flatgeobuf, "export the SQLite databases as FlatGeobuf files with a spatial index?")
 
 	// Refrain from loading shapefiles into memory?
 	flag.BoolVar(&lowmemory, "lowmemory", lowmemory, "do not read the shapefiles into memory?")
@@ -100,6 +164,28 @@ func main() {
 	// Process all shapefiles at once?
 	flag.BoolVar(&unlimited, "unlimited", unlimited, "process all the shapefiles at once?")
 
+	// Validate and repair geometries?
+	flag.BoolVar(&geomqa, "geomqa", geomqa, "validate, repair and normalise the geometries?")
+
+	// Generalised copies of the area and line layers?
+	flag.StringVar(&generalise, "generalise", generalise, "comma separated tolerances in metres to generalise layers at e.g. 5,25,100")
+
+	// Pipeline parallelism
+	flag.IntVar(&decoders, "decodeworkers", decoders, "the number of workers decoding features per shapefile, 0 for one per CPU")
+	flag.IntVar(&splitters, "splitworkers", splitters, "the number of workers splitting features across the grid per shapefile, 0 for one")
+	flag.IntVar(&fileworkers, "fileworkers", fileworkers, "the number of shapefiles imported at once when concurrent, 0 for all")
+	flag.IntVar(&segments, "segmentworkers", segments, "the number of workers reading each large shapefile, 0 for four, 1 to read them sequentially")
+
+	// Custom Layers
+	flag.StringVar(&mapping, "mapping", mapping, "a JSON file mapping other shapefiles to layers of their own")
+
+	// Lookup tables
+	flag.BoolVar(&lookups, "lookups", lookups, "create the feature code and classification lookup tables?")
+	flag.BoolVar(&foreignkeys, "foreignkeys", foreignkeys, "reference the feature code lookup table from the FEATCODE of each layer?")
+
+	// Geometry measures
+	flag.BoolVar(&measures, "measures", measures, "add the area, length, centroid and label point of the line and polygon features?")
+
 	// Skip processing the .sql files?
 	flag.BoolVar(&skipinserts, "skipinserts", skipinserts, "we skip importing the .sql files?")
 
@@ -107,7 +193,7 @@ func main() {
 	flag.BoolVar(&countsonly, "countsonly", countsonly, "just count the database rows and exit?")
 
 	// Exit before running the import?
-	flag.BoolVar(&dryrun, "dryrun", dryrun, "exit the app before running the import?")
+	flag.BoolVar(&dryrun, "dryrun", dryrun, "print an estimate of the import and exit without running it?")
 
 	// Database
 	flag.StringVar(&dbe, "dbengine", dbe, "the database engine mysql/postgres/sqlite")
@@ -157,12 +243,44 @@ func main() {
 	// Log start time
 	logAppStart()
 
+	// Verify a folder against its manifest, leaving the logs of the last
+	// import alone
+	if verify != "" {
+		verifyManifest(verify)
+	}
+
+	// Generalised layers
+	tolerances, err := importer.ParseTolerances(generalise)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Invalid generalise flag: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+
+	// Layer mapping
+	var layerMapping importer.Mapping
+	if mapping != "" {
+		layerMapping, err = importer.LoadMapping(mapping)
+		if err != nil {
+			logger.Log(
+				logger.LVL_FATAL,
+				fmt.Sprintf("%v: Invalid mapping flag: %v", funcName, err.Error()),
+			)
+			bailOut(1)
+		}
+	}
+
 	// Log files
 	checksumLog := getLogFileName(checksumLog)
 	timingsLog := getLogFileName(timingsLog)
+	geometryQALog := getLogFileName(geometryQALog)
+	dedupLog := getLogFileName(dedupLog)
+	attributeLog := getLogFileName(attributeLog)
 
 	// Clear logs
-	err := clearLogs()
+	err = clearLogs()
 	if err != nil {
 		logger.Log(
 			logger.LVL_FATAL,
@@ -192,16 +310,54 @@ func main() {
 	}
 	defer timingsLogFile.Close()
 
-	// Download Ordnance Survey Data
-	if download {
-		err := osdata.DownloadVectorMapDistrict()
+	geometryQALogFile, err := fileutils.GetFile(geometryQALog)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error opening log file: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+	defer geometryQALogFile.Close()
+
+	dedupLogFile, err := fileutils.GetFile(dedupLog)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error opening log file: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+	defer dedupLogFile.Close()
+
+	attributeLogFile, err := fileutils.GetFile(attributeLog)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error opening log file: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+	defer attributeLogFile.Close()
+
+	// Build a mirror of the Ordnance Survey Data
+	if mirror != "" {
+		err := osdata.BuildMirror(source, format, mirror)
 		if err != nil {
 			logger.Log(
 				logger.LVL_FATAL,
-				fmt.Sprintf("%v: Error downloading osdata: %v", funcName, err.Error()),
+				fmt.Sprintf("%v: Error building mirror: %v", funcName, err.Error()),
 			)
 			bailOut(1)
 		}
+		bailOut(0)
+	}
+
+	// Download Ordnance Survey Data
+	var release, lastRelease osdata.Release
+	var squares []string
+	if download {
+		release, lastRelease, squares = downloadRelease()
 	}
 
 	shapefilesToImport, err := importer.GetAllShapefiles(datafolder)
@@ -213,24 +369,54 @@ func main() {
 		bailOut(1)
 	}
 
+	// The tiles no longer published are not imported, their squares are cleared
+	if download {
+		shapefilesToImport = withoutAreas(shapefilesToImport, release.Removed(lastRelease))
+	}
+
 	// Base App Config
 	var appConfig *autoconfig.AppConfig = &autoconfig.AppConfig{
-		SystemDetails:   autoconfig.GetSystemDetails(),
-		LogsFolder:      logFolder,
-		ChecksumLogFile: checksumLog,
-		TimingsLogFile:  timingsLog,
+		SystemDetails:    autoconfig.GetSystemDetails(),
+		LogsFolder:       logFolder,
+		QuarantineFolder: quarantineFolder,
+		ChecksumLogFile:  checksumLog,
+		TimingsLogFile:   timingsLog,
 		ImporterConfig: importer.Config{
-			DataFolder:    datafolder,
-			ShapeFiles:    shapefilesToImport,
-			NumShapeFiles: len(shapefilesToImport),
-			Download:      download,
-			Concurrent:    concurrent,
-			Unlimited:     unlimited,
-			SkipInserts:   skipinserts,
-			UseFiles:      usefiles,
-			LowMemory:     lowmemory,
-			TimingsLog:    timingsLogFile,
-			ChecksumLog:   checksumLogFile,
+			DataFolder:     datafolder,
+			ShapeFiles:     shapefilesToImport,
+			NumShapeFiles:  len(shapefilesToImport),
+			Download:       download,
+			Concurrent:     concurrent,
+			Unlimited:      unlimited,
+			SkipInserts:    skipinserts,
+			UseFiles:       usefiles,
+			SQLWriters:     sqlwriters,
+			SQLOpenFiles:   openfiles,
+			SQLDialect:     sqldialect,
+			SQLRows:        sqlrows,
+			SQLBytes:       sqlbytes,
+			SQLCompress:    sqlcompress,
+			GeoParquet:     parquetdir,
+			GeoPartition:   geosquares,
+			FlatGeobuf:     flatgeobuf,
+			LowMemory:      lowmemory,
+			Squares:        squares,
+			TimingsLog:     timingsLogFile,
+			ChecksumLog:    checksumLogFile,
+			QALog:          geometryQALogFile,
+			DedupLog:       dedupLogFile,
+			AttributeLog:   attributeLogFile,
+			GeometryQA:     geomqa,
+			Generalise:     tolerances,
+			DecodeWorkers:  decoders,
+			SplitWorkers:   splitters,
+			SegmentWorkers: segments,
+			FileWorkers:    fileworkers,
+			StateDir:       statedir,
+			Mapping:        layerMapping,
+			ForeignKeys:    foreignkeys,
+			Lookups:        lookups,
+			Measures:       measures,
 			DB: engine.SEConfig{
 				Engine: dbengine,
 				DBConfig: engine.DBConfig{
@@ -248,6 +434,16 @@ func main() {
 		DryRun: dryrun,
 	}
 
+	// Generalised layers and the derived fields of any record transformers
+	appConfig.ImporterConfig.DB.Schema, err = importer.NewSchema(appConfig.ImporterConfig)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error building schema: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+
 	// Config Check
 	configCheckResults, err := autoconfig.ConfigCheck(appConfig)
 	if err != nil {
@@ -325,8 +521,42 @@ func main() {
 			fmt.Sprintf("TableCounts: %v\n", tableCounts.TableCounts),
 		)
 
+	case appConfig.DryRun:
+		estimate, err := autoconfig.EstimateImport(appConfig)
+		if err != nil {
+			logger.Log(
+				logger.LVL_FATAL,
+				fmt.Sprintf("%v: Error estimating import: %v", funcName, err.Error()),
+			)
+			bailOut(1)
+		}
+
+		logger.Log(
+			logger.LVL_APP,
+			fmt.Sprintf("Import Estimate:\n%v", estimate),
+		)
+
+	case exportdb:
+		err := exportDatabase(appConfig.ImporterConfig.DB.StorageEngine)
+		if err != nil {
+			logger.Log(
+				logger.LVL_FATAL,
+				fmt.Sprintf("%v: Error exporting database: %v", funcName, err.Error()),
+			)
+			bailOut(1)
+		}
+
 	case !appConfig.DryRun:
-		err := importer.Run(start, appConfig.ImporterConfig)
+		err := quarantineSources(appConfig.InvalidSources, appConfig.QuarantineFolder)
+		if err != nil {
+			logger.Log(
+				logger.LVL_FATAL,
+				fmt.Sprintf("%v: Error quarantining sources: %v", funcName, err.Error()),
+			)
+			bailOut(1)
+		}
+
+		err = runImport(appConfig.ImporterConfig)
 		if err != nil {
 			logger.Log(
 				logger.LVL_FATAL,
@@ -334,12 +564,216 @@ func main() {
 			)
 			bailOut(1)
 		}
+
+		// Remember what was imported for next time, the quarantined tiles are
+		// tried again by the next import
+		if download && !skipinserts {
+			var failed []string
+			for _, source := range appConfig.InvalidSources {
+				failed = append(failed, importer.GetSourceArea(source))
+			}
+
+			err := osdata.SaveRelease(statedir, release.Imported(lastRelease, failed))
+			if err != nil {
+				logger.Log(
+					logger.LVL_FATAL,
+					fmt.Sprintf("%v: Error saving release state: %v", funcName, err.Error()),
+				)
+				bailOut(1)
+			}
+		}
 	}
 
 	// Stop any dependencies and cleanup
 	stopApp(appConfig)
 }
 
+// downloadRelease downloads the tiles that changed since the last import and
+// returns the release and the last release along with the squares to
+// re-import, none meaning all, the squares of tiles no longer published are
+// cleared
+func downloadRelease() (osdata.Release, osdata.Release, []string) {
+	var funcName string = "main.downloadRelease"
+
+	release, err := osdata.GetRelease(source, format)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error downloading osdata: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+
+	lastRelease, err := osdata.LoadRelease(statedir)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error loading release state: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+
+	var changed []string = release.Changed(lastRelease)
+	var removed []string = release.Removed(lastRelease)
+	var squares []string
+
+	switch {
+	case cleardown || lastRelease.IsEmpty():
+		// Full import
+		changed = release.Changed(osdata.Release{})
+
+	case len(changed) == 0 && len(removed) == 0:
+		logger.Log(
+			logger.LVL_APP,
+			"The release is unchanged since the last import, nothing to do",
+		)
+		bailOut(0)
+
+	default:
+		squares = importer.ExpandSquares(append(append([]string{}, changed...), removed...))
+
+		logger.Log(
+			logger.LVL_APP,
+			fmt.Sprintf("Tiles changed since the last import %v", changed),
+		)
+
+		if len(removed) > 0 {
+			logger.Log(
+				logger.LVL_APP,
+				fmt.Sprintf("Tiles removed since the last import %v", removed),
+			)
+		}
+	}
+
+	err = osdata.DownloadVectorMapDistrict(release, changed)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error downloading osdata: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+
+	return release, lastRelease, squares
+}
+
+// withoutAreas leaves out the sources of the given tiles
+func withoutAreas(sources []string, areas []string) []string {
+	var skip = make(map[string]bool)
+	for _, area := range areas {
+		skip[area] = true
+	}
+
+	var kept = []string{}
+	for _, source := range sources {
+		if !skip[importer.GetSourceArea(source)] {
+			kept = append(kept, source)
+		}
+	}
+
+	return kept
+}
+
+// verifyManifest checks a folder against its manifest and exits, with 1 if
+// anything does not match
+func verifyManifest(folder string) {
+	var funcName string = "main.verifyManifest"
+
+	problems, err := manifest.Verify(folder)
+	if err != nil {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v: Error verifying manifest: %v", funcName, err.Error()),
+		)
+		bailOut(1)
+	}
+
+	if len(problems) > 0 {
+		logger.Log(
+			logger.LVL_FATAL,
+			fmt.Sprintf("%v does not match its manifest %v", folder, sliceutils.TabList(problems)),
+		)
+		bailOut(1)
+	}
+
+	logger.Log(
+		logger.LVL_APP,
+		fmt.Sprintf("%v matches its manifest", folder),
+	)
+	bailOut(0)
+}
+
+// exportDatabase writes the layers of the database to GeoParquet files
+func exportDatabase(se engine.StorageEngine) error {
+	var funcName string = "main.exportDatabase"
+
+	var e *geoparquet.Exporter = geoparquet.New(parquetdir, se.GetSchema())
+	e.Partition = geosquares
+
+	err := e.Start()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = database.ExportGeoParquet(se, e)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = e.Close()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// runImport runs the import until it is done or the app is interrupted
+func runImport(config importer.Config) error {
+	var funcName string = "main.runImport"
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	imp, err := importer.New(config)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = imp.Run(ctx)
+	if err != nil {
+		imp.Close() //nolint:errcheck
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = imp.Close()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// quarantineSources moves the sources that failed validation out of the
+// data folder so they are not picked up again
+func quarantineSources(sources []string, quarantineFolder string) error {
+	var funcName string = "main.quarantineSources"
+
+	for _, source := range sources {
+		err := importer.Quarantine(source, quarantineFolder)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		logger.Log(
+			logger.LVL_APP,
+			fmt.Sprintf("Quarantined invalid source %v", source),
+		)
+	}
+
+	return nil
+}
+
 func startLoggers(vbs logger.LogLvl) {
 	// Start main logger
 	logger.Start(vbs)
@@ -5,31 +5,36 @@ go 1.18
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/go-sql-driver/mysql v1.7.0
+	github.com/google/flatbuffers v24.3.25+incompatible
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/lib/pq v1.10.7
 	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/pbnjay/memory v0.0.0-20210728143218-7b4eea64cf58
 	github.com/rockwell-uk/csync v1.0.0
-	github.com/rockwell-uk/datastore v1.0.0
 	github.com/rockwell-uk/go-diskbench v1.0.0
 	github.com/rockwell-uk/go-logger v1.0.0
 	github.com/rockwell-uk/go-nationalgrid v1.0.0
 	github.com/rockwell-uk/go-progress v1.0.0
-	github.com/rockwell-uk/go-shpconvert v1.0.0
 	github.com/rockwell-uk/go-sqlbench v1.0.0
 	github.com/rockwell-uk/go-utils v1.0.0
-	github.com/rockwell-uk/shapefile v1.0.0
 	github.com/rockwell-uk/uiprogress v1.0.0
 	github.com/schollz/sqlite3dump v1.3.1
 	github.com/twpayne/go-geos v0.13.1
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	golang.org/x/text v0.8.0
 )
 
 require (
+	github.com/apache/arrow/go/arrow v0.0.0-20200730104253-651201b0f516 // indirect
+	github.com/apache/thrift v0.14.2 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/golang/snappy v0.0.3 // indirect
 	github.com/gosuri/uilive v0.0.4 // indirect
+	github.com/klauspost/compress v1.13.1 // indirect
 	github.com/llgcode/draw2d v0.0.0-20210904075650-80aa0a2a901d // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/pierrec/lz4/v4 v4.1.8 // indirect
 	github.com/rockwell-uk/go-draw v1.0.0 // indirect
 	github.com/rockwell-uk/go-geos-draw v1.0.0 // indirect
 	github.com/rockwell-uk/go-text v1.0.0 // indirect
@@ -37,4 +42,5 @@ require (
 	github.com/wroge/wgs84 v1.1.6 // indirect
 	golang.org/x/image v0.6.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
 )
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rockwell-uk/go-logger/logger"
+
+	"go-uk-maps-import/filelogger"
+)
+
+const (
+	// How many invalid values to list per layer
+	attrMaxInvalid = 100
+)
+
+// attributeReport counts the values of typed fields that could not be parsed
+type attributeReport struct {
+	mu     sync.Mutex
+	layers map[string]*layerAttributes
+}
+
+type layerAttributes struct {
+	Invalid  map[string]int
+	Examples []string
+}
+
+func newAttributeReport() *attributeReport {
+	return &attributeReport{
+		layers: make(map[string]*layerAttributes),
+	}
+}
+
+// parseAttributes converts the values of the typed fields of a record, an
+// invalid value is reported and written as NULL
+func (i *Importer) parseAttributes(dbName string, rec Record) Record {
+	var parsed = make(Record, len(rec))
+
+	for field, value := range rec {
+		column, typed := i.schema.Columns[field]
+		if !typed {
+			parsed[field] = value
+			continue
+		}
+
+		v, err := column.Parse(value)
+		if err == nil {
+			// A code the lookup table does not have would fail the foreign key
+			err = i.schema.CheckReference(field, v)
+			if err != nil {
+				v = nil
+			}
+		}
+		if err != nil {
+			i.attrs.record(dbName, field, fmt.Sprintf("%v", rec["ID"]), err)
+		}
+		parsed[field] = v
+	}
+
+	return parsed
+}
+
+func (r *attributeReport) record(dbName, field, id string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, exists := r.layers[dbName]
+	if !exists {
+		l = &layerAttributes{
+			Invalid: make(map[string]int),
+		}
+		r.layers[dbName] = l
+	}
+
+	l.Invalid[field]++
+	if len(l.Examples) < attrMaxInvalid {
+		l.Examples = append(l.Examples, fmt.Sprintf("%v %v [%v]", id, field, err.Error()))
+	}
+}
+
+func (l layerAttributes) String() string {
+	fields := make([]string, 0, len(l.Invalid))
+	for field := range l.Invalid {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var counts []string
+	for _, field := range fields {
+		counts = append(counts, fmt.Sprintf("%v %v", field, l.Invalid[field]))
+	}
+
+	return fmt.Sprintf("invalid %v\n\tvalues:\n\t\t%v", strings.Join(counts, ", "), strings.Join(l.Examples, "\n\t\t"))
+}
+
+func (r *attributeReport) log(logFile io.Writer) {
+	var invalid int
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	layers := make([]string, 0, len(r.layers))
+	for layer := range r.layers {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+
+	for _, layer := range layers {
+		for _, n := range r.layers[layer].Invalid {
+			invalid += n
+		}
+
+		filelogger.Log(
+			filelogger.LogLine{
+				File: logFile,
+				Line: fmt.Sprintf("[%v] %v\n", layer, r.layers[layer]),
+			},
+		)
+	}
+
+	logger.Log(
+		logger.LVL_APP,
+		fmt.Sprintf("%v invalid attribute values written as NULL\n", invalid),
+	)
+}
@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-uk-maps-import/database/types"
+)
+
+func TestParseColumn(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected types.Column
+		logical  bool
+	}{
+		"integer": {
+			input:    "integer",
+			expected: types.Column{Kind: types.KindInteger},
+			logical:  true,
+		},
+		"decimal": {
+			input:    "decimal(10, 2)",
+			expected: types.Column{Kind: types.KindDecimal, Length: 10, Decimals: 2},
+			logical:  true,
+		},
+		"enum": {
+			input:    "enum(Small,Medium,Large)",
+			expected: types.Column{Kind: types.KindEnum, Values: []string{"Small", "Medium", "Large"}},
+			logical:  true,
+		},
+		"sql": {
+			input: "varchar(50) DEFAULT NULL",
+		},
+	}
+
+	for name, tt := range tests {
+		actual, logical := types.ParseColumn(tt.input)
+		if logical != tt.logical || !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v %v, got %v %v", name, tt.expected, tt.logical, actual, logical)
+		}
+	}
+}
+
+func TestAddColumn(t *testing.T) {
+	tests := map[string]struct {
+		columns  []types.Column
+		expected types.Column
+		valid    bool
+	}{
+		"widened": {
+			columns:  []types.Column{{Kind: types.KindText, Length: 10}, {Kind: types.KindText, Length: 50}, {Kind: types.KindText, Length: 20}},
+			expected: types.Column{Kind: types.KindText, Length: 50},
+			valid:    true,
+		},
+		"decimals": {
+			columns:  []types.Column{{Kind: types.KindDecimal, Length: 8, Decimals: 2}, {Kind: types.KindDecimal, Length: 6, Decimals: 3}},
+			expected: types.Column{Kind: types.KindDecimal, Length: 8, Decimals: 3},
+			valid:    true,
+		},
+		"enum values": {
+			columns:  []types.Column{{Kind: types.KindEnum, Values: []string{"A", "B"}}, {Kind: types.KindEnum, Values: []string{"B", "C"}}},
+			expected: types.Column{Kind: types.KindEnum, Values: []string{"A", "B", "C"}},
+			valid:    true,
+		},
+		"different kinds": {
+			columns: []types.Column{{Kind: types.KindText, Length: 10}, {Kind: types.KindInteger, Length: 10}},
+		},
+	}
+
+	schema := types.NewSchema()
+
+	for name, tt := range tests {
+		var err error
+		for _, c := range tt.columns {
+			if err = schema.AddColumn(name, c); err != nil {
+				break
+			}
+		}
+
+		if tt.valid != (err == nil) {
+			t.Errorf("%v: expected valid %v, got %v", name, tt.valid, err)
+			continue
+		}
+
+		if tt.valid && !reflect.DeepEqual(tt.expected, schema.Columns[name]) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, schema.Columns[name])
+		}
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	i := &Importer{
+		schema: types.NewSchema(),
+		attrs:  newAttributeReport(),
+	}
+
+	tests := map[string]struct {
+		input    Record
+		expected Record
+		invalid  int
+	}{
+		"typed": {
+			input:    Record{"ID": "a1", "FEATCODE": "25200.0000", "HEIGHT": "512", "DISTNAME": "Leeds"},
+			expected: Record{"ID": "a1", "FEATCODE": int64(25200), "HEIGHT": 512.0, "DISTNAME": "Leeds"},
+		},
+		"empty": {
+			input:    Record{"ID": "a2", "DRAWLEVEL": ""},
+			expected: Record{"ID": "a2", "DRAWLEVEL": nil},
+		},
+		"invalid": {
+			input:    Record{"ID": "a3", "FEATCODE": "abc", "ORIENTATIO": "NaN"},
+			expected: Record{"ID": "a3", "FEATCODE": nil, "ORIENTATIO": nil},
+			invalid:  2,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := i.parseAttributes(name, tt.input)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+
+		var invalid int
+		if l, exists := i.attrs.layers[name]; exists {
+			for _, n := range l.Invalid {
+				invalid += n
+			}
+		}
+		if invalid != tt.invalid {
+			t.Errorf("%v: expected %v invalid values, got %v", name, tt.invalid, invalid)
+		}
+	}
+}
+
+func TestParseAttributesForeignKeys(t *testing.T) {
+	i := &Importer{
+		schema: types.NewSchema(),
+		attrs:  newAttributeReport(),
+	}
+	i.schema.ForeignKeys = true
+
+	tests := map[string]struct {
+		input    Record
+		expected Record
+		invalid  int
+	}{
+		"known": {
+			input:    Record{"ID": "a1", "FEATCODE": "25710"},
+			expected: Record{"ID": "a1", "FEATCODE": int64(25710)},
+		},
+		"unknown": {
+			input:    Record{"ID": "a2", "FEATCODE": "12345"},
+			expected: Record{"ID": "a2", "FEATCODE": nil},
+			invalid:  1,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := i.parseAttributes(name, tt.input)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+
+		var invalid int
+		if l, exists := i.attrs.layers[name]; exists {
+			invalid = l.Invalid["FEATCODE"]
+		}
+		if invalid != tt.invalid {
+			t.Errorf("%v: expected %v invalid values, got %v", name, tt.invalid, invalid)
+		}
+	}
+}
+
+func TestFeatureCodes(t *testing.T) {
+	var codes = make(map[int]bool)
+	for _, f := range types.FeatureCodes {
+		if codes[f.Code] {
+			t.Errorf("feature code %v is in the catalogue more than once", f.Code)
+		}
+		codes[f.Code] = true
+
+		if _, exists := types.OSLayer(f.Layer); !exists {
+			t.Errorf("feature code %v has an unknown layer %v", f.Code, f.Layer)
+		}
+	}
+
+	// Every OS layer has its codes in the catalogue
+	var layers = make(map[string]bool)
+	for _, f := range types.FeatureCodes {
+		layers[f.Layer] = true
+	}
+	for layerType := range types.NewSchema().MapLayers {
+		if !layers[layerType] {
+			t.Errorf("layer %v has no feature codes", layerType)
+		}
+	}
+
+	// Every code in the test data is in the catalogue
+	shapeFiles, err := filepath.Glob("./testdata/*.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	more, err := filepath.Glob("../testdata/*.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, shapeFile := range append(shapeFiles, more...) {
+		s, err := openSegmentedShapefile(shapeFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for n := 0; n < s.records(); n++ {
+			rec, _, err := s.record(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec == nil {
+				continue
+			}
+
+			code, err := types.NewSchema().Columns["FEATCODE"].Parse(rec["FEATCODE"])
+			if err != nil {
+				t.Fatalf("%v: %v", shapeFile, err)
+			}
+
+			if !types.IsFeatureCode(int(code.(int64))) {
+				t.Errorf("%v: feature code %v is not in the catalogue", shapeFile, code)
+			}
+		}
+
+		s.Close()
+	}
+}
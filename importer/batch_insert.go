@@ -20,9 +20,12 @@ func (b batchInsert) String() string {
 	return r
 }
 
-type insert map[string]interface{}
+// Record is the attributes of a feature keyed by field name
+type Record map[string]interface{}
 
-func (i insert) String() string {
+type insert = Record
+
+func (i Record) String() string {
 	var r string
 
 	for k, insert := range i {
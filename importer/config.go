@@ -5,22 +5,51 @@ import (
 	"io"
 
 	"go-uk-maps-import/database/engine"
+	"go-uk-maps-import/database/types"
 )
 
 type Config struct {
-	DataFolder    string
-	ShapeFiles    []string
-	NumShapeFiles int
-	Download      bool
-	Concurrent    bool
-	Unlimited     bool
-	SkipInserts   bool
-	UseFiles      bool
-	LowMemory     bool
-	TimingsLog    io.Writer
-	ChecksumLog   io.Writer
-	DB            engine.SEConfig
-	IsTest        bool
+	DataFolder     string
+	ShapeFiles     []string
+	NumShapeFiles  int
+	Download       bool
+	Concurrent     bool
+	Unlimited      bool
+	SkipInserts    bool
+	UseFiles       bool
+	LowMemory      bool
+	Squares        []string
+	GeometryQA     bool
+	Generalise     []int
+	Transformers   []RecordTransformer
+	SQLFolder      string
+	SQLWriters     int
+	SQLOpenFiles   int
+	SQLDialect     string
+	SQLRows        int
+	SQLBytes       int
+	SQLCompress    bool
+	GeoParquet     string
+	GeoPartition   bool
+	FlatGeobuf     bool
+	DecodeWorkers  int
+	SplitWorkers   int
+	QueueSize      int
+	SegmentWorkers int
+	SegmentRecords int
+	FileWorkers    int
+	StateDir       string
+	TimingsLog     io.Writer
+	ChecksumLog    io.Writer
+	QALog          io.Writer
+	DedupLog       io.Writer
+	AttributeLog   io.Writer
+	Mapping        Mapping
+	ForeignKeys    bool
+	Lookups        bool
+	Measures       bool
+	DB             engine.SEConfig
+	IsTest         bool
 }
 
 func (c Config) String() string {
@@ -31,7 +60,32 @@ func (c Config) String() string {
 		"\t\t"+"Unlimited: %v"+"\n"+
 		"\t\t"+"SkipInserts: %v"+"\n"+
 		"\t\t"+"UseFiles: %v"+"\n"+
-		"\t\t"+"LowMemory: %v",
+		"\t\t"+"LowMemory: %v"+"\n"+
+		"\t\t"+"Squares: %v"+"\n"+
+		"\t\t"+"GeometryQA: %v"+"\n"+
+		"\t\t"+"Generalise: %v"+"\n"+
+		"\t\t"+"Transformers: %v"+"\n"+
+		"\t\t"+"SQLFolder: %v"+"\n"+
+		"\t\t"+"SQLWriters: %v"+"\n"+
+		"\t\t"+"SQLOpenFiles: %v"+"\n"+
+		"\t\t"+"SQLDialect: %v"+"\n"+
+		"\t\t"+"SQLRows: %v"+"\n"+
+		"\t\t"+"SQLBytes: %v"+"\n"+
+		"\t\t"+"SQLCompress: %v"+"\n"+
+		"\t\t"+"GeoParquet: %v"+"\n"+
+		"\t\t"+"GeoPartition: %v"+"\n"+
+		"\t\t"+"FlatGeobuf: %v"+"\n"+
+		"\t\t"+"DecodeWorkers: %v"+"\n"+
+		"\t\t"+"SplitWorkers: %v"+"\n"+
+		"\t\t"+"QueueSize: %v"+"\n"+
+		"\t\t"+"SegmentWorkers: %v"+"\n"+
+		"\t\t"+"SegmentRecords: %v"+"\n"+
+		"\t\t"+"FileWorkers: %v"+"\n"+
+		"\t\t"+"StateDir: %v"+"\n"+
+		"\t\t"+"Mapping: %v"+"\n"+
+		"\t\t"+"ForeignKeys: %v"+"\n"+
+		"\t\t"+"Lookups: %v"+"\n"+
+		"\t\t"+"Measures: %v",
 		c.DataFolder,
 		c.NumShapeFiles,
 		c.Download,
@@ -40,5 +94,64 @@ func (c Config) String() string {
 		c.SkipInserts,
 		c.UseFiles,
 		c.LowMemory,
+		c.Squares,
+		c.GeometryQA,
+		c.Generalise,
+		len(c.Transformers),
+		c.SQLFolder,
+		c.SQLWriters,
+		c.SQLOpenFiles,
+		c.SQLDialect,
+		c.SQLRows,
+		c.SQLBytes,
+		c.SQLCompress,
+		c.GeoParquet,
+		c.GeoPartition,
+		c.FlatGeobuf,
+		c.DecodeWorkers,
+		c.SplitWorkers,
+		c.QueueSize,
+		c.SegmentWorkers,
+		c.SegmentRecords,
+		c.FileWorkers,
+		c.StateDir,
+		c.Mapping,
+		c.ForeignKeys,
+		c.Lookups,
+		c.Measures,
 	)
 }
+
+// GetSQLDialect is the dialect of the SQL files, by default that of the
+// storage engine, SQLite databases are exported to MySQL files
+func GetSQLDialect(c Config) string {
+	if c.SQLDialect != "" {
+		return c.SQLDialect
+	}
+
+	if c.DB.Engine == nil || *c.DB.Engine == engine.EngineSQLite {
+		return types.DialectMySQL
+	}
+
+	return *c.DB.Engine
+}
+
+// getStorageDialect is the dialect the tables are created in
+func getStorageDialect(c Config) string {
+	if c.DB.Engine == nil {
+		return types.DialectMySQL
+	}
+
+	return *c.DB.Engine
+}
+
+// CheckSQLDialect returns an error for SQL files that would be loaded into a
+// database of another dialect, the loader connects with the config of the
+// storage engine so files for another database can only be generated
+func CheckSQLDialect(c Config) error {
+	if !c.UseFiles || c.SkipInserts || c.SQLDialect == "" || c.SQLDialect == getStorageDialect(c) {
+		return nil
+	}
+
+	return fmt.Errorf("%v SQL files cannot be loaded into the %v engine, the inserts must be skipped", c.SQLDialect, getStorageDialect(c))
+}
@@ -0,0 +1,493 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-nationalgrid"
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/filelogger"
+)
+
+const (
+	// How many conflicting features to list per layer
+	dedupMaxConflicts = 100
+
+	// How many files the held features are spread over, each file is
+	// resolved on its own so only one is in memory at a time
+	dedupBuckets = 64
+)
+
+// dedupIndex holds the features that cross the edge of their tile until
+// every tile has been read, the same feature is in the shapefile of each
+// tile it crosses, features inside their tile are only in one shapefile,
+// the held features are written to disk as there can be many of them
+type dedupIndex struct {
+	mu      sync.Mutex
+	dir     string
+	buckets [dedupBuckets]*dedupBucket
+	layers  map[string]*layerDedup
+}
+
+type dedupBucket struct {
+	file *os.File
+	enc  *gob.Encoder
+}
+
+// heldFeature is the piece of a feature read from one tile
+type heldFeature struct {
+	DBName      string
+	ID          string
+	SfShortName string
+	Rec         Record
+	WKB         []byte
+}
+
+type layerDedup struct {
+	Held       int
+	Duplicates int
+	Merged     int
+	Conflicts  int
+	Conflicted []string
+}
+
+func init() {
+	// Dates are the only attribute values gob does not know about
+	gob.Register(time.Time{})
+}
+
+func newDedupIndex() *dedupIndex {
+	return &dedupIndex{
+		layers: make(map[string]*layerDedup),
+	}
+}
+
+// mergesTiles reports whether features that cross tiles are merged, when
+// squares are re-imported the neighbouring tiles write only the squares that
+// were cleared so the pieces are written to their squares as they are read
+func (i *Importer) mergesTiles() bool {
+	return len(i.config.Squares) == 0
+}
+
+// crossesTile reports whether a feature reaches outside the 100km square of
+// the tile it was read from, the national GeoPackage has no tiles
+func crossesTile(sfShortName string, bounds *geos.Bounds) bool {
+	var square string = getTileSquare(sfShortName)
+	if square == "" || square == strings.ToLower(geoPackageSquare) {
+		return false
+	}
+
+	squares := nationalgrid.GetSubSquares(bounds)
+	if len(squares) != 1 {
+		return true
+	}
+
+	_, inTile := squares[square]
+
+	return !inTile
+}
+
+// getTileSquare returns the square of a tile e.g. sd for SD_Road.shp
+func getTileSquare(sfShortName string) string {
+	i := strings.Index(sfShortName, "_")
+	if i < 0 {
+		return ""
+	}
+
+	return strings.ToLower(sfShortName[:i])
+}
+
+func (d *dedupIndex) hold(dbName, sfShortName string, rec Record, wkb []byte) error {
+	var funcName string = "importer.dedupIndex.hold"
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var id string = fmt.Sprintf("%v", rec["ID"])
+
+	b, err := d.getBucket(getBucket(dbName, id))
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = b.enc.Encode(heldFeature{
+		DBName:      dbName,
+		ID:          id,
+		SfShortName: sfShortName,
+		Rec:         rec,
+		WKB:         wkb,
+	})
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	d.getLayer(dbName).Held++
+
+	return nil
+}
+
+// getBucket returns the bucket the pieces of a feature are written to
+func getBucket(dbName, id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(dbName + "/" + id))
+
+	return int(h.Sum32() % dedupBuckets)
+}
+
+func (d *dedupIndex) getBucket(n int) (*dedupBucket, error) {
+	if d.buckets[n] != nil {
+		return d.buckets[n], nil
+	}
+
+	if d.dir == "" {
+		dir, err := os.MkdirTemp("", "dedup")
+		if err != nil {
+			return nil, err
+		}
+		d.dir = dir
+	}
+
+	f, err := os.Create(filepath.Join(d.dir, fmt.Sprintf("%v.gob", n)))
+	if err != nil {
+		return nil, err
+	}
+
+	d.buckets[n] = &dedupBucket{
+		file: f,
+		enc:  gob.NewEncoder(f),
+	}
+
+	return d.buckets[n], nil
+}
+
+// readBucket returns the pieces held in a bucket keyed by layer and ID
+func (d *dedupIndex) readBucket(n int) (map[string]map[string][]heldFeature, error) {
+	var funcName string = "importer.dedupIndex.readBucket"
+
+	var features = make(map[string]map[string][]heldFeature)
+
+	b := d.buckets[n]
+	if b == nil {
+		return features, nil
+	}
+
+	_, err := b.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	dec := gob.NewDecoder(b.file)
+	for {
+		var p heldFeature
+		err := dec.Decode(&p)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		ids, exists := features[p.DBName]
+		if !exists {
+			ids = make(map[string][]heldFeature)
+			features[p.DBName] = ids
+		}
+		ids[p.ID] = append(ids[p.ID], p)
+	}
+
+	return features, nil
+}
+
+// close removes the held features
+func (d *dedupIndex) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for n, b := range d.buckets {
+		if b != nil {
+			b.file.Close()
+			d.buckets[n] = nil
+		}
+	}
+
+	if d.dir != "" {
+		os.RemoveAll(d.dir)
+		d.dir = ""
+	}
+}
+
+func (d *dedupIndex) getLayer(dbName string) *layerDedup {
+	l, exists := d.layers[dbName]
+	if !exists {
+		l = &layerDedup{}
+		d.layers[dbName] = l
+	}
+
+	return l
+}
+
+// resolve reduces the pieces of each feature in a bucket to one, identical
+// pieces are dropped, pieces with the same attributes are unioned and pieces
+// with different attributes are conflicts, of which the first is kept, the
+// features are returned keyed by the source they are counted against
+func (d *dedupIndex) resolve(ctx *geos.Context, config Config, schema *types.Schema, bucket int) (map[string][]importAction, error) {
+	var funcName string = "importer.dedupIndex.resolve"
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	features, err := d.readBucket(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var resolved = make(map[string][]importAction)
+
+	for dbName, ids := range features {
+		l := d.getLayer(dbName)
+
+		for id, pieces := range ids {
+			// Tile order so the result does not depend on the import order
+			sort.SliceStable(pieces, func(i, j int) bool {
+				return pieces[i].SfShortName < pieces[j].SfShortName
+			})
+
+			first := pieces[0]
+			action := importAction{insert: first.Rec, wkb: first.WKB}
+
+			switch {
+			case len(pieces) == 1:
+
+			case !sameAttributes(pieces):
+				l.Conflicts++
+				if len(l.Conflicted) < dedupMaxConflicts {
+					l.Conflicted = append(l.Conflicted, fmt.Sprintf("%v %v", id, getPieceSources(pieces)))
+				}
+
+				logger.Log(
+					logger.LVL_WARN,
+					fmt.Sprintf("%v [%v] has different attributes in %v, keeping %v", dbName, id, getPieceSources(pieces), first.SfShortName),
+				)
+
+			case sameGeometry(pieces):
+				l.Duplicates += len(pieces) - 1
+
+			default:
+				g, err := unionPieces(ctx, config, pieces)
+				if err != nil {
+					logger.Log(
+						logger.LVL_ERROR,
+						fmt.Sprintf("%v [%v] %v", dbName, id, err.Error()),
+					)
+					break
+				}
+
+				// The measures of the pieces are replaced by those of the feature
+				if config.Measures {
+					action.insert = measureRecord(schema, dbName, action.insert, g)
+				}
+
+				action.wkb = g.ToWKB()
+				l.Merged += len(pieces) - 1
+			}
+
+			resolved[first.SfShortName] = append(resolved[first.SfShortName], action)
+		}
+	}
+
+	return resolved, nil
+}
+
+func sameAttributes(pieces []heldFeature) bool {
+	first := pieces[0].Rec
+
+	for _, p := range pieces[1:] {
+		if len(p.Rec) != len(first) {
+			return false
+		}
+
+		for field, value := range first {
+			// Each piece is measured on its own
+			if types.IsMeasureField(field) {
+				continue
+			}
+
+			if fmt.Sprintf("%v", p.Rec[field]) != fmt.Sprintf("%v", value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func sameGeometry(pieces []heldFeature) bool {
+	for _, p := range pieces[1:] {
+		if !bytes.Equal(p.WKB, pieces[0].WKB) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unionPieces joins the pieces of a feature split across tiles
+func unionPieces(ctx *geos.Context, config Config, pieces []heldFeature) (*geos.Geom, error) {
+	var funcName string = "importer.unionPieces"
+
+	var g *geos.Geom
+	for _, p := range pieces {
+		piece, err := ctx.NewGeomFromWKB(p.WKB)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		if g == nil {
+			g = piece
+			continue
+		}
+		g = g.Union(piece)
+	}
+
+	// Keep the layer type when the geometries are normalised
+	if config.GeometryQA {
+		if targetType, ok := multiTypes[g.TypeID()]; ok {
+			g = toMulti(ctx, g, targetType)
+			if targetType == geos.TypeIDMultiPolygon {
+				g = g.Normalize()
+			}
+		}
+	}
+
+	return g, nil
+}
+
+func getPieceSources(pieces []heldFeature) []string {
+	var sources []string
+	for _, p := range pieces {
+		sources = append(sources, p.SfShortName)
+	}
+
+	return sources
+}
+
+// importHeld writes the features held by the dedup index once per feature,
+// the rows are counted against the first tile the feature was read from
+func (i *Importer) importHeld(ctx context.Context) error {
+	var funcName string = "importer.importHeld"
+
+	gctx := geos.NewContext()
+
+	for bucket := 0; bucket < dedupBuckets; bucket++ {
+		resolved, err := i.dedup.resolve(gctx, i.config, i.schema, bucket)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		sources := make([]string, 0, len(resolved))
+		for sfShortName := range resolved {
+			sources = append(sources, sfShortName)
+		}
+		sort.Strings(sources)
+
+		for _, sfShortName := range sources {
+			err := i.importResolved(ctx, sfShortName, resolved[sfShortName])
+			if err != nil {
+				return fmt.Errorf("%v: %v", funcName, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// importResolved writes the resolved features of a source
+func (i *Importer) importResolved(ctx context.Context, sfShortName string, actions []importAction) error {
+	var funcName string = "importer.importResolved"
+
+	read := func(ctx context.Context, out chan<- importAction) error {
+		for _, action := range actions {
+			select {
+			case out <- action:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	}
+
+	p := i.newPipeline(getLayerName(i.config, sfShortName), sfShortName)
+	p.held = true
+
+	rowsGenerated, err := p.run(ctx, read)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, info := range i.rateInfo {
+		if info.ShapeFile == sfShortName {
+			mergeRowsGenerated(rowsGenerated, info.Rows)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (d *dedupIndex) log(logFile io.Writer) {
+	var merged, conflicts int
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	layers := make([]string, 0, len(d.layers))
+	for layer := range d.layers {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+
+	for _, layer := range layers {
+		l := d.layers[layer]
+		merged += l.Duplicates + l.Merged
+		conflicts += l.Conflicts
+
+		filelogger.Log(
+			filelogger.LogLine{
+				File: logFile,
+				Line: fmt.Sprintf("[%v] %v\n", layer, l),
+			},
+		)
+	}
+
+	logger.Log(
+		logger.LVL_APP,
+		fmt.Sprintf("%v duplicate features merged, %v conflicts\n", merged, conflicts),
+	)
+}
+
+func (l layerDedup) String() string {
+	var s string = fmt.Sprintf("held %v, duplicates %v, merged %v, conflicts %v", l.Held, l.Duplicates, l.Merged, l.Conflicts)
+
+	if len(l.Conflicted) > 0 {
+		sort.Strings(l.Conflicted)
+		s += fmt.Sprintf("\n\tconflicts:\n\t\t%v", strings.Join(l.Conflicted, "\n\t\t"))
+	}
+
+	return s
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+)
+
+func TestCrossesTile(t *testing.T) {
+	tests := map[string]struct {
+		sfShortName string
+		bounds      *geos.Bounds
+		expected    bool
+	}{
+		"inside": {
+			sfShortName: "SD_Road.shp",
+			bounds:      &geos.Bounds{MinX: 350000, MinY: 450000, MaxX: 350100, MaxY: 450100},
+			expected:    false,
+		},
+		"crosses": {
+			sfShortName: "SD_Road.shp",
+			bounds:      &geos.Bounds{MinX: 399990, MinY: 450000, MaxX: 400010, MaxY: 450100},
+			expected:    true,
+		},
+		"outside": {
+			sfShortName: "SE_Road.shp",
+			bounds:      &geos.Bounds{MinX: 350000, MinY: 450000, MaxX: 350100, MaxY: 450100},
+			expected:    true,
+		},
+		"geopackage": {
+			sfShortName: "GB_Road.gpkg",
+			bounds:      &geos.Bounds{MinX: 399990, MinY: 450000, MaxX: 400010, MaxY: 450100},
+			expected:    false,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := crossesTile(tt.sfShortName, tt.bounds)
+		if actual != tt.expected {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestDedupResolve(t *testing.T) {
+	d := newDedupIndex()
+	defer d.close()
+
+	ctx := geos.NewContext()
+
+	west, err := ctx.NewGeomFromWKT("LINESTRING (399990 450000, 400000 450000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	east, err := ctx.NewGeomFromWKT("LINESTRING (400000 450000, 400010 450000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held := []heldFeature{
+		// Identical in both tiles
+		{DBName: "road", SfShortName: "SE_Road.shp", Rec: Record{"ID": "a", "CLASSIFICA": "A Road"}, WKB: []byte{1}},
+		{DBName: "road", SfShortName: "SD_Road.shp", Rec: Record{"ID": "a", "CLASSIFICA": "A Road"}, WKB: []byte{1}},
+
+		// Different attributes
+		{DBName: "road", SfShortName: "SD_Road.shp", Rec: Record{"ID": "b", "CLASSIFICA": "A Road"}, WKB: []byte{2}},
+		{DBName: "road", SfShortName: "SE_Road.shp", Rec: Record{"ID": "b", "CLASSIFICA": "B Road"}, WKB: []byte{2}},
+
+		// Split across the tiles
+		{DBName: "road", SfShortName: "SE_Road.shp", Rec: Record{"ID": "d", "CLASSIFICA": "A Road"}, WKB: east.ToWKB()},
+		{DBName: "road", SfShortName: "SD_Road.shp", Rec: Record{"ID": "d", "CLASSIFICA": "A Road"}, WKB: west.ToWKB()},
+
+		// Only in one tile
+		{DBName: "road", SfShortName: "SE_Road.shp", Rec: Record{"ID": "c", "CLASSIFICA": "Minor Road", "DATE": time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}, WKB: []byte{3}},
+	}
+
+	for _, h := range held {
+		err := d.hold(h.DBName, h.SfShortName, h.Rec, h.WKB)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var counts = map[string]int{}
+	var dates int
+	var unioned []byte
+	for bucket := 0; bucket < dedupBuckets; bucket++ {
+		resolved, err := d.resolve(ctx, Config{}, types.NewSchema(), bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for sfShortName, actions := range resolved {
+			counts[sfShortName] += len(actions)
+
+			for _, action := range actions {
+				if _, ok := action.insert["DATE"].(time.Time); ok {
+					dates++
+				}
+				if action.insert["ID"] == "d" {
+					unioned = action.wkb
+				}
+			}
+		}
+	}
+
+	if counts["SD_Road.shp"] != 3 || counts["SE_Road.shp"] != 1 {
+		t.Errorf("expected 3 features from SD and 1 from SE, got %v", counts)
+	}
+
+	if dates != 1 {
+		t.Errorf("expected the date to be read back, got %v", dates)
+	}
+
+	g, err := ctx.NewGeomFromWKB(unioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !g.Equals(west.Union(east)) || g.Length() != 20 {
+		t.Errorf("expected the pieces to be unioned, got %v", g.ToWKT())
+	}
+
+	l := d.layers["road"]
+	if l.Held != 7 || l.Duplicates != 1 || l.Conflicts != 1 || l.Merged != 1 {
+		t.Errorf("unexpected report %v", l)
+	}
+
+	dir := d.dir
+	d.close()
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %v to be removed", dir)
+	}
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+)
+
+// layerGeometry is a geometry destined for a layer, either the full detail
+// layer of the source or one of its generalised companions
+type layerGeometry struct {
+	dbName string
+	wkb    []byte
+}
+
+// ParseTolerances reads the comma separated tolerances in metres of the
+// generalised layers e.g. 10,50
+func ParseTolerances(s string) ([]int, error) {
+	var tolerances []int
+
+	if s == "" {
+		return tolerances, nil
+	}
+
+	for _, t := range strings.Split(s, ",") {
+		tolerance, err := strconv.Atoi(strings.TrimSpace(t))
+		if err != nil {
+			return []int{}, err
+		}
+		if tolerance <= 0 {
+			return []int{}, fmt.Errorf("tolerance must be positive [%v]", tolerance)
+		}
+		tolerances = append(tolerances, tolerance)
+	}
+
+	return tolerances, nil
+}
+
+func getLayerGeometries(config Config, g *geos.Geom, b []byte, dbName string) []layerGeometry {
+	var layers = []layerGeometry{
+		{
+			dbName: dbName,
+			wkb:    b,
+		},
+	}
+
+	if len(config.Generalise) == 0 || !isGeneralisable(dbName) {
+		return layers
+	}
+
+	for _, tolerance := range config.Generalise {
+		simplified := g.TopologyPreserveSimplify(float64(tolerance))
+		if simplified == nil || simplified.IsEmpty() {
+			continue
+		}
+
+		layers = append(layers, layerGeometry{
+			dbName: types.GeneralisedLayerName(dbName, tolerance),
+			wkb:    simplified.ToWKB(),
+		})
+	}
+
+	return layers
+}
+
+func isGeneralisable(dbName string) bool {
+	for _, layerType := range types.GeneralisableLayers {
+		if layerType == dbName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+)
+
+func TestParseTolerances(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected []int
+		err      bool
+	}{
+		"none": {
+			input: "",
+		},
+		"one": {
+			input:    "25",
+			expected: []int{25},
+		},
+		"spaced": {
+			input:    "10, 50",
+			expected: []int{10, 50},
+		},
+		"not a number": {
+			input: "10,fifty",
+			err:   true,
+		},
+		"zero": {
+			input: "0",
+			err:   true,
+		},
+		"negative": {
+			input: "-5",
+			err:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := ParseTolerances(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("%v: expected error %v, got %v", name, tt.err, err)
+			continue
+		}
+
+		if !tt.err && !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestAddGeneralisedLayers(t *testing.T) {
+	schema := types.NewSchema()
+
+	var woodland = append(types.LayerType{}, schema.MapLayers["woodland"]...)
+	var ranked = append(append(types.LayerType{}, woodland...), "AREA_RANK")
+	var road = append(types.LayerType{}, schema.MapLayers["road"]...)
+
+	schema.AddGeneralisedLayers([]int{10, 50})
+
+	// Derived fields are added to the companions of a layer
+	err := schema.AddDerivedField("woodland", "AREA_RANK", "int DEFAULT NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		base     string
+		expected types.LayerType
+	}{
+		"woodland_g10": {
+			base:     "woodland",
+			expected: ranked,
+		},
+		"woodland_g50": {
+			base:     "woodland",
+			expected: ranked,
+		},
+		"road_g10": {
+			base:     "road",
+			expected: road,
+		},
+	}
+
+	for companion, tt := range tests {
+		if base := schema.GeneralisedLayers[companion]; base != tt.base {
+			t.Errorf("%v: expected base %v, got %v", companion, tt.base, base)
+		}
+
+		if !reflect.DeepEqual(tt.expected, schema.MapLayers[companion]) {
+			t.Errorf("%v: expected %v, got %v", companion, tt.expected, schema.MapLayers[companion])
+		}
+	}
+
+	if _, exists := schema.MapLayers["building_g10"]; exists {
+		t.Errorf("building is not generalisable")
+	}
+}
+
+func TestGetLayerGeometries(t *testing.T) {
+	ctx := geos.NewContext()
+
+	// The middle point is 10m off the line between the ends
+	g, err := ctx.NewGeomFromWKT("LINESTRING (0 0, 50 10, 100 0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		dbName     string
+		tolerances []int
+		expected   map[string]int
+	}{
+		"not generalised": {
+			dbName:   "road",
+			expected: map[string]int{"road": 3},
+		},
+		"within tolerance": {
+			dbName:     "road",
+			tolerances: []int{5},
+			expected:   map[string]int{"road": 3, "road_g5": 3},
+		},
+		"simplified": {
+			dbName:     "road",
+			tolerances: []int{5, 25},
+			expected:   map[string]int{"road": 3, "road_g5": 3, "road_g25": 2},
+		},
+		"not generalisable": {
+			dbName:     "building",
+			tolerances: []int{25},
+			expected:   map[string]int{"building": 3},
+		},
+	}
+
+	for name, tt := range tests {
+		config := Config{
+			Generalise: tt.tolerances,
+		}
+
+		var actual = make(map[string]int)
+		for _, layer := range getLayerGeometries(config, g, g.ToWKB(), tt.dbName) {
+			simplified, err := ctx.NewGeomFromWKB(layer.wkb)
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			actual[layer.dbName] = simplified.NumCoordinates()
+		}
+
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/filelogger"
+)
+
+const (
+	QA_VALID    = "VALID"
+	QA_REPAIRED = "REPAIRED"
+	QA_REJECTED = "REJECTED"
+
+	// How many rejected features to list per layer
+	qaMaxRejections = 100
+)
+
+// Each layer is stored as a single Multi* type
+var multiTypes = map[geos.TypeID]geos.TypeID{
+	geos.TypeIDPoint:           geos.TypeIDMultiPoint,
+	geos.TypeIDMultiPoint:      geos.TypeIDMultiPoint,
+	geos.TypeIDLineString:      geos.TypeIDMultiLineString,
+	geos.TypeIDLinearRing:      geos.TypeIDMultiLineString,
+	geos.TypeIDMultiLineString: geos.TypeIDMultiLineString,
+	geos.TypeIDPolygon:         geos.TypeIDMultiPolygon,
+	geos.TypeIDMultiPolygon:    geos.TypeIDMultiPolygon,
+}
+
+// The location GEOS appends to a reason e.g. Self-intersection[453.2 123.1]
+var qaReasonLocation = regexp.MustCompile(`\[.*\]$`)
+
+// qaReport counts the outcome of geometry QA for each layer
+type qaReport struct {
+	mu     sync.Mutex
+	layers map[string]*layerQA
+}
+
+type layerQA struct {
+	Valid      int
+	Repaired   int
+	Rejected   int
+	Normalised int
+	Reasons    map[string]int
+	Rejections []string
+}
+
+// checkGeometry parses a feature geometry and, when geometry QA is enabled,
+// repairs invalid geometries and normalises them to the Multi* type of the
+// layer, returning the geometry and its WKB or nil if it was rejected
+func (i *Importer) checkGeometry(ctx *geos.Context, r importAction, dbName string) (*geos.Geom, []byte) {
+	var config Config = i.config
+	var id string = fmt.Sprintf("%v", r.insert["ID"])
+
+	if len(r.wkb) == 0 {
+		i.qa.record(dbName, id, QA_REJECTED, "no geometry")
+		return nil, nil
+	}
+
+	g, err := ctx.NewGeomFromWKB(r.wkb)
+	if err != nil {
+		logger.Log(
+			logger.LVL_ERROR,
+			fmt.Sprintf("%v [%v] %v", dbName, id, err.Error()),
+		)
+		i.qa.record(dbName, id, QA_REJECTED, err.Error())
+		return nil, nil
+	}
+
+	if g.IsEmpty() {
+		i.qa.record(dbName, id, QA_REJECTED, "empty geometry")
+		return nil, nil
+	}
+
+	if !config.GeometryQA {
+		return g, r.wkb
+	}
+
+	targetType, ok := multiTypes[g.TypeID()]
+	if !ok {
+		i.qa.record(dbName, id, QA_REJECTED, fmt.Sprintf("unsupported geometry type %v", g.Type()))
+		return nil, nil
+	}
+
+	var status string = QA_VALID
+	var reason string
+
+	if !g.IsValid() {
+		status = QA_REPAIRED
+		reason = qaReasonLocation.ReplaceAllString(g.IsValidReason(), "")
+
+		g = g.MakeValid()
+		if g == nil || g.IsEmpty() || !g.IsValid() {
+			i.qa.record(dbName, id, QA_REJECTED, reason)
+			return nil, nil
+		}
+	}
+
+	// Single geometries and the collections of a repair become the Multi*
+	// type of the layer
+	var normalised bool = g.TypeID() != targetType
+
+	g = toMulti(ctx, g, targetType)
+	if g.IsEmpty() {
+		i.qa.record(dbName, id, QA_REJECTED, fmt.Sprintf("no %v after repair", geometryTypeName(targetType)))
+		return nil, nil
+	}
+
+	// Consistent ring orientation, exterior clockwise and interior anticlockwise
+	if targetType == geos.TypeIDMultiPolygon {
+		g = g.Normalize()
+	}
+
+	i.qa.record(dbName, id, status, reason)
+	if normalised {
+		i.qa.normalised(dbName)
+	}
+
+	return g, g.ToWKB()
+}
+
+// toMulti promotes a geometry to the target Multi* type, keeping only the
+// parts of that type, MakeValid can return a mixed GeometryCollection
+func toMulti(ctx *geos.Context, g *geos.Geom, targetType geos.TypeID) *geos.Geom {
+	if g.TypeID() == targetType {
+		return g
+	}
+
+	var parts []*geos.Geom
+	collectParts(g, multiTypes[targetType], &parts)
+
+	return ctx.NewCollection(targetType, parts)
+}
+
+func collectParts(g *geos.Geom, targetType geos.TypeID, parts *[]*geos.Geom) {
+	switch g.TypeID() {
+	case geos.TypeIDMultiPoint, geos.TypeIDMultiLineString, geos.TypeIDMultiPolygon, geos.TypeIDGeometryCollection:
+		for i := 0; i < g.NumGeometries(); i++ {
+			collectParts(g.Geometry(i), targetType, parts)
+		}
+	default:
+		if multiTypes[g.TypeID()] == targetType && !g.IsEmpty() {
+			// Parts are owned by their parent so must be cloned
+			*parts = append(*parts, g.Clone())
+		}
+	}
+}
+
+func geometryTypeName(typeID geos.TypeID) string {
+	switch typeID {
+	case geos.TypeIDMultiPoint:
+		return "MultiPoint"
+	case geos.TypeIDMultiLineString:
+		return "MultiLineString"
+	case geos.TypeIDMultiPolygon:
+		return "MultiPolygon"
+	}
+
+	return fmt.Sprintf("%v", typeID)
+}
+
+func newQAReport() *qaReport {
+	return &qaReport{
+		layers: make(map[string]*layerQA),
+	}
+}
+
+// getLayer returns the counts of a layer, the lock must be held
+func (r *qaReport) getLayer(dbName string) *layerQA {
+	qa, exists := r.layers[dbName]
+	if !exists {
+		qa = &layerQA{
+			Reasons: make(map[string]int),
+		}
+		r.layers[dbName] = qa
+	}
+
+	return qa
+}
+
+func (r *qaReport) record(dbName, id, status, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	qa := r.getLayer(dbName)
+
+	switch status {
+	case QA_VALID:
+		qa.Valid++
+	case QA_REPAIRED:
+		qa.Repaired++
+		qa.Reasons[reason]++
+	case QA_REJECTED:
+		qa.Rejected++
+		qa.Reasons[reason]++
+		if len(qa.Rejections) < qaMaxRejections {
+			qa.Rejections = append(qa.Rejections, fmt.Sprintf("%v [%v]", id, reason))
+		}
+	}
+}
+
+// normalised counts a geometry that was converted to the Multi* type of its
+// layer
+func (r *qaReport) normalised(dbName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.getLayer(dbName).Normalised++
+}
+
+func (l layerQA) String() string {
+	var s string = fmt.Sprintf("valid %v, repaired %v, rejected %v, normalised %v", l.Valid, l.Repaired, l.Rejected, l.Normalised)
+
+	reasons := make([]string, 0, len(l.Reasons))
+	for reason := range l.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		s += fmt.Sprintf("\n\t%v: %v", reason, l.Reasons[reason])
+	}
+
+	if len(l.Rejections) > 0 {
+		s += fmt.Sprintf("\n\trejected:\n\t\t%v", strings.Join(l.Rejections, "\n\t\t"))
+	}
+
+	return s
+}
+
+func (r *qaReport) log(logFile io.Writer) {
+	var rejected int
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	layers := make([]string, 0, len(r.layers))
+	for layer := range r.layers {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+
+	for _, layer := range layers {
+		rejected += r.layers[layer].Rejected
+
+		filelogger.Log(
+			filelogger.LogLine{
+				File: logFile,
+				Line: fmt.Sprintf("[%v] %v\n", layer, r.layers[layer]),
+			},
+		)
+	}
+
+	logger.Log(
+		logger.LVL_APP,
+		fmt.Sprintf("%v features rejected by geometry QA\n", rejected),
+	)
+}
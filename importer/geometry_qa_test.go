@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+func TestCheckGeometry(t *testing.T) {
+	ctx := geos.NewContext()
+	i := &Importer{
+		config: Config{
+			GeometryQA: true,
+		},
+		qa: newQAReport(),
+	}
+
+	tests := map[string]struct {
+		wkt      string
+		expected geos.TypeID
+		rejected bool
+		report   layerQA
+	}{
+		"point": {
+			wkt:      "POINT (1 2)",
+			expected: geos.TypeIDMultiPoint,
+			report:   layerQA{Valid: 1, Normalised: 1, Reasons: map[string]int{}},
+		},
+		"line": {
+			wkt:      "LINESTRING (0 0, 1 1)",
+			expected: geos.TypeIDMultiLineString,
+			report:   layerQA{Valid: 1, Normalised: 1, Reasons: map[string]int{}},
+		},
+		"multipolygon": {
+			wkt:      "MULTIPOLYGON (((0 0, 0 10, 10 10, 10 0, 0 0)))",
+			expected: geos.TypeIDMultiPolygon,
+			report:   layerQA{Valid: 1, Reasons: map[string]int{}},
+		},
+		"bowtie": {
+			wkt:      "POLYGON ((0 0, 10 10, 10 0, 0 10, 0 0))",
+			expected: geos.TypeIDMultiPolygon,
+			report:   layerQA{Repaired: 1, Reasons: map[string]int{"Self-intersection": 1}},
+		},
+		"empty": {
+			wkt:      "POLYGON EMPTY",
+			rejected: true,
+			report: layerQA{
+				Rejected:   1,
+				Reasons:    map[string]int{"empty geometry": 1},
+				Rejections: []string{"empty [empty geometry]"},
+			},
+		},
+		"missing": {
+			rejected: true,
+			report: layerQA{
+				Rejected:   1,
+				Reasons:    map[string]int{"no geometry": 1},
+				Rejections: []string{"missing [no geometry]"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		r := importAction{
+			insert: insert{"ID": name},
+		}
+
+		if tt.wkt != "" {
+			g, err := ctx.NewGeomFromWKT(tt.wkt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.wkb = g.ToWKB()
+		}
+
+		// Each case is its own layer in the report
+		actual, b := i.checkGeometry(ctx, r, name)
+
+		if report := i.qa.layers[name]; report == nil || !reflect.DeepEqual(tt.report, *report) {
+			t.Errorf("%v: expected the report %+v, got %+v", name, tt.report, report)
+		}
+
+		if tt.rejected {
+			if actual != nil || b != nil {
+				t.Errorf("%v: expected rejection, got %v", name, actual)
+			}
+			continue
+		}
+
+		if actual == nil {
+			t.Fatalf("%v: unexpected rejection", name)
+		}
+
+		if actual.TypeID() != tt.expected || !actual.IsValid() {
+			t.Errorf("%v: expected valid %v, got %v", name, geometryTypeName(tt.expected), actual.Type())
+		}
+	}
+}
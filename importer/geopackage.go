@@ -0,0 +1,273 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rockwell-uk/go-logger/logger"
+
+	"go-uk-maps-import/database"
+	"go-uk-maps-import/database/types"
+)
+
+// ref: http://www.geopackage.org/spec/#gpb_format
+
+const (
+	gpkgMagic           = "GP"
+	gpkgHeaderSize      = 8
+	gpkgFlagEmpty  byte = 0x10
+)
+
+var gpkgEnvelopeSizes = map[byte]int{
+	0: 0,
+	1: 32,
+	2: 48,
+	3: 48,
+	4: 64,
+}
+
+type geoPackageReader struct {
+	db         *sqlx.DB
+	rows       *sqlx.Rows
+	fields     []string
+	geomColumn string
+}
+
+func (g *geoPackageReader) Next() (insert, wkbFunc, error) {
+	var funcName string = "importer.geoPackageReader.Next"
+
+	if !g.rows.Next() {
+		err := g.rows.Err()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		return nil, nil, io.EOF
+	}
+
+	result := make(map[string]interface{})
+	err := g.rows.MapScan(result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var record insert = insert{}
+	for _, field := range g.fields {
+		value, exists := result[field]
+		if !exists || value == nil {
+			record[field] = nil
+			continue
+		}
+
+		switch v := value.(type) {
+		case []byte:
+			record[field] = fixAttr(field, string(v))
+		default:
+			record[field] = fixAttr(field, fmt.Sprintf("%v", v))
+		}
+	}
+
+	blob, _ := result[g.geomColumn].([]byte)
+	toWKB := func() ([]byte, error) {
+		if blob == nil {
+			return nil, nil
+		}
+
+		b, err := gpkgToWKB(blob)
+		if err != nil {
+			return nil, fmt.Errorf("%v: [%v] %v", funcName, record["ID"], err.Error())
+		}
+
+		return b, nil
+	}
+
+	return record, toWKB, nil
+}
+
+func (g *geoPackageReader) Close() error {
+	g.rows.Close()
+	return g.db.Close()
+}
+
+func openGeoPackage(gpkgFile string) (*sqlx.DB, error) {
+	return sqlx.Connect("sqlite3", fmt.Sprintf("file:%v?mode=ro", gpkgFile))
+}
+
+func openGeoPackageLayer(gpkgFile, layer string) (uint32, *geoPackageReader, error) {
+	var funcName string = "importer.openGeoPackageLayer"
+
+	db, err := openGeoPackage(gpkgFile)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var geomColumn string
+	err = db.QueryRow("SELECT column_name FROM gpkg_geometry_columns WHERE table_name = ?", layer).Scan(&geomColumn)
+	if err != nil {
+		db.Close()
+		return 0, nil, fmt.Errorf("%v: no geometry column for layer %v [%v]", funcName, layer, err.Error())
+	}
+
+	// Map the GeoPackage columns onto the fields expected for the layer
+	var dbName string = database.GetDBNameFromFilename(getSourceShortName(geoPackageLayerSource(gpkgFile, layer)))
+	columns, err := getGeoPackageColumns(db, layer)
+	if err != nil {
+		db.Close()
+		return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var fields []string
+	var selects []string
+	layerFields, _ := types.OSLayer(dbName)
+	for _, field := range layerFields {
+		if field == "GRIDREF" {
+			continue
+		}
+
+		fields = append(fields, field)
+
+		if column, exists := columns[strings.ToUpper(field)]; exists {
+			selects = append(selects, fmt.Sprintf(`"%v" AS "%v"`, column, field))
+		}
+	}
+	selects = append(selects, fmt.Sprintf(`"%v"`, geomColumn))
+
+	var count int
+	err = db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%v"`, layer)).Scan(&count)
+	if err != nil {
+		db.Close()
+		return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	rows, err := db.Queryx(fmt.Sprintf(`SELECT %v FROM "%v"`, strings.Join(selects, ", "), layer))
+	if err != nil {
+		db.Close()
+		return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return uint32(count), &geoPackageReader{
+		db:         db,
+		rows:       rows,
+		fields:     fields,
+		geomColumn: geomColumn,
+	}, nil
+}
+
+// getGeoPackageColumns returns the column names of a layer table keyed by their upper case name
+func getGeoPackageColumns(db *sqlx.DB, layer string) (map[string]string, error) {
+	var columns = make(map[string]string)
+
+	rows, err := db.Queryx(fmt.Sprintf(`PRAGMA table_info("%v")`, layer))
+	if err != nil {
+		return columns, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := make(map[string]interface{})
+		err = rows.MapScan(result)
+		if err != nil {
+			return columns, err
+		}
+
+		if name, ok := result["name"].(string); ok {
+			columns[strings.ToUpper(name)] = name
+		}
+	}
+
+	return columns, rows.Err()
+}
+
+func getGeoPackageLayers(gpkgFile string) ([]string, error) {
+	var funcName string = "importer.getGeoPackageLayers"
+
+	db, err := openGeoPackage(gpkgFile)
+	if err != nil {
+		return []string{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer db.Close()
+
+	var layers []string
+	err = db.Select(&layers, "SELECT table_name FROM gpkg_contents WHERE data_type = 'features' ORDER BY table_name")
+	if err != nil {
+		return []string{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return layers, nil
+}
+
+func getGeoPackageLayerCount(gpkgFile, layer string) (int, error) {
+	var funcName string = "importer.getGeoPackageLayerCount"
+
+	db, err := openGeoPackage(gpkgFile)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer db.Close()
+
+	var count int
+	err = db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%v"`, layer)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return count, nil
+}
+
+// getGeoPackageSources lists the feature layers of a GeoPackage that map onto a known layer type
+func getGeoPackageSources(gpkgFile string) ([]string, error) {
+	var funcName string = "importer.getGeoPackageSources"
+
+	var sources []string
+
+	layers, err := getGeoPackageLayers(gpkgFile)
+	if err != nil {
+		return []string{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	for _, layer := range layers {
+		source := geoPackageLayerSource(gpkgFile, layer)
+		dbName := database.GetDBNameFromFilename(getSourceShortName(source))
+
+		if _, exists := types.OSLayer(dbName); !exists {
+			logger.Log(
+				logger.LVL_DEBUG,
+				fmt.Sprintf("Skipping unknown GeoPackage layer %v [%v]\n", layer, dbName),
+			)
+			continue
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// gpkgToWKB strips the GeoPackage binary header leaving standard WKB
+func gpkgToWKB(b []byte) ([]byte, error) {
+	var funcName string = "importer.gpkgToWKB"
+
+	if len(b) < gpkgHeaderSize || string(b[0:2]) != gpkgMagic {
+		return nil, fmt.Errorf("%v: not a GeoPackage geometry blob", funcName)
+	}
+
+	var flags byte = b[3]
+
+	if flags&gpkgFlagEmpty != 0 {
+		return nil, fmt.Errorf("%v: empty geometry", funcName)
+	}
+
+	envelopeSize, ok := gpkgEnvelopeSizes[(flags>>1)&0x07]
+	if !ok {
+		return nil, fmt.Errorf("%v: invalid envelope indicator %v", funcName, (flags>>1)&0x07)
+	}
+
+	var offset int = gpkgHeaderSize + envelopeSize
+	if len(b) <= offset {
+		return nil, fmt.Errorf("%v: truncated geometry blob", funcName)
+	}
+
+	return b[offset:], nil
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"go-uk-maps-import/database"
+)
+
+func TestGpkgToWKB(t *testing.T) {
+	// POINT(1 2) as little endian WKB
+	wkb := []byte{
+		0x01, 0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40,
+	}
+
+	envelopeXY := make([]byte, 32)
+
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+		wantErr  bool
+	}{
+		"no envelope": {
+			input:    append([]byte{'G', 'P', 0x00, 0x01, 0x00, 0x00, 0x6c, 0x34}, wkb...),
+			expected: wkb,
+		},
+		"xy envelope": {
+			input:    append(append([]byte{'G', 'P', 0x00, 0x03, 0x00, 0x00, 0x6c, 0x34}, envelopeXY...), wkb...),
+			expected: wkb,
+		},
+		"empty": {
+			input:   append([]byte{'G', 'P', 0x00, 0x11, 0x00, 0x00, 0x6c, 0x34}, wkb...),
+			wantErr: true,
+		},
+		"invalid envelope": {
+			input:   append([]byte{'G', 'P', 0x00, 0x0b, 0x00, 0x00, 0x6c, 0x34}, wkb...),
+			wantErr: true,
+		},
+		"not gpkg": {
+			input:   wkb,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := gpkgToWKB(tt.input)
+
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%v: expected error [%v], got [%v]", name, tt.wantErr, err)
+		}
+
+		if !tt.wantErr && !reflect.DeepEqual(tt.expected, actual) {
+			t.Fatalf("%v: expected [%v], got [%v]", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestGetSourceShortName(t *testing.T) {
+	tests := map[string]struct {
+		shortName string
+		dbName    string
+	}{
+		"./testdata/SD_MotorwayJunction.shp": {
+			shortName: "SD_MotorwayJunction.shp",
+			dbName:    "motorway_junction",
+		},
+		"./resources/GB/vmdvec_gb.gpkg#Building": {
+			shortName: "GB_Building.gpkg",
+			dbName:    "building",
+		},
+		"./resources/GB/vmdvec_gb.gpkg#SurfaceWater_Area": {
+			shortName: "GB_SurfaceWater_Area.gpkg",
+			dbName:    "surface_water_area",
+		},
+	}
+
+	for source, tt := range tests {
+		shortName := getSourceShortName(source)
+
+		if tt.shortName != shortName {
+			t.Errorf("Expected [%v]\nGot [%v]", tt.shortName, shortName)
+		}
+
+		dbName := database.GetDBNameFromFilename(shortName)
+
+		if tt.dbName != dbName {
+			t.Errorf("Expected [%v]\nGot [%v]", tt.dbName, dbName)
+		}
+	}
+}
+
+func TestGetRecordCount(t *testing.T) {
+	gpkgFile := filepath.Join(t.TempDir(), "vmdvec_gb.gpkg")
+
+	db, err := sqlx.Connect("sqlite3", gpkgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE "Building" (fid integer); INSERT INTO "Building" VALUES (1), (2)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		source   string
+		expected int
+		valid    bool
+	}{
+		"layer": {
+			source:   geoPackageLayerSource(gpkgFile, "Building"),
+			expected: 2,
+			valid:    true,
+		},
+		"missing layer": {
+			source: geoPackageLayerSource(gpkgFile, "Road"),
+		},
+		"missing file": {
+			source: geoPackageLayerSource(filepath.Join(t.TempDir(), "missing.gpkg"), "Building"),
+		},
+	}
+
+	for name, tt := range tests {
+		actual, err := getRecordCount(tt.source)
+		if tt.valid != (err == nil) {
+			t.Errorf("%v: expected valid %v, got %v", name, tt.valid, err)
+			continue
+		}
+
+		if actual != tt.expected {
+			t.Errorf("%v: expected %v records, got %v", name, tt.expected, actual)
+		}
+	}
+}
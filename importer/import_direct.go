@@ -4,105 +4,71 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/rockwell-uk/datastore"
 	"github.com/rockwell-uk/go-logger/logger"
-	"github.com/rockwell-uk/go-nationalgrid"
-	"github.com/rockwell-uk/go-shpconvert/shpconvert"
-	"github.com/twpayne/go-geos"
 
 	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/database/engine/pgsql"
 )
 
-func importDirect(ctx *geos.Context, r importAction, dbName, sfShortName string) importResult {
-	var rowsGenerated = make(map[string]int)
-
-	b, err := shpconvert.ShpToWKB(r.shape)
-	if err != nil {
-		logger.Log(
-			logger.LVL_FATAL,
-			err.Error(),
-		)
-	}
-
-	shapeGeom, err := ctx.NewGeomFromWKB(b)
-	if err != nil {
-		logger.Log(
-			logger.LVL_FATAL,
-			err.Error(),
-		)
-	}
+// batchWriter is the write stage of the pipeline for a storage engine
+type batchWriter interface {
+	write(batch rowBatch) error
+}
 
-	for square, subSquares := range nationalgrid.GetSubSquares(shapeGeom.Bounds()) {
-		batchInsertsKey := fmt.Sprintf("%v.%v", dbName, square)
-		batchInserts := loadBatchInserts(sfShortName)
+func (i *Importer) newBatchWriter() batchWriter {
+	var config Config = i.config
+	var w batchWriter
 
-		if _, exists := rowsGenerated[square]; !exists {
-			rowsGenerated[square] = 0
+	// SQLite databases are built in memory and exported to files after
+	if config.UseFiles && *config.DB.Engine != engine.EngineSQLite {
+		w = sqlFileWriter{
+			w:       i.sqlWriter,
+			dialect: i.dialect,
+			schema:  i.schema,
 		}
-
-		for _, gridRef := range subSquares {
-			fvClone := insert{}
-			for key, value := range r.insert {
-				fvClone[key] = value
-			}
-
-			fvClone["GRIDREF"] = gridRef
-			fvClone["ogc_geom"] = b
-
-			batchInserts[batchInsertsKey] = append(batchInserts[batchInsertsKey], fvClone)
-
-			rowsGenerated[square]++
+	} else {
+		w = dbWriter{
+			se:          config.DB.StorageEngine,
+			dbFieldsMap: i.dbFieldsMap,
 		}
-
-		saveBatchInserts(sfShortName, batchInserts)
-	}
-
-	return importResult{
-		rowsGenerated: rowsGenerated,
-	}
-}
-
-func getDBName(batchInsertsKey string) string {
-	s := strings.Split(batchInsertsKey, ".")
-
-	return s[0]
-}
-
-func loadBatchInserts(batchInsertsKey string) map[string]batchInsert {
-	var empty = make(map[string]batchInsert)
-
-	batchInserts, err := datastore.Get(batchInsertsKey)
-	if err != nil {
-		saveBatchInserts(batchInsertsKey, empty)
-		return empty
 	}
 
-	if inserts, ok := batchInserts.(map[string]batchInsert); ok {
-		return inserts
+	if i.geoParquet != nil {
+		return teeWriter{w, geoParquetWriter{i.geoParquet}}
 	}
 
-	return empty
+	return w
 }
 
-//nolint:errcheck
-func saveBatchInserts(batchInsertsKey string, batchInserts map[string]batchInsert) {
-	datastore.Put(batchInsertsKey, batchInserts)
+// dbWriter upserts the rows straight into the storage engine
+type dbWriter struct {
+	se          engine.StorageEngine
+	dbFieldsMap map[string]fieldName
 }
 
-func runInserts(se engine.StorageEngine, batchInsertsKey string) {
-	var funcName string = "importer.runInserts"
+func (w dbWriter) write(batch rowBatch) error {
+	var funcName string = "importer.dbWriter.write"
 
-	batchInserts := loadBatchInserts(batchInsertsKey)
-
-	for key, inserts := range batchInserts {
+	for key, rows := range batch {
 		var dbName = getDBName(key)
-		tableName := se.GetTableName(key)
+		tableName := w.se.GetTableName(key)
 
-		fieldsMap, exists := dbFieldsMap[dbName]
+		fieldsMap, exists := w.dbFieldsMap[dbName]
 		if !exists {
-			err := fmt.Errorf("dbFieldsMap does not exits %v", dbName)
-			panic(err)
+			return fmt.Errorf("%v: dbFieldsMap does not exits %v", funcName, dbName)
+		}
+
+		var inserts = make(batchInsert, len(rows))
+		for n, r := range rows {
+			fvClone := insert{}
+			for key, value := range r.rec {
+				fvClone[key] = value
+			}
+
+			fvClone["GRIDREF"] = r.gridRef
+			fvClone["ogc_geom"] = r.wkb
+
+			inserts[n] = fvClone
 		}
 
 		fieldNames := fieldsMap.fieldNames
@@ -111,7 +77,7 @@ func runInserts(se engine.StorageEngine, batchInsertsKey string) {
 		var leadLine string
 		var query string
 
-		switch se.(type) {
+		switch w.se.(type) {
 		case *pgsql.PgSQL:
 			leadLine = fmt.Sprintf(`INSERT INTO %s (%v) VALUES `, tableName, fieldNames)
 			query = fmt.Sprintf("%+v (%+v)", leadLine, placeHolders)
@@ -126,30 +92,23 @@ func runInserts(se engine.StorageEngine, batchInsertsKey string) {
 			fmt.Sprintf("[%v.%v] upserting %v records\n", dbName, tableName, len(inserts)),
 		)
 
-		_, err := se.GetDB(dbName).NamedExec(query, inserts)
+		_, err := w.se.GetDB(dbName).NamedExec(query, inserts)
 		if err != nil {
-			var msg string = fmt.Sprintf("[%v.%v]:\n%v\n\n %+v\n\n (%+v)\n\n %+v\n\n", dbName, tableName, err.Error(), query, inserts, dbFieldsMap)
+			var msg string = fmt.Sprintf("[%v.%v]:\n%v\n\n %+v\n\n (%+v)\n\n %+v\n\n", dbName, tableName, err.Error(), query, inserts, w.dbFieldsMap)
 			logger.Log(
 				logger.LVL_FATAL,
 				err.Error(),
 			)
 
-			showTablesSQL := "SHOW TABLES"
-			tables := []string{}
-			err = se.GetDB(dbName).Select(&tables, showTablesSQL)
-			if err != nil {
-				logger.Log(
-					logger.LVL_FATAL,
-					err.Error(),
-				)
-			}
-
-			db := se.GetDB(dbName)
-			panic(fmt.Errorf("%v: %v, tables %v [%+v]", funcName, msg, tables, db))
+			return fmt.Errorf("%v: %v", funcName, msg)
 		}
-
-		delete(batchInserts, key)
 	}
 
-	saveBatchInserts(batchInsertsKey, batchInserts)
+	return nil
+}
+
+func getDBName(batchInsertsKey string) string {
+	s := strings.Split(batchInsertsKey, ".")
+
+	return s[0]
 }
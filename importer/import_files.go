@@ -1,108 +1,66 @@
 package importer
 
 import (
-	"encoding/hex"
 	"fmt"
 	"sort"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/rockwell-uk/csync/mutex"
-	"github.com/rockwell-uk/go-logger/logger"
-	"github.com/rockwell-uk/go-nationalgrid"
-	"github.com/rockwell-uk/go-shpconvert/shpconvert"
-	"github.com/twpayne/go-geos"
 
+	"go-uk-maps-import/database/types"
 	"go-uk-maps-import/sqlwriter"
 )
 
-var sqlFilesWritten = make(map[string]bool)
-
-func importToFile(ctx *geos.Context, r importAction, dbName string) importResult {
-	var rowsGenerated = make(map[string]int)
-
-	b, err := shpconvert.ShpToWKB(r.shape)
-	if err != nil {
-		logger.Log(
-			logger.LVL_FATAL,
-			err.Error(),
-		)
-	}
-
-	shapeGeom, err := ctx.NewGeomFromWKB(b)
-	if err != nil {
-		logger.Log(
-			logger.LVL_FATAL,
-			err.Error(),
-		)
-	}
-
-	fieldNames, fieldValues := getFieldNamesAndValues(r.insert)
-
-	for square, subSquares := range nationalgrid.GetSubSquares(shapeGeom.Bounds()) {
-		fullTableName := fmt.Sprintf("%s.%s", dbName, square)
-
-		mutex.Lock()
-
-		for _, s := range subSquares {
-			sqlFileName := fmt.Sprintf("%s%s", square, fmt.Sprintf("%02d", s))
-			sqlFilePath := fmt.Sprintf("%s.%s", dbName, sqlFileName)
-
-			if _, exists := sqlFilesWritten[sqlFilePath]; !exists {
-				sqlwriter.Write(
-					sqlwriter.SQLLine{
-						DBName: dbName,
-						Table:  sqlFileName,
-						Line:   fmt.Sprintf(`REPLACE INTO %s (GRIDREF, %vogc_geom) VALUES `, fullTableName, fieldNames),
-					},
-				)
+// sqlFileWriter writes the rows to .sql files in the SQL of a dialect to be
+// loaded into the database later
+type sqlFileWriter struct {
+	w       *sqlwriter.Writer
+	dialect sqlwriter.Dialect
+	schema  *types.Schema
+}
 
-				if _, exists := sqlFilesWritten[fullTableName]; !exists {
-					rowsGenerated[square] = 0
-					sqlFilesWritten[fullTableName] = true
-				}
+func (w sqlFileWriter) write(batch rowBatch) error {
+	for key, rows := range batch {
+		dbName, square, _ := strings.Cut(key, ".")
 
-				sqlFilesWritten[sqlFilePath] = true
-			}
+		for _, r := range rows {
+			fieldNames, fieldValues := getFieldNamesAndValues(r.rec)
+			sqlFileName := fmt.Sprintf("%s%02d", square, r.gridRef)
 
-			sqlwriter.Write(
+			w.w.WriteHeader(
 				sqlwriter.SQLLine{
 					DBName: dbName,
 					Table:  sqlFileName,
-					Line:   fmt.Sprintf(`(%v, %vST_GeomFromWKB(X'%v')),`, s, fieldValues, hex.EncodeToString(b)),
+					Line:   w.dialect.Insert(w.dialect.Table(dbName, square), append([]string{"GRIDREF"}, fieldNames...)),
 				},
 			)
 
-			rowsGenerated[square]++
+			w.w.Write(
+				sqlwriter.SQLLine{
+					DBName: dbName,
+					Table:  sqlFileName,
+					Line:   w.dialect.Row(append([]interface{}{r.gridRef}, fieldValues...), r.wkb, w.schema.LayerSRID(dbName)),
+				},
+			)
 		}
-
-		mutex.Unlock()
 	}
 
-	return importResult{
-		rowsGenerated: rowsGenerated,
-	}
+	return nil
 }
 
-func getFieldNamesAndValues(i insert) (string, string) {
-	var fieldNames, fieldValues string
-
-	// need consistent order
-	mapped := make(map[string]interface{})
-	for name, value := range i {
-		mapped[name] = value
-	}
-
-	keys := make([]string, 0, len(mapped))
-	for k := range mapped {
+// getFieldNamesAndValues returns the fields of a record in a consistent order
+func getFieldNamesAndValues(i insert) ([]string, []interface{}) {
+	keys := make([]string, 0, len(i))
+	for k := range i {
 		keys = append(keys, k)
 	}
 
 	sort.Strings(keys)
 
-	for _, key := range keys {
-		fieldNames += fmt.Sprintf("%v, ", key)
-		fieldValues += fmt.Sprintf("%#v, ", mapped[key])
+	values := make([]interface{}, len(keys))
+	for n, key := range keys {
+		values[n] = i[key]
 	}
 
-	return fieldNames, fieldValues
+	return keys, values
 }
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"strings"
+
+	"go-uk-maps-import/geoparquet"
+)
+
+// geoParquetWriter writes the rows to GeoParquet files as they are imported,
+// a row per grid cell of a feature as in the tables
+type geoParquetWriter struct {
+	e *geoparquet.Exporter
+}
+
+func (w geoParquetWriter) write(batch rowBatch) error {
+	for key, rows := range batch {
+		dbName, square, _ := strings.Cut(key, ".")
+
+		for _, r := range rows {
+			rec := make(map[string]interface{}, len(r.rec)+1)
+			for field, value := range r.rec {
+				rec[field] = value
+			}
+			rec["GRIDREF"] = r.gridRef
+
+			err := w.e.Write(dbName, square, rec, r.wkb)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// teeWriter writes each batch with every writer in turn
+type teeWriter []batchWriter
+
+func (t teeWriter) write(batch rowBatch) error {
+	for _, w := range t {
+		err := w.write(batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,12 +1,15 @@
 package importer
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/rockwell-uk/csync/mutex"
 	"github.com/rockwell-uk/csync/waitgroup"
 	"github.com/rockwell-uk/go-logger/logger"
 	"github.com/rockwell-uk/go-progress/progress"
@@ -14,21 +17,171 @@ import (
 	"github.com/rockwell-uk/go-utils/sliceutils"
 	"github.com/rockwell-uk/go-utils/stringutils"
 	"github.com/rockwell-uk/go-utils/timeutils"
-	"github.com/rockwell-uk/shapefile"
 	"github.com/rockwell-uk/uiprogress"
 
+	"go-uk-maps-import/database/engine"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/geoparquet"
 	"go-uk-maps-import/rates"
+	"go-uk-maps-import/sqlwriter"
 )
 
-var (
+// Importer holds the state of one import, several can run in one process as
+// long as they do not share a storage engine or SQL folder
+type Importer struct {
+	config      Config
+	schema      *types.Schema
+	ownsEngine  bool
+	dbFieldsMap map[string]fieldName
+	sqlWriter   *sqlwriter.Writer
+	dialect     sqlwriter.Dialect
+	geoParquet  *geoparquet.Exporter
+	qa          *qaReport
+	attrs       *attributeReport
+	dedup       *dedupIndex
+	stages      map[string]*stageCounters
+
+	// importSource imports a single source, it is importShapefile other
+	// than when a test records the order the sources are imported in
+	importSource func(ctx context.Context, source string) error
+
+	mu       sync.Mutex
 	imported []string
 	rateInfo rates.RatesInfo
-)
+	history  rates.History
+	ran      rates.History
+}
+
+// New builds the schema of the config and starts the storage engine with it
+// if one has not been provided, a provided engine must have been started
+// with the schema of NewSchema, the importer must be closed after use
+func New(config Config) (*Importer, error) {
+	var funcName string = "importer.New"
+
+	err := CheckSQLDialect(config)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	schema, err := NewSchema(config)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	// The tables of a provided engine must be those of the config
+	if config.DB.StorageEngine != nil {
+		if !reflect.DeepEqual(schema, config.DB.StorageEngine.GetSchema()) {
+			return nil, fmt.Errorf("%v: the storage engine was not started with the schema of the config", funcName)
+		}
+		schema = config.DB.StorageEngine.GetSchema()
+	}
+	config.DB.Schema = schema
+
+	// Pipeline defaults
+	if config.DecodeWorkers <= 0 {
+		config.DecodeWorkers = runtime.NumCPU()
+	}
+	if config.SplitWorkers <= 0 {
+		config.SplitWorkers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.SegmentWorkers <= 0 {
+		config.SegmentWorkers = defaultSegmentWorkers
+	}
+	if config.SegmentRecords <= 0 {
+		config.SegmentRecords = defaultSegmentRecords
+	}
+
+	dialect, err := sqlwriter.GetDialect(GetSQLDialect(config))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	sqlWriter := sqlwriter.New(config.SQLFolder)
+	sqlWriter.Shards = config.SQLWriters
+	sqlWriter.MaxOpenFiles = config.SQLOpenFiles
+	sqlWriter.Dialect = dialect
+	sqlWriter.StatementRows = config.SQLRows
+	sqlWriter.StatementBytes = config.SQLBytes
+	sqlWriter.Compress = config.SQLCompress
+
+	i := &Importer{
+		config:      config,
+		schema:      schema,
+		dbFieldsMap: buildDBFieldsMap(schema),
+		sqlWriter:   sqlWriter,
+		dialect:     dialect,
+		qa:          newQAReport(),
+		attrs:       newAttributeReport(),
+		dedup:       newDedupIndex(),
+		stages:      newStageCounters(config),
+	}
+	i.importSource = i.importShapefile
+
+	if config.GeoParquet != "" {
+		i.geoParquet = geoparquet.New(config.GeoParquet, schema)
+		i.geoParquet.Partition = config.GeoPartition
+	}
+
+	// Past rates are used to start the slowest sources first
+	i.history = make(rates.History)
+	i.ran = make(rates.History)
+	if config.StateDir != "" {
+		i.history, err = rates.LoadHistory(config.StateDir)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
 
-func doImport(config Config) ([]rates.RateInfo, error) {
+	if i.config.DB.StorageEngine == nil {
+		err := engine.Startup(false, &i.config.DB)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		i.ownsEngine = true
+	}
+
+	return i, nil
+}
+
+// Close stops the SQL writer and any storage engine started by New
+func (i *Importer) Close() error {
+	var funcName string = "importer.Close"
+
+	// The engine is shut down even when the writer failed
+	writerErr := i.sqlWriter.Stop()
+
+	if i.ownsEngine {
+		err := engine.Shutdown(false, i.config.DB)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		i.ownsEngine = false
+	}
+
+	if writerErr != nil {
+		return fmt.Errorf("%v: %v", funcName, writerErr.Error())
+	}
+
+	return nil
+}
+
+// Imported lists the sources imported by the last run
+func (i *Importer) Imported() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]string{}, i.imported...)
+}
+
+func (i *Importer) doImport(ctx context.Context) ([]rates.RateInfo, error) {
 	var funcName string = "importer.doImport"
 	var jobName string = "Import"
 
+	var config Config = i.config
+
 	var start time.Time = time.Now()
 	var took time.Duration
 
@@ -46,25 +199,22 @@ func doImport(config Config) ([]rates.RateInfo, error) {
 
 		if !config.Unlimited {
 			// Process the shapefiles one at a time
-			err := sequential(config, nil, config.ShapeFiles)
+			err := i.sequential(ctx, nil, config.ShapeFiles)
 			if err != nil {
 				return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
 			}
 		} else {
 			// Edge case - why would we set unlimited but not concurrent?
-			var tasks []*progress.Task
-			for _, shapeFile := range config.ShapeFiles {
-				tasks = append(tasks, &progress.Task{
-					ID:        shapeFile,
-					Magnitude: float64(shapefile.GetRecordCount(shapeFile)),
-				})
+			tasks, err := getTasks(config.ShapeFiles)
+			if err != nil {
+				return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
 			}
 
 			job := progress.SetupJob(jobName, tasks)
 			defer job.End(true)
 
 			// Process the shapefiles one at a time
-			err := sequential(config, job, config.ShapeFiles)
+			err = i.sequential(ctx, job, config.ShapeFiles)
 			if err != nil {
 				return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
 			}
@@ -86,18 +236,18 @@ func doImport(config Config) ([]rates.RateInfo, error) {
 				listFiles("shapefiles", config.DataFolder, config.ShapeFiles),
 			)
 
-			var tasks []*progress.Task
-			for _, shapeFile := range config.ShapeFiles {
-				tasks = append(tasks, &progress.Task{
-					ID:        shapeFile,
-					Magnitude: float64(shapefile.GetRecordCount(shapeFile)),
-				})
+			tasks, err := getTasks(config.ShapeFiles)
+			if err != nil {
+				return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
 			}
 
 			job := progress.SetupJob(jobName, tasks)
 			defer job.End(true)
 
-			fanout(config, job, config.ShapeFiles)
+			err = i.fanout(ctx, job, config.ShapeFiles)
+			if err != nil {
+				return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
+			}
 		}
 
 		// Not Unlimited
@@ -120,29 +270,44 @@ func doImport(config Config) ([]rates.RateInfo, error) {
 					listFiles("file", config.DataFolder, folders),
 				)
 
+				// The shapefiles of every folder are scheduled together
+				var shapeFiles []string
 				for _, folder := range folders {
 					logger.Log(
 						logger.LVL_DEBUG,
 						fmt.Sprintf("Scanning folder '%v' for shapefiles\n", folder),
 					)
 
-					shapeFiles, err := getShapefilesInFolder(folder)
+					folderShapeFiles, err := getShapefilesInFolder(folder)
 					if err != nil {
 						return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
 					}
+					folderShapeFiles = onlyConfigured(folderShapeFiles, config.ShapeFiles)
 
 					logger.Log(
 						logger.LVL_DEBUG,
-						listFiles("shapefiles", config.DataFolder, shapeFiles),
+						listFiles("shapefiles", config.DataFolder, folderShapeFiles),
 					)
 
-					if len(shapeFiles) > 0 {
+					if len(folderShapeFiles) > 0 {
 						logger.Log(
 							logger.LVL_APP,
-							fmt.Sprintf("Importing [%v] Shapefiles from [%v]", len(shapeFiles), folder),
+							fmt.Sprintf("Found [%v] Shapefiles in [%v]", len(folderShapeFiles), folder),
 						)
+					}
+
+					shapeFiles = append(shapeFiles, folderShapeFiles...)
+				}
+
+				if len(shapeFiles) > 0 {
+					logger.Log(
+						logger.LVL_APP,
+						fmt.Sprintf("Importing [%v] Shapefiles from [%v] folders", len(shapeFiles), len(folders)),
+					)
 
-						fanout(config, nil, shapeFiles)
+					err := i.fanout(ctx, nil, shapeFiles)
+					if err != nil {
+						return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
 					}
 				}
 			} else {
@@ -157,7 +322,10 @@ func doImport(config Config) ([]rates.RateInfo, error) {
 					fmt.Sprintf("Importing Shapefiles [%v]", config.NumShapeFiles),
 				)
 
-				fanout(config, nil, config.ShapeFiles)
+				err := i.fanout(ctx, nil, config.ShapeFiles)
+				if err != nil {
+					return []rates.RateInfo{}, fmt.Errorf("%v: %v", funcName, err.Error())
+				}
 			}
 		}
 	}
@@ -165,7 +333,7 @@ func doImport(config Config) ([]rates.RateInfo, error) {
 	// Log import metrics etc.
 	logger.Log(
 		logger.LVL_DEBUG,
-		fmt.Sprintf("Rates: %v\n", rateInfo),
+		fmt.Sprintf("Rates: %v\n", i.rateInfo),
 	)
 
 	took = timeutils.Took(start)
@@ -175,28 +343,74 @@ func doImport(config Config) ([]rates.RateInfo, error) {
 		fmt.Sprintf("Done Importing Shapefiles [%v]\n", took),
 	)
 
-	return rateInfo, nil
+	return i.rateInfo, nil
 }
 
-func fanout(config Config, job *progress.Job, shapeFiles []string) {
+// getTasks is a progress task for each source sized by its records
+func getTasks(sources []string) ([]*progress.Task, error) {
+	var tasks []*progress.Task
+	for _, source := range sources {
+		records, err := getRecordCount(source)
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, &progress.Task{
+			ID:        source,
+			Magnitude: float64(records),
+		})
+	}
+
+	return tasks, nil
+}
+
+func (i *Importer) fanout(ctx context.Context, job *progress.Job, shapeFiles []string) error {
+	var funcName string = "importer.fanout"
+
+	var config Config = i.config
 	var wg *waitgroup.WaitGroup = waitgroup.New()
 
+	// Limits the shapefiles imported at once
+	var workers = make(chan struct{}, getFileWorkers(config, len(shapeFiles)))
+
+	// The errors of the sources that failed, the others carry on
+	var mu sync.Mutex
+	var failed []string
+
 	// Start progressbar if needed
 	if progress.ShouldShowBar() && !config.Unlimited {
 		uiprogress.Start()
 	}
 
-	for _, shapeFile := range shapeFiles {
+	scheduled, err := i.schedule(shapeFiles)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	for _, shapeFile := range scheduled {
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
 		wg.Add(1)
 
 		go func(sf string) {
+			defer func() { <-workers }()
+
 			if job != nil {
 				task, err := job.GetTask(sf)
 				if err == nil {
 					task.Start()
 				}
 			}
-			importShapefile(config, sf) //nolint:errcheck
+			err := i.importSource(ctx, sf)
+			if err != nil {
+				mu.Lock()
+				failed = append(failed, err.Error())
+				mu.Unlock()
+			}
 			if job != nil {
 				task, err := job.GetTask(sf)
 				if err == nil {
@@ -214,17 +428,39 @@ func fanout(config Config, job *progress.Job, shapeFiles []string) {
 	if progress.ShouldShowBar() && !config.Unlimited {
 		uiprogress.Stop()
 	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("%v: %v", funcName, ctx.Err().Error())
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("%v: %v sources failed %v", funcName, len(failed), failed)
+	}
+
+	return nil
 }
 
-func sequential(config Config, job *progress.Job, shapeFiles []string) error {
+func (i *Importer) sequential(ctx context.Context, job *progress.Job, shapeFiles []string) error {
 	var funcName string = "importer.sequential"
 
+	var config Config = i.config
+
 	// Start progressbar if needed
 	if progress.ShouldShowBar() && !config.Unlimited {
 		uiprogress.Start()
 	}
 
-	for _, shapeFile := range shapeFiles {
+	scheduled, err := i.schedule(shapeFiles)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	for _, shapeFile := range scheduled {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%v: %v", funcName, ctx.Err().Error())
+		}
+
 		if job != nil {
 			task, err := job.GetTask(shapeFile)
 			if err == nil {
@@ -232,7 +468,7 @@ func sequential(config Config, job *progress.Job, shapeFiles []string) error {
 			}
 		}
 
-		err := importShapefile(config, shapeFile)
+		err := i.importSource(ctx, shapeFile)
 		if err != nil {
 			return fmt.Errorf("%v: %v", funcName, err.Error())
 		}
@@ -290,21 +526,54 @@ func GetAllShapefiles(dataFolder string) ([]string, error) {
 func getShapefilesInFolder(dataFolder string) ([]string, error) {
 	var funcName string = "importer.getShapefiles"
 
-	shapeFiles, err := fileutils.Find(dataFolder, ".shp")
+	shapeFiles, err := fileutils.Find(dataFolder, extShapefile)
+	if err != nil {
+		return []string{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	// Each layer of a GeoPackage is imported as a separate source
+	geoPackages, err := fileutils.Find(dataFolder, extGeoPackage)
 	if err != nil {
 		return []string{}, fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
+	for _, geoPackage := range geoPackages {
+		sources, err := getGeoPackageSources(geoPackage)
+		if err != nil {
+			return []string{}, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		shapeFiles = append(shapeFiles, sources...)
+	}
+
 	return shapeFiles, nil
 }
 
-func importShapefile(config Config, shapeFile string) error {
+// onlyConfigured drops the sources found in a folder that were filtered out
+// of the config e.g. quarantined or not being re-imported
+func onlyConfigured(sources, configured []string) []string {
+	var wanted = make(map[string]bool)
+	for _, source := range configured {
+		wanted[source] = true
+	}
+
+	var filtered = []string{}
+	for _, source := range sources {
+		if wanted[source] {
+			filtered = append(filtered, source)
+		}
+	}
+
+	return filtered
+}
+
+func (i *Importer) importShapefile(ctx context.Context, shapeFile string) error {
 	var funcName string = "importer.importShapefile"
 
-	sfShortName := filepath.Base(shapeFile)
+	sfShortName := getSourceShortName(shapeFile)
 
-	recordsProcessed, rowsGenerated, timeTaken, err := doImportShapefile(
-		config,
+	recordsProcessed, rowsGenerated, timeTaken, err := i.doImportShapefile(
+		ctx,
 		shapeFile,
 		sfShortName,
 	)
@@ -314,15 +583,18 @@ func importShapefile(config Config, shapeFile string) error {
 
 	info := rates.RateInfo{
 		ShapeFile: sfShortName,
+		Layer:     getLayerName(i.config, sfShortName),
 		Records:   recordsProcessed,
 		Rows:      rowsGenerated,
 		Duration:  timeTaken,
+		Merged:    i.mergesTiles(),
 	}
 
-	mutex.Lock()
-	rateInfo = append(rateInfo, info)
-	imported = append(imported, shapeFile)
-	mutex.Unlock()
+	i.mu.Lock()
+	i.rateInfo = append(i.rateInfo, info)
+	i.imported = append(i.imported, shapeFile)
+	i.ran.Add(info.Layer, getSourceBytes(shapeFile), info)
+	i.mu.Unlock()
 
 	return nil
 }
@@ -1,8 +1,10 @@
 package importer
 
 import (
+	"context"
 	"io"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -11,6 +13,7 @@ import (
 
 	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/database/engine/mysql"
+	"go-uk-maps-import/database/types"
 	"go-uk-maps-import/filelogger"
 	"go-uk-maps-import/sqlwriter"
 )
@@ -235,7 +238,6 @@ func TestImporter(t *testing.T) {
 	for name, tt := range tests {
 		logger.Start(logVbs)
 		filelogger.Start()
-		sqlwriter.Start()
 
 		err := engine.Startup(false, &tt.dbConfig)
 		if err != nil {
@@ -243,6 +245,16 @@ func TestImporter(t *testing.T) {
 		}
 		tt.importerConfig.DB = tt.dbConfig
 
+		i, err := New(tt.importerConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = i.sqlWriter.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+
 		// pointless step for bizarre scenario using sqlite as intermediary
 		// see runner.go line 70
 		var m *mysql.MySQL
@@ -265,7 +277,7 @@ func TestImporter(t *testing.T) {
 			}
 		}
 
-		rateInfo, err := doImport(tt.importerConfig)
+		rateInfo, err := i.doImport(context.Background())
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -287,7 +299,10 @@ func TestImporter(t *testing.T) {
 		}
 
 		// shutdown
-		sqlwriter.Stop()
+		err = i.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
 		filelogger.Stop()
 		logger.Stop()
 
@@ -302,7 +317,7 @@ func TestImporter(t *testing.T) {
 func TestGetFieldNamesAndValues(t *testing.T) {
 	tests := []struct {
 		input       insert
-		fieldNames  string
+		fieldNames  []string
 		fieldValues string
 	}{
 		{
@@ -310,28 +325,67 @@ func TestGetFieldNamesAndValues(t *testing.T) {
 				"ID":       "196D2113-10D7-48F8-A3C4-432A40B1AFA3",
 				"FEATCODE": 25200,
 			},
-			fieldNames:  "FEATCODE, ID, ",
-			fieldValues: `25200, "196D2113-10D7-48F8-A3C4-432A40B1AFA3", `,
+			fieldNames:  []string{"FEATCODE", "ID"},
+			fieldValues: `25200, '196D2113-10D7-48F8-A3C4-432A40B1AFA3', `,
 		},
 		{
 			input: insert{
 				"ID":       "196D2113-10D7-48F8-A3C4-432A40B1AFA3",
 				"FEATCODE": 25200.0000,
 			},
-			fieldNames:  "FEATCODE, ID, ",
-			fieldValues: `25200, "196D2113-10D7-48F8-A3C4-432A40B1AFA3", `,
+			fieldNames:  []string{"FEATCODE", "ID"},
+			fieldValues: `25200, '196D2113-10D7-48F8-A3C4-432A40B1AFA3', `,
 		},
 	}
 
 	for _, tt := range tests {
-		fieldNames, fieldValues := getFieldNamesAndValues(tt.input)
+		fieldNames, values := getFieldNamesAndValues(tt.input)
 
-		if tt.fieldNames != fieldNames {
+		if !reflect.DeepEqual(tt.fieldNames, fieldNames) {
 			t.Fatalf("fieldNames: expected [%v], got [%v]", tt.fieldNames, fieldNames)
 		}
 
+		var fieldValues string
+		for _, value := range values {
+			fieldValues += sqlwriter.MySQL{}.Literal(value) + ", "
+		}
+
 		if tt.fieldValues != fieldValues {
 			t.Fatalf("fieldValues: expected [%v], got [%v]", tt.fieldValues, fieldValues)
 		}
 	}
 }
+
+func TestCheckSQLDialect(t *testing.T) {
+	tests := map[string]struct {
+		config Config
+		valid  bool
+	}{
+		"engine dialect": {
+			config: Config{UseFiles: true, DB: engine.SEConfig{Engine: pointers.StrPtr(engine.EnginePostgres)}},
+			valid:  true,
+		},
+		"same dialect": {
+			config: Config{UseFiles: true, SQLDialect: types.DialectPostgres, DB: engine.SEConfig{Engine: pointers.StrPtr(engine.EnginePostgres)}},
+			valid:  true,
+		},
+		"other dialect": {
+			config: Config{UseFiles: true, SQLDialect: types.DialectPostgres, DB: engine.SEConfig{Engine: pointers.StrPtr(engine.EngineMySQL)}},
+		},
+		"other dialect skipping inserts": {
+			config: Config{UseFiles: true, SkipInserts: true, SQLDialect: types.DialectPostgres, DB: engine.SEConfig{Engine: pointers.StrPtr(engine.EngineMySQL)}},
+			valid:  true,
+		},
+		"no files": {
+			config: Config{SQLDialect: types.DialectPostgres, DB: engine.SEConfig{Engine: pointers.StrPtr(engine.EngineMySQL)}},
+			valid:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		err := CheckSQLDialect(tt.config)
+		if tt.valid != (err == nil) {
+			t.Errorf("%v: expected valid %v, got %v", name, tt.valid, err)
+		}
+	}
+}
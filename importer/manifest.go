@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rockwell-uk/go-utils/fileutils"
+
+	"go-uk-maps-import/database"
+	"go-uk-maps-import/database/engine/sqlite"
+	"go-uk-maps-import/manifest"
+	"go-uk-maps-import/sqlwriter"
+)
+
+// layerSource is a source file imported by the last run and the squares it
+// wrote rows to
+type layerSource struct {
+	file    string
+	squares map[string]int
+}
+
+// writeSQLManifest lists the SQL files of the run in a manifest in the SQL
+// folder, each file is a layer in a 10km square
+func (i *Importer) writeSQLManifest(sqlFiles []string) error {
+	var funcName string = "importer.writeSQLManifest"
+
+	var folder string = i.sqlWriter.Folder
+	var m *manifest.Manifest = manifest.New()
+	var hashes = manifest.SourceHashes{}
+	var layerSources = i.getLayerSources()
+
+	var rows = make(map[string]int)
+	for path, n := range i.sqlWriter.RowCounts() {
+		rows[filepath.Clean(path)] = n
+	}
+
+	for _, sqlFile := range sqlFiles {
+		layer := filepath.Base(filepath.Dir(sqlFile))
+		table := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(sqlFile), sqlwriter.CompressedExt), ".sql")
+		square := strings.TrimRight(table, "0123456789")
+
+		sources, err := i.getSources(layerSources, layer, square, hashes)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		err = m.Add(folder, sqlFile, manifest.Artefact{
+			Rows:    rows[filepath.Clean(sqlFile)],
+			Layer:   layer,
+			Square:  table,
+			Sources: sources,
+		})
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	err := m.Save(folder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// writeLayerManifest lists the files exported from the SQLite databases in
+// a manifest in their folder, each file is every square of a layer e.g. the
+// database files themselves, rows is the number of rows in the file of each
+// layer
+func (i *Importer) writeLayerManifest(folder string, layerFile func(layer string) string, rows map[string]int) error {
+	var funcName string = "importer.writeLayerManifest"
+
+	var m *manifest.Manifest = manifest.New()
+	var hashes = manifest.SourceHashes{}
+	var layerSources = i.getLayerSources()
+
+	for layer := range i.schema.MapLayers {
+		file := layerFile(layer)
+		if !fileutils.FileExists(file) {
+			continue
+		}
+
+		sources, err := i.getSources(layerSources, layer, "", hashes)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		err = m.Add(folder, file, manifest.Artefact{
+			Rows:    rows[layer],
+			Layer:   layer,
+			Sources: sources,
+		})
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	err := m.Save(folder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// getLayerRows counts the rows of each layer in the databases
+func getLayerRows(se *sqlite.SQLite) (map[string]int, error) {
+	tableCounts, err := database.GetTableCounts(se)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows = make(map[string]int)
+	for fullTableName, n := range tableCounts.TableCounts {
+		layer, _, _ := strings.Cut(fullTableName, ".")
+		rows[layer] += n
+	}
+
+	return rows, nil
+}
+
+// closeGeoParquet finishes the GeoParquet files, listing the sources of each
+// in their manifest
+func (i *Importer) closeGeoParquet() error {
+	var hashes = manifest.SourceHashes{}
+	var layerSources = i.getLayerSources()
+
+	i.geoParquet.Sources = func(layer, square string) ([]manifest.Source, error) {
+		return i.getSources(layerSources, layer, square, hashes)
+	}
+
+	return i.geoParquet.Close()
+}
+
+// getLayerSources lists the sources of the last run by layer, a layer of a
+// GeoPackage is listed as the GeoPackage
+func (i *Importer) getLayerSources() map[string][]layerSource {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var squares = make(map[string]map[string]int)
+	for _, info := range i.rateInfo {
+		squares[info.ShapeFile] = info.Rows
+	}
+
+	var layerSources = make(map[string][]layerSource)
+	for _, source := range i.imported {
+		sfShortName := getSourceShortName(source)
+		layer := getLayerName(i.config, sfShortName)
+
+		file := source
+		if isGeoPackageLayer(source) {
+			file, _ = splitGeoPackageLayer(source)
+		}
+
+		layerSources[layer] = append(layerSources[layer], layerSource{
+			file:    file,
+			squares: squares[sfShortName],
+		})
+	}
+
+	return layerSources
+}
+
+// getSources returns the sources that wrote rows of a layer to a square, or
+// to any square if square is empty, a generalised layer has the sources of
+// the layer it is generalised from
+func (i *Importer) getSources(layerSources map[string][]layerSource, layer, square string, hashes manifest.SourceHashes) ([]manifest.Source, error) {
+	if base, isCompanion := i.schema.GeneralisedLayers[layer]; isCompanion {
+		layer = base
+	}
+
+	var files = make(map[string]bool)
+	for _, ls := range layerSources[layer] {
+		if _, wrote := ls.squares[square]; square == "" || wrote {
+			files[ls.file] = true
+		}
+	}
+
+	var sources = []manifest.Source{}
+	for file := range files {
+		source, err := hashes.Source(file)
+		if err != nil {
+			return []manifest.Source{}, err
+		}
+		sources = append(sources, source)
+	}
+
+	sort.Slice(sources, func(a, b int) bool {
+		return sources[a].File < sources[b].File
+	})
+
+	return sources, nil
+}
@@ -0,0 +1,356 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rockwell-uk/go-logger/logger"
+
+	"go-uk-maps-import/database"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/shpinfo"
+)
+
+const (
+	// The column type of mapped fields without one
+	defaultMappedFieldType = "varchar(255) DEFAULT NULL"
+)
+
+var (
+	mappedLayerRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	mappedFieldRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+// Mapping imports shapefiles that are not part of the OS releases, such as
+// survey or council data, into layers of their own
+type Mapping struct {
+	Layers []LayerMapping `json:"layers"`
+}
+
+// LayerMapping maps the shapefiles whose name matches a glob e.g. survey_*.shp
+// to a layer, one of the mapped fields must be the ID, the other DBF fields
+// are only imported with AllFields
+type LayerMapping struct {
+	Match     string         `json:"match"`
+	Layer     string         `json:"layer"`
+	Partition bool           `json:"partition"`
+	AllFields bool           `json:"all_fields"`
+	Fields    []FieldMapping `json:"fields"`
+}
+
+// FieldMapping renames a DBF field, the kind is a logical type e.g.
+// decimal(10,2) or enum(A,B) written in the dialect of each engine, the type
+// is an SQL column type in the dialect of the storage engine, the type of a
+// field with neither is inferred from the DBF field definition
+type FieldMapping struct {
+	Source string `json:"source"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Kind   string `json:"kind"`
+}
+
+func (m Mapping) String() string {
+	var layers []string
+	for _, l := range m.Layers {
+		layers = append(layers, fmt.Sprintf("%v=%v", l.Match, l.Layer))
+	}
+
+	return fmt.Sprintf("%v", layers)
+}
+
+// LoadMapping reads a JSON layer mapping file
+func LoadMapping(path string) (Mapping, error) {
+	var funcName string = "importer.LoadMapping"
+
+	var mapping Mapping
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return mapping, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if err := json.Unmarshal(b, &mapping); err != nil {
+		return Mapping{}, fmt.Errorf("%v: cannot unmarshal JSON [%v]", funcName, err.Error())
+	}
+
+	err = mapping.validate()
+	if err != nil {
+		return Mapping{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return mapping, nil
+}
+
+func (m Mapping) validate() error {
+	for _, l := range m.Layers {
+		if _, err := filepath.Match(l.Match, ""); err != nil || l.Match == "" {
+			return fmt.Errorf("invalid match %q for layer %v", l.Match, l.Layer)
+		}
+
+		if !mappedLayerRegex.MatchString(l.Layer) {
+			return fmt.Errorf("invalid layer name %q", l.Layer)
+		}
+
+		if _, exists := types.OSLayer(l.Layer); exists {
+			return fmt.Errorf("layer %v is an OS layer", l.Layer)
+		}
+
+		var names = make(map[string]bool)
+		for _, f := range l.Fields {
+			switch {
+			case f.Source == "":
+				return fmt.Errorf("layer %v field %v has no source", l.Layer, f.Name)
+			case !mappedFieldRegex.MatchString(f.Name):
+				return fmt.Errorf("layer %v has an invalid field name %q", l.Layer, f.Name)
+			case f.Name == "GRIDREF":
+				return fmt.Errorf("layer %v cannot map GRIDREF", l.Layer)
+			case names[f.Name]:
+				return fmt.Errorf("layer %v maps %v more than once", l.Layer, f.Name)
+			case f.Kind != "" && f.Type != "":
+				return fmt.Errorf("layer %v field %v has both a kind and a type", l.Layer, f.Name)
+			}
+
+			if _, logical := types.ParseColumn(f.Kind); f.Kind != "" && !logical {
+				return fmt.Errorf("layer %v field %v has an invalid kind %q", l.Layer, f.Name, f.Kind)
+			}
+			names[f.Name] = true
+		}
+
+		if !names["ID"] {
+			return fmt.Errorf("layer %v does not map an ID", l.Layer)
+		}
+	}
+
+	return nil
+}
+
+// register adds the mapped layers to a schema, the column types are
+// inferred from the DBF of the mapped shapefiles, SQL types must be in the
+// dialect of the storage engine
+func (m Mapping) register(schema *types.Schema, shapeFiles []string, dialect string) error {
+	for n, l := range m.Layers {
+		var fields []string
+		var fieldTypes = make(map[string]string)
+
+		dbfFields := m.readMappedSources(schema, n, shapeFiles)
+
+		for _, f := range l.Fields {
+			fields = append(fields, f.Name)
+
+			exists := schema.HasFieldType(f.Name)
+			dbfField, inDBF := findDBFField(dbfFields, f.Source)
+			column, logical := types.ParseColumn(f.Kind)
+
+			switch {
+			case logical:
+				err := schema.AddColumn(f.Name, column)
+				if err != nil {
+					return err
+				}
+			case f.Kind != "":
+				return fmt.Errorf("layer %v field %v has an invalid kind %q", l.Layer, f.Name, f.Kind)
+			case f.Type != "":
+				err := types.ValidateSQLType(f.Type, dialect)
+				if err != nil {
+					return fmt.Errorf("layer %v field %v: %v", l.Layer, f.Name, err.Error())
+				}
+				fieldTypes[f.Name] = f.Type
+			case exists:
+			case inDBF:
+				err := addDBFColumn(schema, f.Name, dbfField)
+				if err != nil {
+					return err
+				}
+			default:
+				fieldTypes[f.Name] = defaultMappedFieldType
+			}
+		}
+
+		if l.AllFields {
+			for k := range dbfFields {
+				dbfField := &dbfFields[k]
+				if l.isMapped(dbfField.Name) {
+					continue
+				}
+
+				if !mappedFieldRegex.MatchString(dbfField.Name) {
+					return fmt.Errorf("layer %v has an invalid DBF field name %q", l.Layer, dbfField.Name)
+				}
+
+				fields = append(fields, dbfField.Name)
+				if schema.HasFieldType(dbfField.Name) {
+					continue
+				}
+
+				err := addDBFColumn(schema, dbfField.Name, dbfField)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err := schema.AddLayer(l.Layer, fields, fieldTypes, l.Partition)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMappedSources records the geometry type and projection of a mapped
+// layer from its shapefiles and returns the union of their DBF fields in the
+// order they are found
+func (m Mapping) readMappedSources(schema *types.Schema, n int, shapeFiles []string) []shpinfo.Field {
+	var l LayerMapping = m.Layers[n]
+	var fields []shpinfo.Field
+
+	for _, shapeFile := range shapeFiles {
+		if isGeoPackageLayer(shapeFile) {
+			continue
+		}
+
+		// Only the first matching layer maps a source
+		if found, mapped := m.find(getSourceShortName(shapeFile)); !mapped || found != &m.Layers[n] {
+			continue
+		}
+
+		// Sources that cannot be read are quarantined by the validation
+		header, err := shpinfo.ReadHeader(shapeFile)
+		if err != nil {
+			continue
+		}
+
+		dbfHeader, err := shpinfo.ReadDBFHeader(shpinfo.SiblingPath(shapeFile, shpinfo.ExtDbf))
+		if err != nil {
+			continue
+		}
+
+		// Coordinates that are not in metres cannot be measured
+		prj, err := shpinfo.ReadPrj(shpinfo.SiblingPath(shapeFile, shpinfo.ExtPrj))
+		if err != nil || !shpinfo.IsBritishNationalGrid(prj) {
+			schema.UnmeasuredLayers[l.Layer] = true
+		}
+
+		geometryType, exists := schema.GeometryTypes[l.Layer]
+		switch {
+		case !exists:
+			schema.GeometryTypes[l.Layer] = header.ShapeTypeName()
+		case geometryType != header.ShapeTypeName():
+			logger.Log(
+				logger.LVL_WARN,
+				fmt.Sprintf("%v is %v, layer %v is %v\n", shapeFile, header.ShapeTypeName(), l.Layer, geometryType),
+			)
+		}
+
+		for _, field := range dbfHeader.Fields {
+			if existing, found := findDBFField(fields, field.Name); found {
+				if field.Length > existing.Length {
+					existing.Length = field.Length
+				}
+				if field.Decimals > existing.Decimals {
+					existing.Decimals = field.Decimals
+				}
+				continue
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func addDBFColumn(schema *types.Schema, name string, field *shpinfo.Field) error {
+	column, err := types.ColumnFromDBF(field.Type, field.Length, field.Decimals)
+	if err != nil {
+		return fmt.Errorf("field %v: %v", name, err.Error())
+	}
+
+	return schema.AddColumn(name, column)
+}
+
+func findDBFField(fields []shpinfo.Field, name string) (*shpinfo.Field, bool) {
+	for n := range fields {
+		if strings.EqualFold(fields[n].Name, name) {
+			return &fields[n], true
+		}
+	}
+
+	return nil, false
+}
+
+// isMapped reports whether a DBF field is read or replaced by a mapped field
+func (l LayerMapping) isMapped(dbfField string) bool {
+	if strings.EqualFold(dbfField, "GRIDREF") {
+		return true
+	}
+
+	for _, f := range l.Fields {
+		if strings.EqualFold(f.Source, dbfField) || strings.EqualFold(f.Name, dbfField) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find returns the mapping of a source, the first match wins
+func (m Mapping) find(sfShortName string) (*LayerMapping, bool) {
+	for n, l := range m.Layers {
+		if matched, _ := filepath.Match(l.Match, sfShortName); matched {
+			return &m.Layers[n], true
+		}
+	}
+
+	return nil, false
+}
+
+// apply renames the mapped fields of a record, dropping the others unless
+// every field is imported
+func (l LayerMapping) apply(schema *types.Schema, rec Record) Record {
+	var mapped = make(Record, len(l.Fields))
+
+	for _, f := range l.Fields {
+		value, exists := rec[f.Source]
+		if !exists {
+			value = getFieldFold(rec, f.Source)
+		}
+		mapped[f.Name] = value
+	}
+
+	if l.AllFields {
+		for _, field := range schema.MapLayers[l.Layer] {
+			if _, exists := mapped[field]; !exists && !l.isMapped(field) && !schema.IsDerivedField(l.Layer, field) {
+				mapped[field] = rec[field]
+			}
+		}
+	}
+
+	return mapped
+}
+
+// getFieldFold finds a field ignoring case, DBF field names are usually
+// upper case but not always
+func getFieldFold(rec Record, field string) interface{} {
+	for name, value := range rec {
+		if strings.EqualFold(name, field) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+// getLayerName is the layer a source is imported into, the layer of an OS
+// source is given by its file name e.g. SD_Road.shp is road
+func getLayerName(config Config, sfShortName string) string {
+	if l, mapped := config.Mapping.find(sfShortName); mapped {
+		return l.Layer
+	}
+
+	return database.GetDBNameFromFilename(sfShortName)
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-uk-maps-import/database/types"
+)
+
+func TestLoadMapping(t *testing.T) {
+	tests := map[string]struct {
+		json  string
+		valid bool
+	}{
+		"valid": {
+			json:  `{"layers": [{"match": "survey_*.shp", "layer": "survey_point", "fields": [{"source": "FID", "name": "ID"}]}]}`,
+			valid: true,
+		},
+		"no id": {
+			json: `{"layers": [{"match": "survey_*.shp", "layer": "survey_point", "fields": [{"source": "NAME", "name": "NAME"}]}]}`,
+		},
+		"os layer": {
+			json: `{"layers": [{"match": "survey_*.shp", "layer": "road", "fields": [{"source": "FID", "name": "ID"}]}]}`,
+		},
+		"invalid layer name": {
+			json: `{"layers": [{"match": "survey_*.shp", "layer": "Survey Point", "fields": [{"source": "FID", "name": "ID"}]}]}`,
+		},
+		"kind": {
+			json:  `{"layers": [{"match": "survey_*.shp", "layer": "survey_point", "fields": [{"source": "FID", "name": "ID"}, {"source": "H", "name": "HEIGHT_M", "kind": "decimal(8,2)"}]}]}`,
+			valid: true,
+		},
+		"invalid kind": {
+			json: `{"layers": [{"match": "survey_*.shp", "layer": "survey_point", "fields": [{"source": "FID", "name": "ID"}, {"source": "H", "name": "HEIGHT_M", "kind": "varchar(8)"}]}]}`,
+		},
+		"kind and type": {
+			json: `{"layers": [{"match": "survey_*.shp", "layer": "survey_point", "fields": [{"source": "FID", "name": "ID"}, {"source": "H", "name": "HEIGHT_M", "kind": "integer", "type": "int"}]}]}`,
+		},
+		"gridref": {
+			json: `{"layers": [{"match": "survey_*.shp", "layer": "survey_point", "fields": [{"source": "FID", "name": "ID"}, {"source": "REF", "name": "GRIDREF"}]}]}`,
+		},
+	}
+
+	for name, tt := range tests {
+		path := filepath.Join(t.TempDir(), "mapping.json")
+		err := os.WriteFile(path, []byte(tt.json), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = LoadMapping(path)
+		if tt.valid && err != nil {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestMappingRegister(t *testing.T) {
+	schema := types.NewSchema()
+
+	mapping := Mapping{
+		Layers: []LayerMapping{
+			{
+				Match: "council_*.shp",
+				Layer: "council_asset",
+				Fields: []FieldMapping{
+					{Source: "ASSET_ID", Name: "ID"},
+					{Source: "KIND", Name: "ASSET_KIND", Type: "varchar(50) DEFAULT NULL"},
+					{Source: "NOTES", Name: "ASSET_NOTES", Type: "text"},
+					{Source: "SIZE", Name: "ASSET_SIZE", Kind: "enum(Small,Large)"},
+				},
+			},
+		},
+	}
+
+	// Registering again leaves the schema as it was
+	for n := 0; n < 2; n++ {
+		err := mapping.register(schema, nil, types.DialectMySQL)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := types.LayerType{"ID", "GRIDREF", "ASSET_KIND", "ASSET_NOTES", "ASSET_SIZE"}
+	if !reflect.DeepEqual(expected, schema.MapLayers["council_asset"]) {
+		t.Errorf("expected %v, got %v", expected, schema.MapLayers["council_asset"])
+	}
+
+	// An SQL type is used as it is, a kind is parsed and written per dialect
+	if _, typed := schema.Columns["ASSET_NOTES"]; typed {
+		t.Error("expected the SQL type text not to be a logical kind")
+	}
+	if fieldType, _ := schema.GetFieldType("ASSET_NOTES", types.DialectPostgres); fieldType != "text" {
+		t.Errorf("expected text, got %v", fieldType)
+	}
+	if c := schema.Columns["ASSET_SIZE"]; c.Kind != types.KindEnum {
+		t.Errorf("expected an enum, got %v", c)
+	}
+
+	squares := schema.LayerSquares("council_asset")
+	if !reflect.DeepEqual([]string{types.UnpartitionedSquare}, squares) {
+		t.Errorf("expected a single table, got %v", squares)
+	}
+
+	l, mapped := mapping.find("council_parks.shp")
+	if !mapped {
+		t.Fatal("expected council_parks.shp to be mapped")
+	}
+
+	rec := l.apply(schema, Record{"ASSET_ID": "a1", "kind": "bench", "OTHER": "x"})
+	if !reflect.DeepEqual(Record{"ID": "a1", "ASSET_KIND": "bench", "ASSET_NOTES": nil, "ASSET_SIZE": nil}, rec) {
+		t.Errorf("unexpected record %v", rec)
+	}
+
+	if _, mapped := mapping.find("SD_Road.shp"); mapped {
+		t.Error("expected SD_Road.shp not to be mapped")
+	}
+}
+
+func TestMappingInferSchema(t *testing.T) {
+	schema := types.NewSchema()
+
+	mapping := Mapping{
+		Layers: []LayerMapping{
+			{
+				Match:     "SD_Motorway*.shp",
+				Layer:     "junction_survey",
+				Partition: true,
+				AllFields: true,
+				Fields: []FieldMapping{
+					{Source: "ID", Name: "ID"},
+					{Source: "JUNCTNUM", Name: "JUNCTION"},
+				},
+			},
+		},
+	}
+
+	err := mapping.register(schema, []string{"./testdata/SD_MotorwayJunction.shp"}, types.DialectMySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := types.LayerType{"ID", "GRIDREF", "JUNCTION", "FEATCODE"}
+	if !reflect.DeepEqual(expected, schema.MapLayers["junction_survey"]) {
+		t.Errorf("expected %v, got %v", expected, schema.MapLayers["junction_survey"])
+	}
+
+	if geometryType := schema.GeometryTypes["junction_survey"]; geometryType != "PointZ" {
+		t.Errorf("expected PointZ, got %v", geometryType)
+	}
+
+	tests := map[string]struct {
+		dialect  string
+		expected string
+	}{
+		"mysql": {
+			dialect:  types.DialectMySQL,
+			expected: "varchar(10) DEFAULT NULL",
+		},
+		"sqlite": {
+			dialect:  types.DialectSQLite,
+			expected: "text DEFAULT NULL",
+		},
+	}
+
+	for name, tt := range tests {
+		actual, _ := schema.GetFieldType("JUNCTION", tt.dialect)
+		if actual != tt.expected {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+
+	rec := mapping.Layers[0].apply(schema, Record{"ID": "a1", "JUNCTNUM": "32", "FEATCODE": 25240})
+	if !reflect.DeepEqual(Record{"ID": "a1", "JUNCTION": "32", "FEATCODE": 25240}, rec) {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
+
+func TestMappingSQLType(t *testing.T) {
+	tests := map[string]struct {
+		sqlType string
+		dialect string
+		valid   bool
+	}{
+		"mysql varchar": {
+			sqlType: "varchar(50) DEFAULT NULL",
+			dialect: types.DialectMySQL,
+			valid:   true,
+		},
+		"mysql unsigned": {
+			sqlType: "int(10) unsigned NOT NULL",
+			dialect: types.DialectMySQL,
+			valid:   true,
+		},
+		"pgsql double precision": {
+			sqlType: "double precision DEFAULT NULL",
+			dialect: types.DialectPostgres,
+			valid:   true,
+		},
+		"pgsql unsigned": {
+			sqlType: "int unsigned",
+			dialect: types.DialectPostgres,
+		},
+		"pgsql mediumtext": {
+			sqlType: "mediumtext",
+			dialect: types.DialectPostgres,
+		},
+		"sqlite jsonb": {
+			sqlType: "jsonb",
+			dialect: types.DialectSQLite,
+		},
+		"injection": {
+			sqlType: "int, DROP TABLE x",
+			dialect: types.DialectMySQL,
+		},
+	}
+
+	for name, tt := range tests {
+		mapping := Mapping{
+			Layers: []LayerMapping{
+				{
+					Match: "council_*.shp",
+					Layer: "council_asset",
+					Fields: []FieldMapping{
+						{Source: "ASSET_ID", Name: "ID"},
+						{Source: "KIND", Name: "ASSET_KIND", Type: tt.sqlType},
+					},
+				},
+			},
+		}
+
+		err := mapping.register(types.NewSchema(), nil, tt.dialect)
+		if tt.valid && err != nil {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
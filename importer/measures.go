@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"math"
+
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+)
+
+// addMeasureFields adds the measure columns of a layer to a schema
+func addMeasureFields(schema *types.Schema, layerType string) error {
+	for _, field := range schema.MeasureFields(layerType) {
+		err := schema.AddDerivedColumn(layerType, field, types.MeasureColumn(field))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// measureRecord sets the measures of a feature from its full geometry, the
+// label point is on the line or inside the polygon unlike the centroid
+func measureRecord(schema *types.Schema, dbName string, rec Record, g *geos.Geom) Record {
+	var fields []string = schema.MeasureFields(dbName)
+	if len(fields) == 0 {
+		return rec
+	}
+
+	for _, field := range fields {
+		rec[field] = nil
+	}
+
+	if g == nil || g.IsEmpty() {
+		return rec
+	}
+
+	for _, field := range fields {
+		switch field {
+		case types.AreaField:
+			rec[field] = roundMeasure(g.Area())
+		case types.LengthField:
+			rec[field] = roundMeasure(g.Length())
+		}
+	}
+
+	if centroid := g.Centroid(); centroid != nil && !centroid.IsEmpty() {
+		rec[types.CentroidXField] = roundMeasure(centroid.X())
+		rec[types.CentroidYField] = roundMeasure(centroid.Y())
+	}
+
+	if label := g.PointOnSurface(); label != nil && !label.IsEmpty() {
+		rec[types.LabelXField] = roundMeasure(label.X())
+		rec[types.LabelYField] = roundMeasure(label.Y())
+	}
+
+	return rec
+}
+
+// roundMeasure rounds to the centimetre of the measure columns
+func roundMeasure(v float64) float64 {
+	return math.Round(v*100) / 100
+}
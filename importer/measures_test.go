@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"go-uk-maps-import/database/types"
+)
+
+func TestAddMeasureFields(t *testing.T) {
+	schema := types.NewSchema()
+
+	tests := map[string]struct {
+		layer    string
+		expected []string
+	}{
+		"polygon": {
+			layer:    "woodland",
+			expected: []string{"area_m2", "centroid_x", "centroid_y", "label_x", "label_y"},
+		},
+		"line": {
+			layer:    "surface_water_line",
+			expected: []string{"length_m", "centroid_x", "centroid_y", "label_x", "label_y"},
+		},
+		"point": {
+			layer: "spot_height",
+		},
+	}
+
+	for name, tt := range tests {
+		// Adding the fields again leaves the schema as it was
+		for n := 0; n < 2; n++ {
+			err := addMeasureFields(schema, tt.layer)
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+		}
+
+		actual := []string(schema.DerivedFields[tt.layer])
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+
+	actual, _ := schema.GetFieldType("area_m2", types.DialectPostgres)
+	if actual != "decimal(14,2) DEFAULT NULL" {
+		t.Errorf("unexpected area_m2 type %v", actual)
+	}
+}
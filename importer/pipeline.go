@@ -0,0 +1,540 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-nationalgrid"
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/filelogger"
+)
+
+const (
+	STAGE_READ   = "read"
+	STAGE_DECODE = "decode"
+	STAGE_SPLIT  = "split"
+	STAGE_BATCH  = "batch"
+	STAGE_WRITE  = "write"
+
+	defaultQueueSize = 1000
+	batchQueueSize   = 2
+	sampleInterval   = 100 * time.Millisecond
+)
+
+// The stages in the order features flow through them
+var stageNames = []string{STAGE_READ, STAGE_DECODE, STAGE_SPLIT, STAGE_BATCH, STAGE_WRITE}
+
+// StageStats is the work done by a stage of the import pipeline, the queue
+// in front of the slowest stage stays full while the stages after it wait
+type StageStats struct {
+	Stage    string
+	Workers  int
+	Items    int64
+	Busy     time.Duration
+	AvgQueue float64
+	MaxQueue int64
+	QueueCap int
+}
+
+// Throughput is the items per second the stage handles while busy
+func (s StageStats) Throughput() float64 {
+	if s.Busy == 0 {
+		return 0
+	}
+
+	return float64(s.Items) / s.Busy.Seconds() * float64(s.Workers)
+}
+
+func (s StageStats) String() string {
+	var queue string = "-"
+	if s.QueueCap > 0 {
+		queue = fmt.Sprintf("avg %.1f max %v/%v", s.AvgQueue, s.MaxQueue, s.QueueCap)
+	}
+
+	return fmt.Sprintf("%-6v\tworkers %v\titems %v\t%.0f/s\tqueue %v", s.Stage, s.Workers, s.Items, s.Throughput(), queue)
+}
+
+// stageCounters are shared by the pipelines of every source being imported
+type stageCounters struct {
+	workers  int
+	queueCap int
+	items    int64
+	busy     int64
+	samples  int64
+	depth    int64
+	maxDepth int64
+}
+
+func (c *stageCounters) done(started time.Time) {
+	atomic.AddInt64(&c.items, 1)
+	atomic.AddInt64(&c.busy, int64(time.Since(started)))
+}
+
+func (c *stageCounters) sample(depth int) {
+	atomic.AddInt64(&c.samples, 1)
+	atomic.AddInt64(&c.depth, int64(depth))
+
+	for {
+		max := atomic.LoadInt64(&c.maxDepth)
+		if int64(depth) <= max || atomic.CompareAndSwapInt64(&c.maxDepth, max, int64(depth)) {
+			return
+		}
+	}
+}
+
+func newStageCounters(config Config) map[string]*stageCounters {
+	var workers = map[string]int{
+		STAGE_DECODE: config.DecodeWorkers,
+		STAGE_SPLIT:  config.SplitWorkers,
+	}
+
+	var counters = make(map[string]*stageCounters)
+	for _, stage := range stageNames {
+		c := &stageCounters{
+			workers:  1,
+			queueCap: config.QueueSize,
+		}
+		if n, exists := workers[stage]; exists {
+			c.workers = n
+		}
+
+		switch stage {
+		case STAGE_READ:
+			// Nothing queues in front of the reader
+			c.queueCap = 0
+		case STAGE_WRITE:
+			c.queueCap = batchQueueSize
+		}
+
+		counters[stage] = c
+	}
+
+	return counters
+}
+
+// Stats returns the work done by each stage of the pipeline so far
+func (i *Importer) Stats() []StageStats {
+	var stats []StageStats
+
+	for _, stage := range stageNames {
+		c := i.stages[stage]
+
+		s := StageStats{
+			Stage:    stage,
+			Workers:  c.workers,
+			Items:    atomic.LoadInt64(&c.items),
+			Busy:     time.Duration(atomic.LoadInt64(&c.busy)),
+			MaxQueue: atomic.LoadInt64(&c.maxDepth),
+			QueueCap: c.queueCap,
+		}
+
+		if samples := atomic.LoadInt64(&c.samples); samples > 0 {
+			s.AvgQueue = float64(atomic.LoadInt64(&c.depth)) / float64(samples)
+		}
+
+		stats = append(stats, s)
+	}
+
+	return stats
+}
+
+func (i *Importer) logStats(logFile io.Writer) {
+	for _, s := range i.Stats() {
+		logger.Log(
+			logger.LVL_DEBUG,
+			fmt.Sprintf("Stage %v\n", s),
+		)
+
+		filelogger.Log(
+			filelogger.LogLine{
+				File: logFile,
+				Line: fmt.Sprintf("stage %v", s),
+			},
+		)
+	}
+}
+
+// decodedFeature is a feature ready to be split across the grid
+type decodedFeature struct {
+	rec    Record
+	bounds *geos.Bounds
+	layers []layerGeometry
+}
+
+// row is a feature in one 10km grid square of a layer, the key is
+// layer.square e.g. road.sd
+type row struct {
+	key     string
+	gridRef int
+	rec     Record
+	wkb     []byte
+}
+
+type rowBatch map[string][]row
+
+// pipeline moves the features of one source through the import stages
+type pipeline struct {
+	i           *Importer
+	dbName      string
+	sfShortName string
+	writer      batchWriter
+
+	// mapping is set for sources imported through a layer mapping
+	mapping *LayerMapping
+
+	// held features were checked when they were read and are written once
+	// every tile has been read
+	held bool
+
+	features chan importAction
+	decoded  chan decodedFeature
+	split    chan importResult
+	batches  chan rowBatch
+}
+
+func (i *Importer) newPipeline(dbName, sfShortName string) *pipeline {
+	var queueSize int = i.config.QueueSize
+	mapping, _ := i.config.Mapping.find(sfShortName)
+
+	return &pipeline{
+		i:           i,
+		dbName:      dbName,
+		sfShortName: sfShortName,
+		writer:      i.newBatchWriter(),
+		mapping:     mapping,
+		features:    make(chan importAction, queueSize),
+		decoded:     make(chan decodedFeature, queueSize),
+		split:       make(chan importResult, queueSize),
+		batches:     make(chan rowBatch, batchQueueSize),
+	}
+}
+
+// run starts the stages and feeds them from read, returning the rows
+// generated per square once every batch has been written
+func (p *pipeline) run(parent context.Context, read func(ctx context.Context, out chan<- importAction) error) (map[string]int, error) {
+	var funcName string = "importer.pipeline.run"
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var firstErr error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	var rowsGenerated = make(map[string]int)
+	var stages sync.WaitGroup
+	var decoders, splitters sync.WaitGroup
+
+	go p.sampleQueues(ctx)
+
+	// Read
+	stages.Add(1)
+	go func() {
+		defer stages.Done()
+		defer close(p.features)
+
+		err := read(ctx, p.features)
+		if err != nil {
+			fail(err)
+		}
+	}()
+
+	// Decode, each worker has its own geos context
+	for n := 0; n < p.i.stages[STAGE_DECODE].workers; n++ {
+		decoders.Add(1)
+		go func() {
+			defer decoders.Done()
+
+			err := p.decodeStage(ctx, geos.NewContext())
+			if err != nil {
+				fail(err)
+			}
+		}()
+	}
+	stages.Add(1)
+	go func() {
+		defer stages.Done()
+		decoders.Wait()
+		close(p.decoded)
+	}()
+
+	// Grid split
+	for n := 0; n < p.i.stages[STAGE_SPLIT].workers; n++ {
+		splitters.Add(1)
+		go func() {
+			defer splitters.Done()
+			p.splitStage(ctx)
+		}()
+	}
+	stages.Add(1)
+	go func() {
+		defer stages.Done()
+		splitters.Wait()
+		close(p.split)
+	}()
+
+	// Batch
+	stages.Add(1)
+	go func() {
+		defer stages.Done()
+		defer close(p.batches)
+		p.batchStage(ctx, rowsGenerated)
+	}()
+
+	// Write
+	stages.Add(1)
+	go func() {
+		defer stages.Done()
+
+		err := p.writeStage(ctx)
+		if err != nil {
+			fail(err)
+		}
+	}()
+
+	stages.Wait()
+
+	if firstErr == nil && parent.Err() != nil {
+		firstErr = parent.Err()
+	}
+
+	if firstErr != nil {
+		return rowsGenerated, fmt.Errorf("%v: %v", funcName, firstErr.Error())
+	}
+
+	return rowsGenerated, nil
+}
+
+// decodeStage converts the geometries to WKB, checks them and prepares the
+// records, a geometry that cannot be converted fails the source
+func (p *pipeline) decodeStage(ctx context.Context, gctx *geos.Context) error {
+	var counters *stageCounters = p.i.stages[STAGE_DECODE]
+
+	for f := range p.features {
+		started := time.Now()
+
+		if f.toWKB != nil {
+			b, err := f.toWKB()
+			if err != nil {
+				return err
+			}
+			f.wkb = b
+		}
+
+		var rec Record
+		var shapeGeom *geos.Geom
+		var b []byte
+
+		if p.held {
+			rec, shapeGeom, b = decodeHeld(gctx, f, p.dbName)
+		} else {
+			if p.mapping != nil {
+				f.insert = p.mapping.apply(p.i.schema, f.insert)
+			}
+			rec, shapeGeom, b = p.i.prepareFeature(gctx, f, p.dbName)
+		}
+
+		if shapeGeom == nil {
+			counters.done(started)
+			continue
+		}
+
+		bounds := shapeGeom.Bounds()
+
+		// The same feature is in each tile it crosses, mapped sources
+		// are not tiled
+		if !p.held && p.mapping == nil && p.i.mergesTiles() && crossesTile(p.sfShortName, bounds) {
+			err := p.i.dedup.hold(p.dbName, p.sfShortName, rec, b)
+			if err != nil {
+				return err
+			}
+			counters.done(started)
+			continue
+		}
+
+		d := decodedFeature{
+			rec:    rec,
+			bounds: bounds,
+			layers: getLayerGeometries(p.i.config, shapeGeom, b, p.dbName),
+		}
+		counters.done(started)
+
+		select {
+		case p.decoded <- d:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func decodeHeld(gctx *geos.Context, f importAction, dbName string) (Record, *geos.Geom, []byte) {
+	g, err := gctx.NewGeomFromWKB(f.wkb)
+	if err != nil {
+		logger.Log(
+			logger.LVL_ERROR,
+			fmt.Sprintf("%v [%v] %v", dbName, f.insert["ID"], err.Error()),
+		)
+		return nil, nil, nil
+	}
+
+	return f.insert, g, f.wkb
+}
+
+func (p *pipeline) splitStage(ctx context.Context) {
+	var counters *stageCounters = p.i.stages[STAGE_SPLIT]
+
+	for d := range p.decoded {
+		started := time.Now()
+
+		var result = importResult{
+			rowsGenerated: make(map[string]int),
+		}
+
+		for square, subSquares := range p.getSubSquares(d.bounds) {
+			if !shouldWrite(p.i.config, square) {
+				continue
+			}
+
+			if _, exists := result.rowsGenerated[square]; !exists {
+				result.rowsGenerated[square] = 0
+			}
+
+			for _, layer := range d.layers {
+				key := fmt.Sprintf("%v.%v", layer.dbName, square)
+
+				for _, gridRef := range subSquares {
+					result.rows = append(result.rows, row{
+						key:     key,
+						gridRef: gridRef,
+						rec:     d.rec,
+						wkb:     layer.wkb,
+					})
+
+					// Only the full detail rows are checked against the source
+					if layer.dbName == p.dbName {
+						result.rowsGenerated[square]++
+					}
+				}
+			}
+		}
+		counters.done(started)
+
+		select {
+		case p.split <- result:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// getSubSquares returns the 10km grid refs of each square a feature is in,
+// the features of a layer that is not partitioned share a single table
+func (p *pipeline) getSubSquares(bounds *geos.Bounds) map[string][]int {
+	if p.mapping != nil && !p.mapping.Partition {
+		return map[string][]int{
+			strings.ToLower(types.UnpartitionedSquare): {0},
+		}
+	}
+
+	return nationalgrid.GetSubSquares(bounds)
+}
+
+// batchStage groups the rows by table, sending a batch every chunkSize features
+func (p *pipeline) batchStage(ctx context.Context, rowsGenerated map[string]int) {
+	var counters *stageCounters = p.i.stages[STAGE_BATCH]
+	var batch = make(rowBatch)
+	var features int
+
+	send := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+
+		select {
+		case p.batches <- batch:
+			batch = make(rowBatch)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for result := range p.split {
+		started := time.Now()
+
+		for _, r := range result.rows {
+			batch[r.key] = append(batch[r.key], r)
+		}
+		mergeRowsGenerated(result.rowsGenerated, rowsGenerated)
+
+		features++
+		counters.done(started)
+
+		if features%chunkSize == 0 && !send() {
+			return
+		}
+	}
+
+	if ctx.Err() == nil {
+		send()
+	}
+}
+
+func (p *pipeline) writeStage(ctx context.Context) error {
+	var counters *stageCounters = p.i.stages[STAGE_WRITE]
+
+	for batch := range p.batches {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		started := time.Now()
+
+		err := p.writer.write(batch)
+		if err != nil {
+			return err
+		}
+		counters.done(started)
+	}
+
+	return nil
+}
+
+// sampleQueues records how full the queue in front of each stage is
+func (p *pipeline) sampleQueues(ctx context.Context) {
+	var queues = map[string]func() int{
+		STAGE_DECODE: func() int { return len(p.features) },
+		STAGE_SPLIT:  func() int { return len(p.decoded) },
+		STAGE_BATCH:  func() int { return len(p.split) },
+		STAGE_WRITE:  func() int { return len(p.batches) },
+	}
+
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for stage, depth := range queues {
+				p.i.stages[stage].sample(depth())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBatchStage(t *testing.T) {
+	i := &Importer{
+		stages: newStageCounters(Config{}),
+	}
+
+	tests := map[string]struct {
+		features int
+		batches  int
+		expected map[string]int
+	}{
+		"single batch": {
+			features: 10,
+			batches:  1,
+			expected: map[string]int{"sd": 10},
+		},
+		"full batches": {
+			features: chunkSize * 2,
+			batches:  2,
+			expected: map[string]int{"sd": chunkSize * 2},
+		},
+		"remainder": {
+			features: chunkSize + 1,
+			batches:  2,
+			expected: map[string]int{"sd": chunkSize + 1},
+		},
+	}
+
+	for name, tt := range tests {
+		p := &pipeline{
+			i:       i,
+			split:   make(chan importResult, tt.features),
+			batches: make(chan rowBatch, tt.features),
+		}
+
+		for n := 0; n < tt.features; n++ {
+			p.split <- importResult{
+				rows:          []row{{key: "road.sd", gridRef: 1}},
+				rowsGenerated: map[string]int{"sd": 1},
+			}
+		}
+		close(p.split)
+
+		rowsGenerated := make(map[string]int)
+		p.batchStage(context.Background(), rowsGenerated)
+		close(p.batches)
+
+		var batches, rows int
+		for batch := range p.batches {
+			batches++
+			rows += len(batch["road.sd"])
+		}
+
+		if batches != tt.batches || rows != tt.features {
+			t.Errorf("%v: expected %v batches of %v rows, got %v of %v", name, tt.batches, tt.features, batches, rows)
+		}
+
+		if !reflect.DeepEqual(tt.expected, rowsGenerated) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, rowsGenerated)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -10,58 +11,120 @@ import (
 	"go-uk-maps-import/database"
 	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/database/engine/mysql"
+	"go-uk-maps-import/database/engine/pgsql"
 	"go-uk-maps-import/database/engine/sqlite"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/flatgeobuf"
 	"go-uk-maps-import/rates"
 	"go-uk-maps-import/sqlwriter"
 )
 
-func Run(start time.Time, config Config) error {
+// Run imports the configured sources, cancelling the context stops the
+// import between records
+func (i *Importer) Run(ctx context.Context) error {
 	var funcName string = "runner.Run"
 	var importStart time.Time = time.Now()
 
+	i.mu.Lock()
+	i.imported = nil
+	i.rateInfo = nil
+	i.ran = make(rates.History)
+	i.dedup = newDedupIndex()
+	i.attrs = newAttributeReport()
+	i.mu.Unlock()
+
+	// Remove the features held by the dedup index
+	defer i.dedup.close()
+
+	var config *Config = &i.config
+
 	if config.UseFiles {
 		// Start SQL Writer
-		sqlwriter.Start()
+		err := i.sqlWriter.Start()
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	// SQLite databases are built in memory so cannot be partially re-imported
+	if _, ok := config.DB.StorageEngine.(*sqlite.SQLite); ok && len(config.Squares) > 0 {
+		logger.Log(
+			logger.LVL_WARN,
+			"SQLite databases are rebuilt in full, importing all squares",
+		)
+		config.Squares = nil
+	}
+
+	if i.geoParquet != nil {
+		if len(config.Squares) > 0 {
+			logger.Log(
+				logger.LVL_WARN,
+				"The GeoParquet files will only hold the squares being re-imported",
+			)
+		}
+
+		err := i.geoParquet.Start()
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
+	// Clear the squares being re-imported and the squares around them
+	if len(config.Squares) > 0 {
+		config.Squares = ClearedSquares(config.Squares)
+
+		err := database.ClearSquares(config.DB.StorageEngine, config.Squares)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+
+		config.ShapeFiles = SelectSources(config.ShapeFiles, config.Squares)
+		config.NumShapeFiles = len(config.ShapeFiles)
+
+		logger.Log(
+			logger.LVL_APP,
+			fmt.Sprintf("Re-importing squares %v [%v files]", config.Squares, config.NumShapeFiles),
+		)
+		logger.Log(
+			logger.LVL_WARN,
+			"Features that cross tiles are not merged when re-importing squares",
+		)
 	}
 
 	// Do the import
-	rateInfo, err := doImport(config)
+	rateInfo, err := i.doImport(ctx)
+	if err != nil {
+		return fmt.Errorf("%v %v", funcName, err.Error())
+	}
+
+	// Features that cross tiles are written once every tile has been read
+	err = i.importHeld(ctx)
 	if err != nil {
 		return fmt.Errorf("%v %v", funcName, err.Error())
 	}
 
+	// Finish the GeoParquet files
+	if i.geoParquet != nil {
+		err = i.closeGeoParquet()
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
 	switch se := config.DB.StorageEngine.(type) {
-	case *mysql.MySQL:
+	case *mysql.MySQL, *pgsql.PgSQL:
 
 		if config.UseFiles {
 			// Stop SQL Writer
-			sqlwriter.Stop()
-
-			// SQL files that were generated
-			sqlFiles, err := database.GetGeneratedSQLFiles(sqlwriter.Folder)
+			err = i.sqlWriter.Stop()
 			if err != nil {
 				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
 
-			// Finish off the SQL files
-			err = sqlwriter.FinishSQLFiles(sqlFiles)
+			err = i.loadSQLFiles(config.SkipInserts)
 			if err != nil {
 				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
-
-			// Checksums
-			err = sqlwriter.CalculateChecksums(sqlFiles, config.ChecksumLog)
-			if err != nil {
-				return fmt.Errorf("%v %v", funcName, err.Error())
-			}
-
-			// Database Inserts
-			if !config.SkipInserts {
-				err = engine.DoInserts(config.DB.DBConfig, sqlFiles)
-				if err != nil {
-					return fmt.Errorf("%v %v", funcName, err.Error())
-				}
-			}
 		}
 
 	case *sqlite.SQLite:
@@ -72,64 +135,68 @@ func Run(start time.Time, config Config) error {
 			return fmt.Errorf("%v %v", funcName, err.Error())
 		}
 
-		// Why? ¯\_(ツ)_/¯
-		if config.UseFiles {
-			// Export the SQLite databases to .sql files
-			err = se.ExportToMySQLFiles()
+		// List the database files
+		rows, err := getLayerRows(se)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+
+		err = i.writeLayerManifest(sqlite.SQLiteStorageFolder, se.GetDatabasePath, rows)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+
+		if config.FlatGeobuf {
+			// Export the SQLite databases to .fgb files, a feature once
+			features, err := se.ExportToFlatGeobufFiles()
 			if err != nil {
 				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
 
-			// Stop SQL Writer
-			sqlwriter.Stop()
-
-			// Connect and prepare MySQL
-			e := &mysql.MySQL{
-				Config: mysql.MySQLConfig{
-					Host:    *config.DB.DBConfig.Host,
-					Port:    *config.DB.DBConfig.Port,
-					User:    *config.DB.DBConfig.User,
-					Pass:    *config.DB.DBConfig.Pass,
-					Schema:  *config.DB.DBConfig.Schema,
-					Timeout: *config.DB.DBConfig.Timeout,
-				},
+			// List the FlatGeobuf files
+			err = i.writeLayerManifest(flatgeobuf.DefaultFolder, se.GetFlatGeobufPath, features)
+			if err != nil {
+				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
-			err := e.Connect()
+		}
+
+		// Why? ¯\_(ツ)_/¯
+		if config.UseFiles {
+			// Export the SQLite databases to .sql files
+			err = se.ExportToSQLFiles(i.sqlWriter, i.dialect)
 			if err != nil {
 				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
-			err = e.Prepare()
+
+			// Stop SQL Writer
+			err = i.sqlWriter.Stop()
 			if err != nil {
 				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
 
-			if !config.SkipInserts {
-				// SQL files that were generated
-				sqlFiles, err := database.GetGeneratedSQLFiles(sqlwriter.Folder)
-				if err != nil {
-					return fmt.Errorf("%v %v", funcName, err.Error())
-				}
+			// SQLite files are for loading elsewhere, the databases are
+			// already written
+			var skipInserts bool = config.SkipInserts || i.dialect.Name() == types.DialectSQLite
 
-				// Finish off the SQL files
-				err = sqlwriter.FinishSQLFiles(sqlFiles)
-				if err != nil {
-					return fmt.Errorf("%v %v", funcName, err.Error())
+			if !skipInserts {
+				// Connect and prepare the database of the dialect
+				var dialect string = i.dialect.Name()
+				target := engine.SEConfig{
+					Engine:   &dialect,
+					DBConfig: config.DB.DBConfig,
+					Schema:   i.schema,
 				}
 
-				// Checksums
-				err = sqlwriter.CalculateChecksums(sqlFiles, config.ChecksumLog)
+				err = engine.Startup(false, &target)
 				if err != nil {
 					return fmt.Errorf("%v %v", funcName, err.Error())
 				}
+				defer engine.Shutdown(false, target) //nolint:errcheck
+			}
 
-				// Database Inserts
-				if !config.SkipInserts {
-					// Insert Into MySQL
-					err = engine.DoInserts(config.DB.DBConfig, sqlFiles)
-					if err != nil {
-						return fmt.Errorf("%v %v", funcName, err.Error())
-					}
-				}
+			err = i.loadSQLFiles(skipInserts)
+			if err != nil {
+				return fmt.Errorf("%v %v", funcName, err.Error())
 			}
 		}
 	}
@@ -140,6 +207,24 @@ func Run(start time.Time, config Config) error {
 	// Count the number of rows generated
 	rates.LogRowsGenerated(config.TimingsLog, rateInfo)
 
+	// Pipeline stage throughput
+	i.logStats(config.TimingsLog)
+
+	// Geometry QA report
+	if config.QALog != nil {
+		i.qa.log(config.QALog)
+	}
+
+	// Invalid attribute report
+	if config.AttributeLog != nil {
+		i.attrs.log(config.AttributeLog)
+	}
+
+	// Duplicate feature report
+	if config.DedupLog != nil {
+		i.dedup.log(config.DedupLog)
+	}
+
 	// Run Row Counts Checks
 	database.LogRowCountChecks(config.DB.StorageEngine, config.DataFolder, rateInfo)
 
@@ -153,6 +238,14 @@ func Run(start time.Time, config Config) error {
 	var duration time.Duration = time.Since(importStart)
 	rates.LogActualRate(config.TimingsLog, rateInfo, duration)
 
+	// Remember the rates for scheduling and estimating the next run
+	if config.StateDir != "" {
+		err := rates.SaveHistory(config.StateDir, i.ran)
+		if err != nil {
+			return fmt.Errorf("%v %v", funcName, err.Error())
+		}
+	}
+
 	// Log import time
 	var took time.Duration = timeutils.Took(importStart)
 	logger.Log(
@@ -162,3 +255,44 @@ func Run(start time.Time, config Config) error {
 
 	return nil
 }
+
+// loadSQLFiles logs the checksums of the generated SQL files, lists them in
+// a manifest and loads them with the loader of their dialect
+func (i *Importer) loadSQLFiles(skipInserts bool) error {
+	var funcName string = "importer.loadSQLFiles"
+
+	// SQL files that were generated
+	sqlFiles, err := database.GetGeneratedSQLFiles(i.sqlWriter.Folder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	// Checksums
+	err = sqlwriter.CalculateChecksums(sqlFiles, i.config.ChecksumLog)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	// List the SQL files
+	err = i.writeSQLManifest(sqlFiles)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if skipInserts {
+		return nil
+	}
+
+	// Database Inserts
+	loader, err := engine.GetLoader(i.dialect.Name(), i.config.DB.DBConfig)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = engine.DoInserts(loader, sqlFiles)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-utils/fileutils"
+
+	"go-uk-maps-import/rates"
+	"go-uk-maps-import/shpinfo"
+)
+
+const (
+	// Used for layers that have not been imported before
+	defaultRecordsPerSecond = 2000
+
+	// The queues in front of the decode, split and batch stages
+	pipelineQueues = 3
+)
+
+// sourceEstimate is the predicted work of importing a single source
+type sourceEstimate struct {
+	source   string
+	layer    string
+	records  int
+	bytes    int64
+	duration time.Duration
+}
+
+// Estimate is the predicted cost of an import
+type Estimate struct {
+	Sources    int
+	Records    int
+	Workers    int
+	Duration   time.Duration
+	PeakMemory int64
+	OutputSize int64
+}
+
+func (e Estimate) String() string {
+	return fmt.Sprintf("\t\t"+"Sources: %v"+"\n"+
+		"\t\t"+"Records: %v"+"\n"+
+		"\t\t"+"Workers: %v"+"\n"+
+		"\t\t"+"Duration: %v"+"\n"+
+		"\t\t"+"PeakMemory: %v"+"\n"+
+		"\t\t"+"OutputSize: %v",
+		e.Sources,
+		e.Records,
+		e.Workers,
+		e.Duration.Round(time.Second),
+		fmt.Sprintf("%.2f%v", fileutils.ByteSizeConvert(e.PeakMemory, "gb"), "gb"),
+		fmt.Sprintf("%.2f%v", fileutils.ByteSizeConvert(e.OutputSize, "gb"), "gb"),
+	)
+}
+
+// EstimateImport predicts the duration, peak memory and output size of an
+// import from the historical rates in the state folder, fallbackRate is the
+// records per second used for layers without any history
+func EstimateImport(config Config, fallbackRate float64) (Estimate, error) {
+	var funcName string = "importer.EstimateImport"
+
+	var history = make(rates.History)
+	if config.StateDir != "" {
+		var err error
+		history, err = rates.LoadHistory(config.StateDir)
+		if err != nil {
+			return Estimate{}, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	estimates, err := estimateSources(config, config.ShapeFiles, history, fallbackRate)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var workers int = getFileWorkers(config, len(estimates))
+
+	var e = Estimate{
+		Sources:  len(estimates),
+		Workers:  workers,
+		Duration: getMakespan(estimates, workers),
+	}
+
+	var totalBytes int64
+	for _, s := range estimates {
+		e.Records += s.records
+		totalBytes += s.bytes
+
+		var rowsPerRecord float64 = history[s.layer].RowsPerRecord()
+		if rowsPerRecord == 0 {
+			rowsPerRecord = 1
+		}
+		e.OutputSize += int64(float64(s.bytes) * rowsPerRecord)
+	}
+
+	// The largest sources are started first so are in memory together
+	if !config.LowMemory {
+		for n := 0; n < workers && n < len(estimates); n++ {
+			e.PeakMemory += estimates[n].bytes
+		}
+	} else if e.Records > 0 {
+		var recordBytes int64 = totalBytes / int64(e.Records)
+		e.PeakMemory = int64(workers*config.QueueSize*pipelineQueues) * recordBytes
+	}
+
+	return e, nil
+}
+
+// estimateSources returns the sources largest first, ordered by their
+// predicted duration then size
+func estimateSources(config Config, sources []string, history rates.History, fallbackRate float64) ([]sourceEstimate, error) {
+	if fallbackRate <= 0 {
+		fallbackRate = defaultRecordsPerSecond
+	}
+
+	var estimates []sourceEstimate
+	for _, source := range sources {
+		layer := getLayerName(config, getSourceShortName(source))
+
+		records, err := getRecordCount(source)
+		if err != nil {
+			return nil, err
+		}
+
+		s := sourceEstimate{
+			source:  source,
+			layer:   layer,
+			records: records,
+			bytes:   getSourceBytes(source),
+		}
+
+		var rate float64 = history[layer].RecordsPerSecond()
+		if rate == 0 {
+			rate = fallbackRate
+		}
+		s.duration = time.Duration(float64(s.records) / rate * float64(time.Second))
+
+		estimates = append(estimates, s)
+	}
+
+	sort.SliceStable(estimates, func(i, j int) bool {
+		if estimates[i].duration != estimates[j].duration {
+			return estimates[i].duration > estimates[j].duration
+		}
+		return estimates[i].bytes > estimates[j].bytes
+	})
+
+	return estimates, nil
+}
+
+// getMakespan assigns each source in turn to the least loaded worker and
+// returns the time the last worker finishes
+func getMakespan(estimates []sourceEstimate, workers int) time.Duration {
+	if workers < 1 {
+		return 0
+	}
+
+	var loads = make([]time.Duration, workers)
+	for _, s := range estimates {
+		least := 0
+		for n := range loads {
+			if loads[n] < loads[least] {
+				least = n
+			}
+		}
+		loads[least] += s.duration
+	}
+
+	var makespan time.Duration
+	for _, load := range loads {
+		if load > makespan {
+			makespan = load
+		}
+	}
+
+	return makespan
+}
+
+// getFileWorkers is the number of sources imported at once
+func getFileWorkers(config Config, sources int) int {
+	switch {
+	case !config.Concurrent:
+		return 1
+	case config.Unlimited || config.FileWorkers <= 0 || config.FileWorkers > sources:
+		return sources
+	}
+
+	return config.FileWorkers
+}
+
+// getSourceBytes is the size of the .shp and .dbf read for a shapefile, a
+// GeoPackage layer is read through SQLite so is not counted
+func getSourceBytes(source string) int64 {
+	if isGeoPackageLayer(source) {
+		return 0
+	}
+
+	var size int64
+	for _, file := range []string{source, shpinfo.SiblingPath(source, shpinfo.ExtDbf)} {
+		info, err := os.Stat(file)
+		if err == nil {
+			size += info.Size()
+		}
+	}
+
+	return size
+}
+
+// schedule orders the sources so the biggest and slowest are started first,
+// which shortens the import when the sources are shared between workers
+func (i *Importer) schedule(sources []string) ([]string, error) {
+	estimates, err := estimateSources(i.config, sources, i.history, defaultRecordsPerSecond)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []string
+	for _, s := range estimates {
+		scheduled = append(scheduled, s.source)
+	}
+
+	logger.Log(
+		logger.LVL_INTERNAL,
+		fmt.Sprintf("Scheduled %v\n", scheduled),
+	)
+
+	return scheduled, nil
+}
@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-uk-maps-import/rates"
+	"go-uk-maps-import/shpinfo"
+)
+
+func TestGetMakespan(t *testing.T) {
+	estimates := func(durations ...time.Duration) []sourceEstimate {
+		var e []sourceEstimate
+		for _, d := range durations {
+			e = append(e, sourceEstimate{duration: d})
+		}
+		return e
+	}
+
+	tests := map[string]struct {
+		estimates []sourceEstimate
+		workers   int
+		expected  time.Duration
+	}{
+		"sequential": {
+			estimates: estimates(3*time.Second, 2*time.Second, time.Second),
+			workers:   1,
+			expected:  6 * time.Second,
+		},
+		"largest first": {
+			estimates: estimates(4*time.Second, 3*time.Second, 2*time.Second, 2*time.Second, time.Second),
+			workers:   2,
+			expected:  6 * time.Second,
+		},
+		"one each": {
+			estimates: estimates(5*time.Second, time.Second),
+			workers:   2,
+			expected:  5 * time.Second,
+		},
+		"no workers": {
+			estimates: estimates(time.Second),
+			workers:   0,
+			expected:  0,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := getMakespan(tt.estimates, tt.workers)
+		if actual != tt.expected {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestGetFileWorkers(t *testing.T) {
+	tests := map[string]struct {
+		config   Config
+		expected int
+	}{
+		"sequential": {
+			config:   Config{FileWorkers: 4},
+			expected: 1,
+		},
+		"limited": {
+			config:   Config{Concurrent: true, FileWorkers: 4},
+			expected: 4,
+		},
+		"no limit": {
+			config:   Config{Concurrent: true},
+			expected: 10,
+		},
+		"unlimited": {
+			config:   Config{Concurrent: true, Unlimited: true, FileWorkers: 4},
+			expected: 10,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := getFileWorkers(tt.config, 10)
+		if actual != tt.expected {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestDoImportOrder(t *testing.T) {
+	// The largest source is in the last folder
+	dataFolder := t.TempDir()
+	for folder, layers := range map[string][]string{
+		"HP": {"HP_AdministrativeBoundary", "HP_Woodland"},
+		"SD": {"SD_MotorwayJunction"},
+	} {
+		for _, layer := range layers {
+			copyShapefile(t, "../testdata/"+layer+".shp", filepath.Join(dataFolder, folder))
+		}
+	}
+
+	shapeFiles, err := GetAllShapefiles(dataFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		config Config
+	}{
+		"sequential": {
+			config: Config{},
+		},
+		"concurrent": {
+			config: Config{Concurrent: true, FileWorkers: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		tt.config.DataFolder = dataFolder
+		tt.config.ShapeFiles = shapeFiles
+		tt.config.NumShapeFiles = len(shapeFiles)
+
+		var imported []string
+		i := &Importer{
+			config:  tt.config,
+			history: make(rates.History),
+			importSource: func(ctx context.Context, source string) error {
+				imported = append(imported, filepath.Base(source))
+				return nil
+			},
+		}
+
+		_, err := i.doImport(context.Background())
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		expected := []string{"SD_MotorwayJunction.shp", "HP_AdministrativeBoundary.shp", "HP_Woodland.shp"}
+		if !reflect.DeepEqual(expected, imported) {
+			t.Errorf("%v: expected %v, got %v", name, expected, imported)
+		}
+	}
+}
+
+func TestDoImportFailure(t *testing.T) {
+	dataFolder := t.TempDir()
+	for _, layer := range []string{"HP_AdministrativeBoundary", "HP_Woodland", "SD_MotorwayJunction"} {
+		copyShapefile(t, "../testdata/"+layer+".shp", dataFolder)
+	}
+
+	shapeFiles, err := GetAllShapefiles(dataFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var imported []string
+	i := &Importer{
+		config: Config{
+			DataFolder:    dataFolder,
+			ShapeFiles:    shapeFiles,
+			NumShapeFiles: len(shapeFiles),
+			Concurrent:    true,
+			FileWorkers:   2,
+		},
+		history: make(rates.History),
+		importSource: func(ctx context.Context, source string) error {
+			if filepath.Base(source) == "HP_Woodland.shp" {
+				return errors.New("disk full")
+			}
+
+			mu.Lock()
+			imported = append(imported, filepath.Base(source))
+			mu.Unlock()
+
+			return nil
+		},
+	}
+
+	// The other sources are imported but the import fails
+	_, err = i.doImport(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("expected the error of the failed source, got %v", err)
+	}
+
+	sort.Strings(imported)
+	expected := []string{"HP_AdministrativeBoundary.shp", "SD_MotorwayJunction.shp"}
+	if !reflect.DeepEqual(expected, imported) {
+		t.Errorf("expected %v, got %v", expected, imported)
+	}
+}
+
+func copyShapefile(t *testing.T, shapeFile, folder string) {
+	err := os.MkdirAll(folder, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ext := range []string{shpinfo.ExtShp, shpinfo.ExtShx, shpinfo.ExtDbf, shpinfo.ExtPrj} {
+		source := shpinfo.SiblingPath(shapeFile, ext)
+
+		b, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(filepath.Join(folder, filepath.Base(source)), b, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-uk-maps-import/shpinfo"
+)
+
+const (
+	defaultSegmentWorkers = 4
+	defaultSegmentRecords = 100000
+
+	// Each .shp record starts with its number and content length
+	shpRecordHeaderSize = 8
+
+	// The first byte of a deleted .dbf record
+	dbfDeletedFlag = '*'
+)
+
+// segmentedShapefile reads the records of a shapefile at the offsets in its
+// .shx index, so several workers can read one large shapefile at once
+type segmentedShapefile struct {
+	shp     recordFile
+	dbf     recordFile
+	offsets []shpinfo.Offset
+	header  shpinfo.DBFHeader
+}
+
+// recordFile is a .shp or .dbf, either open or read into memory
+type recordFile interface {
+	io.ReaderAt
+	io.Closer
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (m memoryFile) Close() error {
+	return nil
+}
+
+// useSegments reports whether a source is a shapefile large enough to be
+// read by more than one worker
+func useSegments(config Config, source string) bool {
+	if isGeoPackageLayer(source) || config.SegmentWorkers < 2 {
+		return false
+	}
+
+	count, err := shpinfo.ShxRecordCount(shpinfo.SiblingPath(source, shpinfo.ExtShx))
+	if err != nil {
+		return false
+	}
+
+	return count >= config.SegmentRecords
+}
+
+func openSegmentedShapefile(shapeFile string) (*segmentedShapefile, error) {
+	var funcName string = "importer.openSegmentedShapefile"
+
+	offsets, err := shpinfo.ReadShx(shpinfo.SiblingPath(shapeFile, shpinfo.ExtShx))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	header, err := shpinfo.ReadDBFHeader(shpinfo.SiblingPath(shapeFile, shpinfo.ExtDbf))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if header.Records != len(offsets) {
+		return nil, fmt.Errorf("%v: %v has %v shapes but %v attribute records", funcName, shapeFile, len(offsets), header.Records)
+	}
+
+	shp, err := os.Open(shapeFile)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	dbf, err := os.Open(shpinfo.SiblingPath(shapeFile, shpinfo.ExtDbf))
+	if err != nil {
+		shp.Close()
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return &segmentedShapefile{
+		shp:     shp,
+		dbf:     dbf,
+		offsets: offsets,
+		header:  header,
+	}, nil
+}
+
+// toMemory reads the .shp and .dbf into memory and closes them
+func (s *segmentedShapefile) toMemory() error {
+	var funcName string = "importer.segmentedShapefile.toMemory"
+
+	for _, f := range []*recordFile{&s.shp, &s.dbf} {
+		file, ok := (*f).(*os.File)
+		if !ok {
+			continue
+		}
+
+		b, err := os.ReadFile(file.Name())
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		file.Close()
+		*f = memoryFile{bytes.NewReader(b)}
+	}
+
+	return nil
+}
+
+func (s *segmentedShapefile) records() int {
+	return len(s.offsets)
+}
+
+// record reads the nth record, it is safe to call from several goroutines,
+// a deleted record is returned as a nil insert
+func (s *segmentedShapefile) record(n int) (insert, wkbFunc, error) {
+	var funcName string = "importer.segmentedShapefile.record"
+
+	var offset shpinfo.Offset = s.offsets[n]
+
+	attrs := make([]byte, s.header.RecordLength)
+	_, err := s.dbf.ReadAt(attrs, int64(s.header.HeaderLength+n*s.header.RecordLength))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: attributes %v [%v]", funcName, n, err.Error())
+	}
+
+	if attrs[0] == dbfDeletedFlag {
+		return nil, nil, nil
+	}
+
+	shape := make([]byte, offset.Length)
+	_, err = s.shp.ReadAt(shape, offset.Offset+shpRecordHeaderSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: shape %v [%v]", funcName, n, err.Error())
+	}
+
+	toWKB := func() ([]byte, error) {
+		b, err := shpRecordToWKB(shape)
+		if err != nil {
+			return nil, fmt.Errorf("%v: shape %v [%v]", funcName, n, err.Error())
+		}
+
+		return b, nil
+	}
+
+	return s.getInsert(attrs), toWKB, nil
+}
+
+func (s *segmentedShapefile) getInsert(attrs []byte) insert {
+	var record insert = insert{}
+
+	// Skip the deleted flag
+	var pos int = 1
+	for _, field := range s.header.Fields {
+		end := pos + field.Length
+		if end > len(attrs) {
+			end = len(attrs)
+		}
+
+		value := strings.Trim(string(attrs[pos:end]), " \x00")
+		record[field.Name] = fixAttr(field.Name, value)
+		pos = end
+	}
+
+	return record
+}
+
+// read sends every record to out from a worker per segment, recorded is
+// called once each record has been queued
+func (s *segmentedShapefile) read(parent context.Context, workers int, counters *stageCounters, out chan<- importAction, recorded func()) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+
+	for _, segment := range getSegments(s.records(), workers) {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+
+			for n := start; n < end; n++ {
+				readStarted := time.Now()
+
+				rec, toWKB, err := s.record(n)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				counters.done(readStarted)
+
+				if rec == nil {
+					continue
+				}
+
+				select {
+				case out <- importAction{insert: rec, toWKB: toWKB}:
+				case <-ctx.Done():
+					return
+				}
+
+				recorded()
+			}
+		}(segment[0], segment[1])
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+func (s *segmentedShapefile) Close() error {
+	s.dbf.Close()
+	return s.shp.Close()
+}
+
+// getSegments splits a record range into contiguous [start, end) segments
+func getSegments(records, workers int) [][2]int {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > records {
+		workers = records
+	}
+
+	var segments [][2]int
+	var start int
+	for n := 0; n < workers; n++ {
+		end := start + records/workers
+		if n < records%workers {
+			end++
+		}
+
+		segments = append(segments, [2]int{start, end})
+		start = end
+	}
+
+	return segments
+}
@@ -0,0 +1,299 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go-uk-maps-import/shpinfo"
+)
+
+func TestGetSegments(t *testing.T) {
+	tests := map[string]struct {
+		records  int
+		workers  int
+		expected [][2]int
+	}{
+		"even": {
+			records:  8,
+			workers:  2,
+			expected: [][2]int{{0, 4}, {4, 8}},
+		},
+		"remainder": {
+			records:  7,
+			workers:  3,
+			expected: [][2]int{{0, 3}, {3, 5}, {5, 7}},
+		},
+		"more workers than records": {
+			records:  2,
+			workers:  4,
+			expected: [][2]int{{0, 1}, {1, 2}},
+		},
+	}
+
+	for name, tt := range tests {
+		actual := getSegments(tt.records, tt.workers)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestShpRecordToWKB(t *testing.T) {
+	square := func(clockwise bool, x float64) []float64 {
+		if clockwise {
+			return []float64{x, 0, x, 1, x + 1, 1, x + 1, 0, x, 0}
+		}
+		return []float64{x, 0, x + 1, 0, x + 1, 1, x, 1, x, 0}
+	}
+
+	tests := map[string]struct {
+		content  []byte
+		geomType uint32
+		parts    uint32
+	}{
+		"point": {
+			content:  shpContent(shpPoint, nil, []float64{1, 2}),
+			geomType: wkbPoint,
+		},
+		"pointz": {
+			content:  shpContent(shpPoint+shpZOffset, nil, []float64{1, 2, 3, 4}),
+			geomType: wkbPoint,
+		},
+		"line": {
+			content:  shpContent(shpPolyLine, []int{0}, []float64{0, 0, 1, 1}),
+			geomType: wkbLineString,
+			parts:    2,
+		},
+		"multiline": {
+			content:  shpContent(shpPolyLine, []int{0, 2}, []float64{0, 0, 1, 1, 2, 2, 3, 3}),
+			geomType: wkbMultiLineString,
+			parts:    2,
+		},
+		"polygon with hole": {
+			content:  shpContent(shpPolygon, []int{0, 5}, append(square(true, 0), square(false, 0)...)),
+			geomType: wkbPolygon,
+			parts:    2,
+		},
+		"multipolygon": {
+			content:  shpContent(shpPolygon, []int{0, 5}, append(square(true, 0), square(true, 5)...)),
+			geomType: wkbMultiPolygon,
+			parts:    2,
+		},
+	}
+
+	for name, tt := range tests {
+		b, err := shpRecordToWKB(tt.content)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		if geomType := binary.LittleEndian.Uint32(b[1:5]); geomType != tt.geomType {
+			t.Errorf("%v: expected type %v, got %v", name, tt.geomType, geomType)
+		}
+
+		if tt.parts > 0 {
+			if parts := binary.LittleEndian.Uint32(b[5:9]); parts != tt.parts {
+				t.Errorf("%v: expected %v parts, got %v", name, tt.parts, parts)
+			}
+		}
+	}
+}
+
+func TestSegmentedShapefile(t *testing.T) {
+	s, err := openSegmentedShapefile("./testdata/SD_MotorwayJunction.shp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var out = make(chan importAction, s.records())
+	var mu sync.Mutex
+	var recorded int
+
+	err = s.read(context.Background(), 3, &stageCounters{}, out, func() {
+		mu.Lock()
+		recorded++
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+
+	var ids = make(map[interface{}]bool)
+	for action := range out {
+		ids[action.insert["ID"]] = true
+
+		b, err := action.toWKB()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if geomType := binary.LittleEndian.Uint32(b[1:5]); geomType != wkbPoint {
+			t.Errorf("expected a point, got type %v", geomType)
+		}
+	}
+
+	if recorded != 64 || len(ids) != 64 {
+		t.Errorf("expected 64 records, got %v recorded and %v ids", recorded, len(ids))
+	}
+}
+
+func TestSegmentedShapefileDeleted(t *testing.T) {
+	dir := t.TempDir()
+	for _, ext := range []string{shpinfo.ExtShp, shpinfo.ExtShx, shpinfo.ExtDbf} {
+		b, err := os.ReadFile(shpinfo.SiblingPath("./testdata/SD_MotorwayJunction.shp", ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, "SD_MotorwayJunction"+ext), b, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shapeFile := filepath.Join(dir, "SD_MotorwayJunction.shp")
+	dbfFile := shpinfo.SiblingPath(shapeFile, shpinfo.ExtDbf)
+
+	header, err := shpinfo.ReadDBFHeader(dbfFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flag the first record as deleted
+	f, err := os.OpenFile(dbfFile, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.WriteAt([]byte{dbfDeletedFlag}, int64(header.HeaderLength))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := openSegmentedShapefile(shapeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	deleted, _, err := s.record(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != nil {
+		t.Errorf("expected the deleted record to be skipped, got %v", deleted)
+	}
+
+	var out = make(chan importAction, s.records())
+	var mu sync.Mutex
+	var recorded int
+
+	err = s.read(context.Background(), 2, &stageCounters{}, out, func() {
+		mu.Lock()
+		recorded++
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(out)
+
+	if len(out) != 63 || recorded != 63 {
+		t.Errorf("expected 63 records, got %v queued and %v recorded", len(out), recorded)
+	}
+}
+
+// TestSegmentedParity checks a shapefile read in order from memory yields the
+// same records as the segmented reader reading the files
+func TestSegmentedParity(t *testing.T) {
+	var shapeFiles []string
+	for _, dataFolder := range []string{"./testdata", "../testdata"} {
+		found, err := GetAllShapefiles(dataFolder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shapeFiles = append(shapeFiles, found...)
+	}
+
+	for _, shapeFile := range shapeFiles {
+		t.Run(filepath.Base(shapeFile), func(t *testing.T) {
+			s, err := openSegmentedShapefile(shapeFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			_, r, err := openSource(Config{}, shapeFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			var n int
+			for {
+				expected, expectedWKB, err := r.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				actual, actualWKB, err := s.record(n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				n++
+
+				if !reflect.DeepEqual(expected, actual) {
+					t.Fatalf("record %v: expected %v, got %v", n, expected, actual)
+				}
+
+				if !bytes.Equal(getWKB(t, expectedWKB), getWKB(t, actualWKB)) {
+					t.Errorf("record %v: the geometries differ", n)
+				}
+			}
+
+			if n != s.records() {
+				t.Errorf("expected %v records, got %v", s.records(), n)
+			}
+		})
+	}
+}
+
+func getWKB(t *testing.T, toWKB wkbFunc) []byte {
+	wkb, err := toWKB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return wkb
+}
+
+// shpContent builds the content of a .shp record, parts is nil for a point
+func shpContent(shapeType int, parts []int, coords []float64) []byte {
+	var w = &bytes.Buffer{}
+	binary.Write(w, binary.LittleEndian, int32(shapeType)) //nolint:errcheck
+
+	if parts != nil {
+		w.Write(make([]byte, shpBboxSize))
+		binary.Write(w, binary.LittleEndian, int32(len(parts)))    //nolint:errcheck
+		binary.Write(w, binary.LittleEndian, int32(len(coords)/2)) //nolint:errcheck
+		for _, part := range parts {
+			binary.Write(w, binary.LittleEndian, int32(part)) //nolint:errcheck
+		}
+	}
+
+	binary.Write(w, binary.LittleEndian, coords) //nolint:errcheck
+
+	return w.Bytes()
+}
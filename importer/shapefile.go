@@ -1,10 +1,12 @@
 package importer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"sync"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -12,14 +14,8 @@ import (
 	"github.com/rockwell-uk/go-logger/logger"
 	"github.com/rockwell-uk/go-progress/progress"
 	"github.com/rockwell-uk/go-utils/stringutils"
-	"github.com/rockwell-uk/shapefile"
-	"github.com/rockwell-uk/shapefile/dbf"
-	"github.com/rockwell-uk/shapefile/shp"
 	"github.com/rockwell-uk/uiprogress"
-	"github.com/twpayne/go-geos"
 
-	"go-uk-maps-import/database"
-	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/database/types"
 	"go-uk-maps-import/filelogger"
 	"go-uk-maps-import/osdata"
@@ -27,22 +23,26 @@ import (
 )
 
 const (
-	chunkSize     = 500
-	ACTION_CHECK  = "CHECK"
-	ACTION_INSERT = "INSERT"
-	ACTION_DONE   = "DONE"
+	chunkSize = 500
 )
 
+// importResult is the rows a feature generates and their count per square
 type importResult struct {
+	rows          []row
 	rowsGenerated map[string]int
 }
 
+// importAction is a feature read from a source, the geometry of a feature
+// read from a source file is converted to WKB by the decode stage
 type importAction struct {
-	action string
 	insert insert
-	shape  shp.Shape
+	wkb    []byte
+	toWKB  wkbFunc
 }
 
+// wkbFunc converts a geometry as it was read from its source to WKB
+type wkbFunc func() ([]byte, error)
+
 type fieldName struct {
 	fieldNames   string
 	placeHolders string
@@ -52,10 +52,11 @@ func (m fieldName) String() string {
 	return fmt.Sprintf("\n\tfieldNames:%v\n\tplaceHolders:%v\n", m.fieldNames, m.placeHolders)
 }
 
-var dbFieldsMap = make(map[string]fieldName)
+// buildDBFieldsMap builds the insert field lists of the layers of a schema
+func buildDBFieldsMap(schema *types.Schema) map[string]fieldName {
+	var dbFieldsMap = make(map[string]fieldName)
 
-func init() {
-	for layerType, allFieldNames := range types.MapLayers {
+	for layerType, allFieldNames := range schema.MapLayers {
 		var fieldNames string
 		var placeHolders string
 
@@ -72,34 +73,49 @@ func init() {
 			placeHolders,
 		}
 	}
+
+	return dbFieldsMap
 }
 
-func doImportShapefile(config Config, shapeFile, sfShortName string) (int, map[string]int, time.Duration, error) {
+func (i *Importer) doImportShapefile(ctx context.Context, shapeFile, sfShortName string) (int, map[string]int, time.Duration, error) {
 	var funcName string = "importer.doImportShapefile"
 	var jobName string = "Importing shapefile"
 
+	var config Config = i.config
+
 	var importStarted = time.Now()
 	var sfRowsGenerated = make(map[string]int)
 	var recordsInFile uint32
 	var recordsProcessed int
 
-	// One context per shapefile
-	var gctx *geos.Context = geos.NewContext()
-
 	logger.Log(
 		logger.LVL_DEBUG,
 		fmt.Sprintf("%v [%v]\n", jobName, sfShortName),
 	)
 
-	var dbName = database.GetDBNameFromFilename(shapeFile)
+	var dbName = getLayerName(config, sfShortName)
 	var rateInterval = 10000
 	var barInterval = 1000
 
-	var r *shapefile.Reader
-	if !config.LowMemory {
-		recordsInFile, r = shapefile.ReadShapeFileToMemory(shapeFile)
+	// Large shapefiles are read by a worker per segment of the .shx index
+	var segmented *segmentedShapefile
+	var r sourceReader
+	var err error
+
+	if useSegments(config, shapeFile) {
+		segmented, err = openSegmentedShapefile(shapeFile)
+		if err != nil {
+			return recordsProcessed, sfRowsGenerated, time.Since(importStarted), fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		defer segmented.Close()
+
+		recordsInFile = uint32(segmented.records())
 	} else {
-		recordsInFile, r = shapefile.ReadShapeFile(shapeFile)
+		recordsInFile, r, err = openSource(config, shapeFile)
+		if err != nil {
+			return recordsProcessed, sfRowsGenerated, time.Since(importStarted), fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		defer r.Close()
 	}
 
 	logger.Log(
@@ -107,35 +123,6 @@ func doImportShapefile(config Config, shapeFile, sfShortName string) (int, map[s
 		fmt.Sprintf("Records In File %v, [%v]\n", sfShortName, recordsInFile),
 	)
 
-	ops := make(chan importAction)
-	res := make(chan importResult, 1)
-
-	go func() {
-		for f := range ops {
-			switch f.action {
-			case ACTION_INSERT:
-				if config.UseFiles && *config.DB.Engine == engine.EngineMySQL {
-					res <- importToFile(gctx, f, dbName)
-				} else {
-					res <- importDirect(gctx, f, dbName, sfShortName)
-				}
-			case ACTION_CHECK:
-				if !config.UseFiles || *config.DB.Engine == engine.EngineSQLite {
-					if recordsProcessed > 0 && recordsProcessed%chunkSize == 0 {
-						runInserts(config.DB.StorageEngine, sfShortName)
-					}
-				}
-				res <- importResult{}
-			case ACTION_DONE:
-				if !config.UseFiles || *config.DB.Engine == engine.EngineSQLite {
-					runInserts(config.DB.StorageEngine, sfShortName)
-				}
-				res <- importResult{}
-				return
-			}
-		}
-	}()
-
 	var magnitude int = int(math.Ceil(float64(recordsInFile) / float64(barInterval)))
 	var wg *waitgroup.WaitGroup = waitgroup.New()
 
@@ -177,22 +164,16 @@ func doImportShapefile(config Config, shapeFile, sfShortName string) (int, map[s
 		task.Start()
 	}
 
-	for {
-		rec, err := r.Next()
-
-		var shouldLogRate bool = recordsProcessed > 0 && recordsProcessed%rateInterval == 0
-		var shouldUpdateBar bool = recordsProcessed > 0 && recordsProcessed%barInterval == 0
+	// recorded is called once a record has been queued, the segment
+	// workers share the count, rate log and progress bar
+	var mu sync.Mutex
+	recorded := func() {
+		mu.Lock()
+		defer mu.Unlock()
 
-		if err == nil {
-			if recordsProcessed > 0 {
-				ops <- importAction{
-					action: ACTION_CHECK,
-				}
-				<-res
-			}
-		}
+		recordsProcessed++
 
-		if shouldLogRate {
+		if recordsProcessed%rateInterval == 0 {
 			rate, rateLogLine := logRate(sfShortName, importStarted, recordsProcessed)
 			if rate > 0 {
 				logger.Log(
@@ -202,7 +183,7 @@ func doImportShapefile(config Config, shapeFile, sfShortName string) (int, map[s
 			}
 		}
 
-		if showBar && shouldUpdateBar {
+		if showBar && recordsProcessed%barInterval == 0 {
 			if currentChunk == 0 {
 				task.End()
 			}
@@ -221,53 +202,77 @@ func doImportShapefile(config Config, shapeFile, sfShortName string) (int, map[s
 
 			wg.Wait()
 		}
+	}
 
-		if errors.Is(err, io.EOF) {
-			if showBar {
-				wg.Add(1)
+	finished := func() {
+		if showBar {
+			wg.Add(1)
 
-				go func(j *progress.Job, t *progress.Task) {
-					t.End()
-					j.UpdateBar()
-					wg.Done()
-				}(job, task)
+			go func(j *progress.Job, t *progress.Task) {
+				t.End()
+				j.UpdateBar()
+				wg.Done()
+			}(job, task)
 
-				wg.Wait()
-			}
+			wg.Wait()
+		}
+	}
+
+	// The read stage of the pipeline
+	read := func(ctx context.Context, out chan<- importAction) error {
+		var counters *stageCounters = i.stages[STAGE_READ]
 
-			ops <- importAction{
-				action: ACTION_DONE,
+		if segmented != nil {
+			err := segmented.read(ctx, config.SegmentWorkers, counters, out, recorded)
+			if err != nil {
+				return err
 			}
-			<-res
-
-			_, rateLogLine := logRate(sfShortName, importStarted, recordsProcessed)
-			filelogger.Log(
-				filelogger.LogLine{
-					File: config.TimingsLog,
-					Line: rateLogLine,
-				},
-			)
-
-			return recordsProcessed, sfRowsGenerated, time.Since(importStarted), nil
-		} else if err != nil {
-			logger.Log(
-				logger.LVL_FATAL,
-				err.Error(),
-			)
-			return recordsProcessed, sfRowsGenerated, time.Since(importStarted), fmt.Errorf("%v: %v", funcName, err.Error())
-		}
 
-		ops <- importAction{
-			action: ACTION_INSERT,
-			insert: getInsert(rec, r.Fields()),
-			shape:  rec.Shape,
+			finished()
+			return nil
 		}
-		result := <-res
 
-		sfRowsGenerated = mergeRowsGenerated(result.rowsGenerated, sfRowsGenerated)
+		for {
+			readStarted := time.Now()
+			rec, toWKB, err := r.Next()
 
-		recordsProcessed++
+			if errors.Is(err, io.EOF) {
+				finished()
+				return nil
+			} else if err != nil {
+				logger.Log(
+					logger.LVL_FATAL,
+					err.Error(),
+				)
+				return err
+			}
+
+			counters.done(readStarted)
+
+			select {
+			case out <- importAction{insert: rec, toWKB: toWKB}:
+			case <-ctx.Done():
+				return nil
+			}
+
+			recorded()
+		}
+	}
+
+	sfRowsGenerated, err = i.newPipeline(dbName, sfShortName).run(ctx, read)
+	if err != nil {
+		return recordsProcessed, sfRowsGenerated, time.Since(importStarted), fmt.Errorf("%v: %v", funcName, err.Error())
 	}
+
+	_, rateLogLine := logRate(sfShortName, importStarted, recordsProcessed)
+	filelogger.Log(
+		filelogger.LogLine{
+			File: config.TimingsLog,
+			Line: rateLogLine,
+		},
+	)
+
+	return recordsProcessed, sfRowsGenerated, time.Since(importStarted), nil
 }
 
 func mergeRowsGenerated(source map[string]int, dest map[string]int) map[string]int {
@@ -278,25 +283,9 @@ func mergeRowsGenerated(source map[string]int, dest map[string]int) map[string]i
 	return dest
 }
 
-func getInsert(rec *shapefile.Record, fields []*dbf.Field) insert {
-	var record insert = insert{}
-
-	for i, field := range fields {
-		value := rec.Attr(i)
-
-		// Fix invalid UTF8 strings
-		value = osdata.InvalidUTF8Fix(value)
-
-		// Fix for FEATCODE in "some" shapefiles
-		if field.Name == "FEATCODE" {
-			asInt := osdata.FeatcodeFix(value)
-			record[field.Name] = asInt
-		} else {
-			record[field.Name] = value
-		}
-	}
-
-	return record
+func fixAttr(fieldName, value string) interface{} {
+	// Fix invalid UTF8 strings, the typed fields are parsed when decoded
+	return osdata.InvalidUTF8Fix(value)
 }
 
 func getRate(diff time.Duration, recordsProcessed int) float64 {
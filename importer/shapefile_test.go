@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"context"
 	"io"
 	"testing"
 
@@ -11,7 +12,6 @@ import (
 	"go-uk-maps-import/database/engine"
 	"go-uk-maps-import/database/engine/mysql"
 	"go-uk-maps-import/filelogger"
-	"go-uk-maps-import/sqlwriter"
 )
 
 func BenchmarkImportShapeDirect(b *testing.B) {
@@ -37,12 +37,18 @@ func BenchmarkImportShapeDirect(b *testing.B) {
 	replaceQueryRgx := `REPLACE INTO motorway_junction.sd \((.+), (.+), (.+), (.+), ogc_geom\) VALUES \((.+), (.+), (.+), (.+), (.+)\)`
 	numQueries := 1
 
-	for i := 0; i < b.N; i++ {
-		for n := 0; n < numQueries; n++ {
+	i, err := New(config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer i.Close()
+
+	for n := 0; n < b.N; n++ {
+		for q := 0; q < numQueries; q++ {
 			mock.ExpectExec(replaceQueryRgx).WithArgs().WillReturnResult(sqlmock.NewResult(1, 1))
 		}
 
-		_, _, _, err := doImportShapefile(config, sf, sfsn)
+		_, _, _, err := i.doImportShapefile(context.Background(), sf, sfsn)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -56,7 +62,6 @@ func BenchmarkImportShapeDirect(b *testing.B) {
 func BenchmarkImportShapeToFile(b *testing.B) {
 	logger.Start(logger.LVL_FATAL)
 	filelogger.Start()
-	sqlwriter.Start()
 
 	sf := "./testdata/SD_MotorwayJunction.shp"
 	sfsn := "SD_MotorwayJunction.shp"
@@ -65,10 +70,25 @@ func BenchmarkImportShapeToFile(b *testing.B) {
 		TimingsLog: io.Discard,
 		UseFiles:   true,
 		IsTest:     true,
+		DB: engine.SEConfig{
+			Engine:        &engine.EngineMySQL,
+			StorageEngine: &mysql.MySQL{},
+		},
+	}
+
+	i, err := New(config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer i.Close()
+
+	err = i.sqlWriter.Start()
+	if err != nil {
+		b.Fatal(err)
 	}
 
-	for i := 0; i < b.N; i++ {
-		_, _, _, err := doImportShapefile(config, sf, sfsn)
+	for n := 0; n < b.N; n++ {
+		_, _, _, err := i.doImportShapefile(context.Background(), sf, sfsn)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// ref: https://www.esri.com/content/dam/esrisites/sitecore-archive/Files/Pdfs/library/whitepapers/pdfs/shapefile.pdf
+
+const (
+	shpNull       = 0
+	shpPoint      = 1
+	shpPolyLine   = 3
+	shpPolygon    = 5
+	shpMultiPoint = 8
+
+	// The Z and M variants of a type share its x,y layout
+	shpZOffset = 10
+	shpMOffset = 20
+
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+
+	shpBboxSize  = 32
+	shpPointSize = 16
+)
+
+type shpPointXY struct {
+	X float64
+	Y float64
+}
+
+// shpRecordToWKB converts the content of a .shp record to 2D WKB, the Z
+// and M values are dropped, every shapefile is converted by it whether it
+// is read in order or in segments
+func shpRecordToWKB(b []byte) ([]byte, error) {
+	var funcName string = "importer.shpRecordToWKB"
+
+	if len(b) < 4 {
+		return nil, fmt.Errorf("%v: truncated record", funcName)
+	}
+
+	var shapeType int = int(binary.LittleEndian.Uint32(b[0:4]))
+	if shapeType > shpMOffset {
+		shapeType -= shpMOffset
+	} else if shapeType > shpZOffset {
+		shapeType -= shpZOffset
+	}
+
+	var w = &bytes.Buffer{}
+	var err error
+
+	switch shapeType {
+	case shpNull:
+		writeWKBHeader(w, wkbGeometryCollection)
+		writeUint32(w, 0)
+	case shpPoint:
+		err = writePointWKB(w, b[4:])
+	case shpMultiPoint:
+		err = writeMultiPointWKB(w, b[4:])
+	case shpPolyLine:
+		err = writePolyLineWKB(w, b[4:])
+	case shpPolygon:
+		err = writePolygonWKB(w, b[4:])
+	default:
+		err = fmt.Errorf("unsupported shape type %v", shapeType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return w.Bytes(), nil
+}
+
+func writePointWKB(w *bytes.Buffer, b []byte) error {
+	points, err := readShpPoints(b, 1)
+	if err != nil {
+		return err
+	}
+
+	writeWKBHeader(w, wkbPoint)
+	writePoints(w, points)
+
+	return nil
+}
+
+func writeMultiPointWKB(w *bytes.Buffer, b []byte) error {
+	if len(b) < shpBboxSize+4 {
+		return fmt.Errorf("truncated multipoint")
+	}
+
+	var numPoints int = int(binary.LittleEndian.Uint32(b[shpBboxSize:]))
+
+	points, err := readShpPoints(b[shpBboxSize+4:], numPoints)
+	if err != nil {
+		return err
+	}
+
+	writeWKBHeader(w, wkbMultiPoint)
+	writeUint32(w, uint32(numPoints))
+	for _, p := range points {
+		writeWKBHeader(w, wkbPoint)
+		writePoints(w, []shpPointXY{p})
+	}
+
+	return nil
+}
+
+func writePolyLineWKB(w *bytes.Buffer, b []byte) error {
+	parts, err := readShpParts(b)
+	if err != nil {
+		return err
+	}
+
+	if len(parts) == 1 {
+		writeWKBHeader(w, wkbLineString)
+		writeRing(w, parts[0])
+		return nil
+	}
+
+	writeWKBHeader(w, wkbMultiLineString)
+	writeUint32(w, uint32(len(parts)))
+	for _, part := range parts {
+		writeWKBHeader(w, wkbLineString)
+		writeRing(w, part)
+	}
+
+	return nil
+}
+
+// writePolygonWKB groups the rings into polygons, outer rings are clockwise
+// and the anticlockwise holes that follow belong to the last outer ring
+func writePolygonWKB(w *bytes.Buffer, b []byte) error {
+	rings, err := readShpParts(b)
+	if err != nil {
+		return err
+	}
+
+	var polygons [][][]shpPointXY
+	for _, ring := range rings {
+		if len(polygons) == 0 || isClockwise(ring) {
+			polygons = append(polygons, [][]shpPointXY{ring})
+			continue
+		}
+
+		last := len(polygons) - 1
+		polygons[last] = append(polygons[last], ring)
+	}
+
+	if len(polygons) == 1 {
+		writePolygon(w, polygons[0])
+		return nil
+	}
+
+	writeWKBHeader(w, wkbMultiPolygon)
+	writeUint32(w, uint32(len(polygons)))
+	for _, polygon := range polygons {
+		writePolygon(w, polygon)
+	}
+
+	return nil
+}
+
+// readShpParts reads the parts of a polyline or polygon
+func readShpParts(b []byte) ([][]shpPointXY, error) {
+	if len(b) < shpBboxSize+8 {
+		return nil, fmt.Errorf("truncated parts header")
+	}
+
+	var numParts int = int(binary.LittleEndian.Uint32(b[shpBboxSize:]))
+	var numPoints int = int(binary.LittleEndian.Uint32(b[shpBboxSize+4:]))
+	var partsStart int = shpBboxSize + 8
+	var pointsStart int = partsStart + numParts*4
+
+	if numParts < 1 || len(b) < pointsStart {
+		return nil, fmt.Errorf("invalid parts %v", numParts)
+	}
+
+	points, err := readShpPoints(b[pointsStart:], numPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts = make([][]shpPointXY, numParts)
+	for n := 0; n < numParts; n++ {
+		start := int(binary.LittleEndian.Uint32(b[partsStart+n*4:]))
+		end := numPoints
+		if n+1 < numParts {
+			end = int(binary.LittleEndian.Uint32(b[partsStart+(n+1)*4:]))
+		}
+
+		if start > end || end > numPoints {
+			return nil, fmt.Errorf("invalid part %v [%v-%v]", n, start, end)
+		}
+
+		parts[n] = points[start:end]
+	}
+
+	return parts, nil
+}
+
+func readShpPoints(b []byte, n int) ([]shpPointXY, error) {
+	if n < 0 || len(b) < n*shpPointSize {
+		return nil, fmt.Errorf("truncated points, expected %v", n)
+	}
+
+	var points = make([]shpPointXY, n)
+	for i := range points {
+		points[i] = shpPointXY{
+			X: math.Float64frombits(binary.LittleEndian.Uint64(b[i*shpPointSize:])),
+			Y: math.Float64frombits(binary.LittleEndian.Uint64(b[i*shpPointSize+8:])),
+		}
+	}
+
+	return points, nil
+}
+
+// isClockwise uses the shoelace formula, a negative area is clockwise
+func isClockwise(ring []shpPointXY) bool {
+	var area float64
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i].X*ring[i+1].Y - ring[i+1].X*ring[i].Y
+	}
+
+	return area < 0
+}
+
+func writePolygon(w *bytes.Buffer, rings [][]shpPointXY) {
+	writeWKBHeader(w, wkbPolygon)
+	writeUint32(w, uint32(len(rings)))
+	for _, ring := range rings {
+		writeRing(w, ring)
+	}
+}
+
+func writeRing(w *bytes.Buffer, points []shpPointXY) {
+	writeUint32(w, uint32(len(points)))
+	writePoints(w, points)
+}
+
+func writePoints(w *bytes.Buffer, points []shpPointXY) {
+	var b [shpPointSize]byte
+	for _, p := range points {
+		binary.LittleEndian.PutUint64(b[0:8], math.Float64bits(p.X))
+		binary.LittleEndian.PutUint64(b[8:16], math.Float64bits(p.Y))
+		w.Write(b[:])
+	}
+}
+
+func writeWKBHeader(w *bytes.Buffer, geomType uint32) {
+	// Little endian
+	w.WriteByte(1)
+	writeUint32(w, geomType)
+}
+
+func writeUint32(w *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	w.Write(b[:])
+}
@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"go-uk-maps-import/database"
+	"go-uk-maps-import/shpinfo"
+)
+
+const (
+	extShapefile  = ".shp"
+	extGeoPackage = ".gpkg"
+
+	// A GeoPackage layer is addressed as path/to/file.gpkg#LayerName
+	geoPackageLayerSep = "#"
+
+	// The national GeoPackage covers the whole of GB
+	geoPackageSquare = "GB"
+)
+
+// sourceReader yields the records of a single import source, either a
+// shapefile or one layer of a GeoPackage, as an insert and the conversion of
+// its geometry to WKB
+type sourceReader interface {
+	Next() (insert, wkbFunc, error)
+	Close() error
+}
+
+// shapefileReader reads the records of a shapefile in order, with the same
+// record reader and WKB conversion as a segmented read
+type shapefileReader struct {
+	s *segmentedShapefile
+	n int
+}
+
+func (r *shapefileReader) Next() (insert, wkbFunc, error) {
+	for r.n < r.s.records() {
+		rec, toWKB, err := r.s.record(r.n)
+		r.n++
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Skip the deleted records
+		if rec == nil {
+			continue
+		}
+
+		return rec, toWKB, nil
+	}
+
+	return nil, nil, io.EOF
+}
+
+func (r *shapefileReader) Close() error {
+	return r.s.Close()
+}
+
+func openSource(config Config, source string) (uint32, sourceReader, error) {
+	var funcName string = "importer.openSource"
+
+	if isGeoPackageLayer(source) {
+		gpkgFile, layer := splitGeoPackageLayer(source)
+
+		recordsInFile, r, err := openGeoPackageLayer(gpkgFile, layer)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		return recordsInFile, r, nil
+	}
+
+	s, err := openSegmentedShapefile(source)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if !config.LowMemory {
+		err = s.toMemory()
+		if err != nil {
+			s.Close()
+			return 0, nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	return uint32(s.records()), &shapefileReader{s: s}, nil
+}
+
+// getRecordCount is the number of records of a source, an error is returned
+// for a GeoPackage layer that cannot be counted
+func getRecordCount(source string) (int, error) {
+	if isGeoPackageLayer(source) {
+		gpkgFile, layer := splitGeoPackageLayer(source)
+
+		return getGeoPackageLayerCount(gpkgFile, layer)
+	}
+
+	return shpinfo.ShxRecordCount(shpinfo.SiblingPath(source, shpinfo.ExtShx))
+}
+
+// getSourceShortName returns the name used for logging and rates, for a
+// GeoPackage layer this mimics the OS shapefile naming e.g. GB_Building.gpkg
+// so the database name can still be derived from it
+func getSourceShortName(source string) string {
+	if isGeoPackageLayer(source) {
+		_, layer := splitGeoPackageLayer(source)
+		return fmt.Sprintf("%v_%v%v", geoPackageSquare, layer, extGeoPackage)
+	}
+
+	return filepath.Base(source)
+}
+
+// GetSourceArea returns the tile a source was downloaded in e.g. SD, or GB
+// for a GeoPackage layer
+func GetSourceArea(source string) string {
+	return strings.ToUpper(database.GetSquareFilename(getSourceShortName(source)))
+}
+
+func isGeoPackageLayer(source string) bool {
+	return strings.Contains(source, extGeoPackage+geoPackageLayerSep)
+}
+
+func splitGeoPackageLayer(source string) (string, string) {
+	i := strings.LastIndex(source, geoPackageLayerSep)
+
+	return source[:i], source[i+1:]
+}
+
+func geoPackageLayerSource(gpkgFile, layer string) string {
+	return fmt.Sprintf("%v%v%v", gpkgFile, geoPackageLayerSep, layer)
+}
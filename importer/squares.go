@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rockwell-uk/go-nationalgrid"
+
+	"go-uk-maps-import/database"
+)
+
+// ExpandSquares turns a list of changed tile areas into the (lower case)
+// squares to re-import, the GB tile covers every square
+func ExpandSquares(areas []string) []string {
+	var squares = make(map[string]bool)
+
+	for _, area := range areas {
+		squares[strings.ToLower(area)] = true
+
+		if strings.EqualFold(area, geoPackageSquare) {
+			for square := range nationalgrid.NationalGridSquares {
+				squares[strings.ToLower(square)] = true
+			}
+		}
+	}
+
+	var expanded = make([]string, 0, len(squares))
+	for square := range squares {
+		expanded = append(expanded, square)
+	}
+
+	sort.Strings(expanded)
+
+	return expanded
+}
+
+// SelectSources returns the sources that need to be re-imported to rebuild
+// the given squares, features from neighbouring squares can overlap so their
+// sources are included too
+func SelectSources(shapeFiles []string, squares []string) []string {
+	if len(squares) == 0 {
+		return shapeFiles
+	}
+
+	var wanted = make(map[string]bool)
+	for _, square := range squares {
+		wanted[square] = true
+		for _, neighbour := range neighbourSquares(square) {
+			wanted[neighbour] = true
+		}
+	}
+
+	var selected = []string{}
+	for _, shapeFile := range shapeFiles {
+		if wanted[database.GetSquareFilename(getSourceShortName(shapeFile))] {
+			selected = append(selected, shapeFile)
+		}
+	}
+
+	return selected
+}
+
+// ClearedSquares returns the squares emptied to re-import the given squares,
+// the features of a tile can reach the squares around it so they are cleared
+// too and rebuilt from every tile that can write to them
+func ClearedSquares(squares []string) []string {
+	var cleared = make(map[string]bool)
+	for _, square := range squares {
+		cleared[square] = true
+		for _, neighbour := range neighbourSquares(square) {
+			cleared[neighbour] = true
+		}
+	}
+
+	var expanded = make([]string, 0, len(cleared))
+	for square := range cleared {
+		expanded = append(expanded, square)
+	}
+
+	sort.Strings(expanded)
+
+	return expanded
+}
+
+// shouldWrite reports whether rows are written to a square, only the squares
+// that were cleared are written when re-importing so the others keep their
+// rows
+func shouldWrite(config Config, square string) bool {
+	if len(config.Squares) == 0 {
+		return true
+	}
+
+	for _, s := range config.Squares {
+		if s == square {
+			return true
+		}
+	}
+
+	return false
+}
+
+func neighbourSquares(square string) []string {
+	var neighbours []string
+
+	origin, exists := nationalgrid.NationalGridSquares[strings.ToUpper(square)]
+	if !exists {
+		return neighbours
+	}
+
+	for key, gridSquare := range nationalgrid.NationalGridSquares {
+		if math.Abs(gridSquare[0]-origin[0]) <= 1 && math.Abs(gridSquare[1]-origin[1]) <= 1 {
+			neighbours = append(neighbours, strings.ToLower(key))
+		}
+	}
+
+	return neighbours
+}
@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectSources(t *testing.T) {
+	shapeFiles := []string{
+		"./shp/SD/SD_Woodland.shp",
+		"./shp/SE/SE_Woodland.shp",
+		"./shp/NY/NY_Woodland.shp",
+		"./shp/TQ/TQ_Woodland.shp",
+	}
+
+	tests := map[string]struct {
+		squares  []string
+		expected []string
+	}{
+		"all": {
+			squares:  nil,
+			expected: shapeFiles,
+		},
+		"with neighbours": {
+			squares: []string{"sd"},
+			expected: []string{
+				"./shp/SD/SD_Woodland.shp",
+				"./shp/SE/SE_Woodland.shp",
+				"./shp/NY/NY_Woodland.shp",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		actual := SelectSources(shapeFiles, tt.squares)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestClearedSquares(t *testing.T) {
+	expected := []string{"nx", "ny", "nz", "sd", "se", "sh", "sj", "sk"}
+
+	actual := ClearedSquares([]string{"sd"})
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	// Every tile that can write to a cleared square is re-imported
+	for _, square := range actual {
+		for _, neighbour := range neighbourSquares(square) {
+			sources := SelectSources([]string{"./shp/" + neighbour + "_Woodland.shp"}, actual)
+			if len(sources) != 1 {
+				t.Errorf("expected the tile %v next to %v to be re-imported", neighbour, square)
+			}
+		}
+	}
+}
+
+func TestShouldWrite(t *testing.T) {
+	config := Config{
+		Squares: ClearedSquares([]string{"sd"}),
+	}
+
+	tests := map[string]struct {
+		square   string
+		expected bool
+	}{
+		"changed square": {
+			square:   "sd",
+			expected: true,
+		},
+		"cleared neighbour": {
+			square:   "se",
+			expected: true,
+		},
+		"not cleared": {
+			square:   "tq",
+			expected: false,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := shouldWrite(config, tt.square)
+		if tt.expected != actual {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"fmt"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+)
+
+// RecordTransformer lets embedding code derive, rewrite or drop features
+// before they are written, the layer is the database name e.g. road
+type RecordTransformer interface {
+	// Fields returns the columns the transformer adds to a layer keyed by
+	// name with their SQL type, these are created with the layer tables
+	Fields(layer string) map[string]string
+
+	// Transform returns the record to write, or false to drop the feature
+	Transform(layer string, rec Record, geom *geos.Geom) (Record, bool, error)
+}
+
+// NewSchema returns the layer definitions of a config, the OS layers with
+// the generalised layers, the mapped layers, the measures and the fields of
+// the configured transformers, the storage engine creates its tables from it
+func NewSchema(config Config) (*types.Schema, error) {
+	var funcName string = "importer.NewSchema"
+
+	var schema *types.Schema = types.NewSchema()
+
+	schema.AddGeneralisedLayers(config.Generalise)
+	schema.ForeignKeys = config.ForeignKeys
+	schema.LookupTables = config.Lookups
+
+	err := config.Mapping.register(schema, config.ShapeFiles, getStorageDialect(config))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if config.Measures {
+		for _, layerType := range schema.MapLayers.Ordered() {
+			if _, isCompanion := schema.GeneralisedLayers[layerType]; isCompanion {
+				continue
+			}
+
+			err := addMeasureFields(schema, layerType)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+			}
+		}
+	}
+
+	for _, transformer := range config.Transformers {
+		for _, layerType := range schema.MapLayers.Ordered() {
+			// Companion layers are extended along with their layer
+			if _, isCompanion := schema.GeneralisedLayers[layerType]; isCompanion {
+				continue
+			}
+
+			for field, fieldType := range transformer.Fields(layerType) {
+				err := schema.AddDerivedField(layerType, field, fieldType)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+				}
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+// prepareFeature checks the geometry of a feature and transforms its record,
+// returning nil if the feature should not be written
+func (i *Importer) prepareFeature(ctx *geos.Context, r importAction, dbName string) (Record, *geos.Geom, []byte) {
+	shapeGeom, b := i.checkGeometry(ctx, r, dbName)
+	if shapeGeom == nil {
+		return nil, nil, nil
+	}
+
+	rec := i.parseAttributes(dbName, r.insert)
+	if i.config.Measures {
+		rec = measureRecord(i.schema, dbName, rec, shapeGeom)
+	}
+
+	rec, keep, err := transformRecord(i.config, i.schema, dbName, rec, shapeGeom)
+	if err != nil {
+		logger.Log(
+			logger.LVL_ERROR,
+			fmt.Sprintf("%v [%v] %v", dbName, r.insert["ID"], err.Error()),
+		)
+		return nil, nil, nil
+	}
+
+	if !keep {
+		return nil, nil, nil
+	}
+
+	return rec, shapeGeom, b
+}
+
+// transformRecord runs a record through the configured transformers in
+// order, derived fields a transformer leaves unset are written as NULL
+func transformRecord(config Config, schema *types.Schema, dbName string, rec Record, g *geos.Geom) (Record, bool, error) {
+	var funcName string = "importer.transformRecord"
+
+	if len(config.Transformers) == 0 {
+		return rec, true, nil
+	}
+
+	var sourceFields = make(map[string]bool)
+	for field := range rec {
+		sourceFields[field] = true
+	}
+
+	for _, transformer := range config.Transformers {
+		var keep bool
+		var err error
+
+		rec, keep, err = transformer.Transform(dbName, rec, g)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		if !keep {
+			return nil, false, nil
+		}
+	}
+
+	for field := range rec {
+		if !sourceFields[field] && !schema.IsDerivedField(dbName, field) {
+			return nil, false, fmt.Errorf("%v: undeclared field %v in layer %v", funcName, field, dbName)
+		}
+	}
+
+	for _, field := range schema.DerivedFields[dbName] {
+		if _, exists := rec[field]; !exists {
+			rec[field] = nil
+		}
+	}
+
+	return rec, true, nil
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+
+	"go-uk-maps-import/database/types"
+)
+
+type testTransformer struct{}
+
+func (t testTransformer) Fields(layer string) map[string]string {
+	return map[string]string{
+		"AREA_RANK": "int DEFAULT NULL",
+	}
+}
+
+func (t testTransformer) Transform(layer string, rec Record, geom *geos.Geom) (Record, bool, error) {
+	switch rec["ID"] {
+	case "drop":
+		return rec, false, nil
+	case "ranked":
+		rec["AREA_RANK"] = 1
+	case "undeclared":
+		rec["UNKNOWN"] = 1
+	}
+
+	return rec, true, nil
+}
+
+func TestTransformRecord(t *testing.T) {
+	var config Config = Config{
+		Transformers: []RecordTransformer{testTransformer{}},
+	}
+
+	schema := types.NewSchema()
+	err := schema.AddDerivedField("glasshouse", "AREA_RANK", "int DEFAULT NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		rec      Record
+		expected Record
+		keep     bool
+		err      bool
+	}{
+		"derived": {
+			rec:      Record{"ID": "ranked", "FEATCODE": 1.0},
+			expected: Record{"ID": "ranked", "FEATCODE": 1.0, "AREA_RANK": 1},
+			keep:     true,
+		},
+		"unset derived field": {
+			rec:      Record{"ID": "unranked", "FEATCODE": 1.0},
+			expected: Record{"ID": "unranked", "FEATCODE": 1.0, "AREA_RANK": nil},
+			keep:     true,
+		},
+		"dropped": {
+			rec: Record{"ID": "drop", "FEATCODE": 1.0},
+		},
+		"undeclared field": {
+			rec: Record{"ID": "undeclared", "FEATCODE": 1.0},
+			err: true,
+		},
+	}
+
+	for name, tt := range tests {
+		actual, keep, err := transformRecord(config, schema, "glasshouse", tt.rec, nil)
+		if (err != nil) != tt.err {
+			t.Errorf("%v: expected error %v, got %v", name, tt.err, err)
+			continue
+		}
+
+		if keep != tt.keep {
+			t.Errorf("%v: expected keep %v, got %v", name, tt.keep, keep)
+		}
+
+		if tt.keep && !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestNewSchema(t *testing.T) {
+	tests := map[string]struct {
+		config      Config
+		derived     bool
+		generalised bool
+	}{
+		"transformer": {
+			config:  Config{Transformers: []RecordTransformer{testTransformer{}}},
+			derived: true,
+		},
+		"generalised": {
+			config:      Config{Generalise: []int{25}},
+			generalised: true,
+		},
+	}
+
+	// Each config has its own schema
+	var schemas = make(map[string]*types.Schema)
+	for name, tt := range tests {
+		schema, err := NewSchema(tt.config)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		schemas[name] = schema
+	}
+
+	for name, tt := range tests {
+		schema := schemas[name]
+
+		if derived := schema.IsDerivedField("glasshouse", "AREA_RANK"); derived != tt.derived {
+			t.Errorf("%v: expected the transformer field %v, got %v", name, tt.derived, derived)
+		}
+
+		if _, generalised := schema.MapLayers["woodland_g25"]; generalised != tt.generalised {
+			t.Errorf("%v: expected the generalised layer %v, got %v", name, tt.generalised, generalised)
+		}
+	}
+
+	if _, exists := types.NewSchema().MapLayers["woodland_g25"]; exists {
+		t.Errorf("the OS layers were extended")
+	}
+}
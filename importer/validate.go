@@ -0,0 +1,259 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-nationalgrid"
+	"github.com/rockwell-uk/go-utils/fileutils"
+
+	"go-uk-maps-import/database"
+	"go-uk-maps-import/database/types"
+	"go-uk-maps-import/shpinfo"
+)
+
+// OS source files are named SQ_LayerName e.g. SD_SurfaceWater_Area.shp
+var sourceNameRegex = regexp.MustCompile(`^[A-Z]{2}_[A-Za-z]+(_[A-Za-z]+)*\.(shp|gpkg)$`)
+
+type ValidationResult struct {
+	Source   string
+	Errors   []string
+	Warnings []string
+}
+
+func (v ValidationResult) IsValid() bool {
+	return len(v.Errors) == 0
+}
+
+func (v ValidationResult) String() string {
+	var problems []string
+	problems = append(problems, v.Errors...)
+	problems = append(problems, v.Warnings...)
+
+	return fmt.Sprintf("%v [%v]", v.Source, strings.Join(problems, ", "))
+}
+
+// ValidateSources checks every source before the import starts, returning
+// the sources that can be imported and the results for those with problems
+func ValidateSources(sources []string, mapping Mapping) ([]string, []ValidationResult) {
+	var valid []string
+	var problems []ValidationResult
+
+	for _, source := range sources {
+		result := validateSource(source, mapping)
+
+		if result.IsValid() {
+			valid = append(valid, source)
+		}
+
+		if len(result.Errors) > 0 || len(result.Warnings) > 0 {
+			logger.Log(
+				logger.LVL_DEBUG,
+				fmt.Sprintf("Validation %v\n", result),
+			)
+			problems = append(problems, result)
+		}
+	}
+
+	return valid, problems
+}
+
+// Quarantine moves a shapefile and its sibling files out of the data folder
+func Quarantine(source, quarantineFolder string) error {
+	var funcName string = "importer.Quarantine"
+
+	// The layers of a GeoPackage are skipped rather than moving the whole file
+	if isGeoPackageLayer(source) {
+		return nil
+	}
+
+	var destFolder string = filepath.Join(quarantineFolder, filepath.Base(filepath.Dir(source)))
+
+	err := fileutils.MkDir(destFolder)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	for _, ext := range []string{shpinfo.ExtShp, shpinfo.ExtShx, shpinfo.ExtDbf, shpinfo.ExtPrj} {
+		sibling := shpinfo.SiblingPath(source, ext)
+		if !fileutils.FileExists(sibling) {
+			continue
+		}
+
+		err := os.Rename(sibling, filepath.Join(destFolder, filepath.Base(sibling)))
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func validateSource(source string, mapping Mapping) ValidationResult {
+	var result ValidationResult = ValidationResult{
+		Source: source,
+	}
+
+	var sfShortName string = getSourceShortName(source)
+
+	// Mapped sources do not follow the OS naming convention
+	if l, mapped := mapping.find(sfShortName); mapped && !isGeoPackageLayer(source) {
+		return validateMappedSource(source, l, result)
+	}
+
+	// Naming convention
+	if !sourceNameRegex.MatchString(sfShortName) {
+		result.Errors = append(result.Errors, "filename does not match SQ_LayerName")
+		return result
+	}
+
+	var square string = strings.ToUpper(database.GetSquareFilename(sfShortName))
+	if _, exists := nationalgrid.NationalGridSquares[square]; !exists && square != geoPackageSquare {
+		result.Errors = append(result.Errors, fmt.Sprintf("unknown national grid square %v", square))
+	}
+
+	var dbName string = database.GetDBNameFromFilename(sfShortName)
+	expected, exists := types.OSLayer(dbName)
+	if !exists {
+		result.Errors = append(result.Errors, fmt.Sprintf("unknown layer %v", dbName))
+		return result
+	}
+
+	var fields []string
+	var err error
+	if isGeoPackageLayer(source) {
+		fields, err = getGeoPackageFields(source)
+	} else {
+		fields, err = validateShapefile(source, &result)
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	// The DBF must provide every field the layer table expects
+	var present = make(map[string]bool)
+	for _, field := range fields {
+		present[strings.ToUpper(field)] = true
+	}
+
+	for _, field := range expected {
+		if field == "GRIDREF" {
+			continue
+		}
+		if !present[field] {
+			result.Errors = append(result.Errors, fmt.Sprintf("missing field %v", field))
+		}
+	}
+
+	return result
+}
+
+// validateMappedSource checks a shapefile provides the fields its mapping
+// reads, the geometries are stored as British National Grid so a source in
+// any other projection is rejected
+func validateMappedSource(source string, l *LayerMapping, result ValidationResult) ValidationResult {
+	fields, err := validateShapefile(source, &result)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	var present = make(map[string]bool)
+	for _, field := range fields {
+		present[strings.ToUpper(field)] = true
+	}
+
+	for _, f := range l.Fields {
+		if !present[strings.ToUpper(f.Source)] {
+			result.Errors = append(result.Errors, fmt.Sprintf("missing field %v", f.Source))
+		}
+	}
+
+	return result
+}
+
+// validateShapefile checks the sibling files agree with each other and
+// returns the DBF field names, the projection must be British National Grid
+func validateShapefile(shapeFile string, result *ValidationResult) ([]string, error) {
+	for _, ext := range []string{shpinfo.ExtShx, shpinfo.ExtDbf, shpinfo.ExtPrj} {
+		if !fileutils.FileExists(shpinfo.SiblingPath(shapeFile, ext)) {
+			return []string{}, fmt.Errorf("missing %v file", ext)
+		}
+	}
+
+	header, err := shpinfo.ReadHeader(shapeFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	size, err := fileutils.FileSizeBytes(shapeFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	if size != header.FileLength {
+		return []string{}, fmt.Errorf("file length %v does not match header %v", size, header.FileLength)
+	}
+
+	shpRecords, err := shpinfo.ShxRecordCount(shpinfo.SiblingPath(shapeFile, shpinfo.ExtShx))
+	if err != nil {
+		return []string{}, err
+	}
+
+	dbfHeader, err := shpinfo.ReadDBFHeader(shpinfo.SiblingPath(shapeFile, shpinfo.ExtDbf))
+	if err != nil {
+		return []string{}, err
+	}
+
+	if shpRecords != dbfHeader.Records {
+		return []string{}, fmt.Errorf("record count mismatch shx %v, dbf %v", shpRecords, dbfHeader.Records)
+	}
+
+	if shpRecords == 0 {
+		result.Warnings = append(result.Warnings, "no records")
+	}
+
+	// Projection
+	prj, err := shpinfo.ReadPrj(shpinfo.SiblingPath(shapeFile, shpinfo.ExtPrj))
+	if err != nil {
+		return []string{}, err
+	}
+
+	if !shpinfo.IsBritishNationalGrid(prj) {
+		return []string{}, fmt.Errorf("projection is not British National Grid")
+	}
+
+	var fields []string
+	for _, field := range dbfHeader.Fields {
+		fields = append(fields, field.Name)
+	}
+
+	return fields, nil
+}
+
+func getGeoPackageFields(source string) ([]string, error) {
+	gpkgFile, layer := splitGeoPackageLayer(source)
+
+	db, err := openGeoPackage(gpkgFile)
+	if err != nil {
+		return []string{}, err
+	}
+	defer db.Close()
+
+	columns, err := getGeoPackageColumns(db, layer)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var fields []string
+	for field := range columns {
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-uk-maps-import/shpinfo"
+)
+
+func TestValidateSources(t *testing.T) {
+	sf := "./testdata/SD_MotorwayJunction.shp"
+	dir := t.TempDir()
+
+	// A copy of the test shapefile without its .prj
+	noPrj := filepath.Join(dir, "SD_MotorwayJunction.shp")
+	for _, ext := range []string{shpinfo.ExtShp, shpinfo.ExtShx, shpinfo.ExtDbf} {
+		b, err := os.ReadFile(shpinfo.SiblingPath(sf, ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(shpinfo.SiblingPath(noPrj, ext), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Mapped copies in British National Grid and in WGS 84
+	survey := filepath.Join(dir, "survey_bng.shp")
+	wgs84 := filepath.Join(dir, "survey_wgs84.shp")
+	for _, ext := range []string{shpinfo.ExtShp, shpinfo.ExtShx, shpinfo.ExtDbf, shpinfo.ExtPrj} {
+		b, err := os.ReadFile(shpinfo.SiblingPath(sf, ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(shpinfo.SiblingPath(survey, ext), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if ext == shpinfo.ExtPrj {
+			b = []byte(`GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`)
+		}
+		if err := os.WriteFile(shpinfo.SiblingPath(wgs84, ext), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mapping := Mapping{
+		Layers: []LayerMapping{
+			{
+				Match:  "survey_*.shp",
+				Layer:  "survey_point",
+				Fields: []FieldMapping{{Source: "ID", Name: "ID"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		source  string
+		mapping Mapping
+		valid   bool
+	}{
+		"valid": {
+			source: sf,
+			valid:  true,
+		},
+		"missing prj": {
+			source: noPrj,
+			valid:  false,
+		},
+		"bad name": {
+			source: "./testdata/MotorwayJunction.shp",
+			valid:  false,
+		},
+		"unknown layer": {
+			source: "./testdata/SD_Motorway.shp",
+			valid:  false,
+		},
+		"mapped": {
+			source:  survey,
+			mapping: mapping,
+			valid:   true,
+		},
+		"mapped not british national grid": {
+			source:  wgs84,
+			mapping: mapping,
+			valid:   false,
+		},
+	}
+
+	for name, tt := range tests {
+		valid, problems := ValidateSources([]string{tt.source}, tt.mapping)
+
+		if tt.valid != (len(valid) == 1) {
+			t.Errorf("%v: expected valid %v, got %v", name, tt.valid, problems)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-utils/fileutils"
+)
+
+// FileName is written alongside the artefacts of an import
+const FileName = "manifest.json"
+
+// Manifest lists the artefacts in a folder so they can be checked by anyone
+// they are handed to, the paths are relative to the folder
+type Manifest struct {
+	Created   time.Time  `json:"created"`
+	Artefacts []Artefact `json:"artefacts"`
+}
+
+// Artefact is a file written by an import, Square is empty for a file that
+// holds every square of its layer
+type Artefact struct {
+	Path    string   `json:"path"`
+	SHA256  string   `json:"sha256"`
+	Size    int64    `json:"size"`
+	Rows    int      `json:"rows"`
+	Layer   string   `json:"layer"`
+	Square  string   `json:"square,omitempty"`
+	Sources []Source `json:"sources"`
+}
+
+// Source is a file the rows of an artefact were read from
+type Source struct {
+	File string `json:"file"`
+	MD5  string `json:"md5"`
+}
+
+func New() *Manifest {
+	return &Manifest{
+		Created:   time.Now().UTC(),
+		Artefacts: []Artefact{},
+	}
+}
+
+// Add hashes a file in the folder of the manifest and lists it
+func (m *Manifest) Add(folder, file string, a Artefact) error {
+	var funcName string = "manifest.Add"
+
+	path, err := filepath.Rel(folder, file)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	hash, err := fileutils.FileHash(file)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	a.Path = filepath.ToSlash(path)
+	a.SHA256 = hash
+	a.Size = info.Size()
+	if a.Sources == nil {
+		a.Sources = []Source{}
+	}
+
+	m.Artefacts = append(m.Artefacts, a)
+
+	return nil
+}
+
+func Load(folder string) (Manifest, error) {
+	var funcName string = "manifest.Load"
+
+	var m Manifest
+
+	b, err := os.ReadFile(filepath.Join(folder, FileName))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, fmt.Errorf("%v: cannot unmarshal JSON [%v]", funcName, err.Error())
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest to its folder, the artefacts in path order
+func (m *Manifest) Save(folder string) error {
+	var funcName string = "manifest.Save"
+
+	sort.Slice(m.Artefacts, func(a, b int) bool {
+		return m.Artefacts[a].Path < m.Artefacts[b].Path
+	})
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = os.WriteFile(filepath.Join(folder, FileName), b, 0o644)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Saved manifest of %v [%v artefacts]\n", folder, len(m.Artefacts)),
+	)
+
+	return nil
+}
+
+// SourceHashes keeps the MD5 of each source file so a source shared by many
+// artefacts is only read once
+type SourceHashes map[string]string
+
+// Source returns the source with the MD5 of its file, file is the path the
+// source is listed by
+func (h SourceHashes) Source(file string) (Source, error) {
+	var funcName string = "manifest.Source"
+
+	if md5Hash, exists := h[file]; exists {
+		return Source{File: filepath.Base(file), MD5: md5Hash}, nil
+	}
+
+	md5Hash, err := fileutils.GetMD5Hash(file)
+	if err != nil {
+		return Source{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	h[file] = md5Hash
+
+	return Source{File: filepath.Base(file), MD5: md5Hash}, nil
+}
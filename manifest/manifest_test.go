@@ -0,0 +1,88 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := map[string]struct {
+		change   func(folder string) error
+		expected []string
+	}{
+		"unchanged": {
+			change:   func(folder string) error { return nil },
+			expected: []string{},
+		},
+		"modified": {
+			change: func(folder string) error {
+				return os.WriteFile(filepath.Join(folder, "road", "sd01.sql"), []byte("(2);\n"), 0o644)
+			},
+			expected: []string{
+				"road/sd01.sql: sha256 3dc55e7e994af33cd0a1ea7d26c7aa05196092048b64109e226bf1ec304def31, expected e2cf694960a7ac31879a6e6f78712d3128ab8e3eedfc0672b0ffc38e9b395951",
+			},
+		},
+		"truncated": {
+			change: func(folder string) error {
+				return os.WriteFile(filepath.Join(folder, "road", "sd01.sql"), []byte("(1"), 0o644)
+			},
+			expected: []string{"road/sd01.sql: size 2, expected 5"},
+		},
+		"missing": {
+			change: func(folder string) error {
+				return os.Remove(filepath.Join(folder, "road", "sd01.sql"))
+			},
+			expected: []string{"road/sd01.sql: missing"},
+		},
+		"not listed": {
+			change: func(folder string) error {
+				return os.WriteFile(filepath.Join(folder, "road", "sd02.sql"), []byte("(3);\n"), 0o644)
+			},
+			expected: []string{"road/sd02.sql: not in the manifest"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			folder := t.TempDir()
+
+			err := os.MkdirAll(filepath.Join(folder, "road"), 0o755)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file := filepath.Join(folder, "road", "sd01.sql")
+			err = os.WriteFile(file, []byte("(1);\n"), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := New()
+			err = m.Add(folder, file, Artefact{Rows: 1, Layer: "road", Square: "sd01"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = m.Save(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.change(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := Verify(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v\nactual %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rockwell-uk/go-utils/fileutils"
+)
+
+// Verify checks the files of a folder against its manifest, it returns a
+// line for each artefact that is missing or has changed and for each file
+// that is not listed
+func Verify(folder string) ([]string, error) {
+	var funcName string = "manifest.Verify"
+
+	var problems = []string{}
+
+	m, err := Load(folder)
+	if err != nil {
+		return problems, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var listed = make(map[string]bool)
+	for _, a := range m.Artefacts {
+		listed[a.Path] = true
+
+		problem, err := check(folder, a)
+		if err != nil {
+			return problems, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	err = filepath.WalkDir(folder, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		path, err := filepath.Rel(folder, s)
+		if err != nil {
+			return err
+		}
+		path = filepath.ToSlash(path)
+
+		if path != FileName && !listed[path] {
+			problems = append(problems, fmt.Sprintf("%v: not in the manifest", path))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return problems, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	sort.Strings(problems)
+
+	return problems, nil
+}
+
+func check(folder string, a Artefact) (string, error) {
+	var file string = filepath.Join(folder, filepath.FromSlash(a.Path))
+
+	info, err := os.Stat(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Sprintf("%v: missing", a.Path), nil
+		}
+		return "", err
+	}
+
+	if info.Size() != a.Size {
+		return fmt.Sprintf("%v: size %v, expected %v", a.Path, info.Size(), a.Size), nil
+	}
+
+	hash, err := fileutils.FileHash(file)
+	if err != nil {
+		return "", err
+	}
+
+	if hash != a.SHA256 {
+		return fmt.Sprintf("%v: sha256 %v, expected %v", a.Path, hash, a.SHA256), nil
+	}
+
+	return "", nil
+}
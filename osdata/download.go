@@ -3,7 +3,6 @@ package osdata
 import (
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 
 	"github.com/rockwell-uk/csync/waitgroup"
@@ -115,15 +114,15 @@ func download(filepath string, url string, counter *WriteCounter) error {
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	resp, cancel, err := fileutils.Request(url, http.MethodGet, nil, nil)
+	r, cancel, err := openURL(url)
 	defer cancel()
 	if err != nil {
 		out.Close()
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
-	defer resp.Body.Close()
+	defer r.Close()
 
-	if _, err = io.Copy(out, io.TeeReader(resp.Body, counter)); err != nil {
+	if _, err = io.Copy(out, io.TeeReader(r, counter)); err != nil {
 		out.Close()
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
@@ -0,0 +1,221 @@
+package osdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-utils/fileutils"
+	"github.com/rockwell-uk/go-utils/timeutils"
+)
+
+const (
+	mirrorManifest = "manifest.json"
+
+	schemeFile  = "file"
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+)
+
+// A mirror is a folder (served locally or over http) holding the tile zips
+// alongside a manifest in the same format as the OS Data Hub download list,
+// with each tile URL relative to the mirror root
+
+// BuildMirror downloads the tiles for the given format from source and copies
+// them with a manifest into mirrorDir, so mirrorDir can be used as a -source
+func BuildMirror(source, format, mirrorDir string) error {
+	var funcName string = "osdata.BuildMirror"
+	var jobName string = "Building OSData Mirror"
+
+	var start time.Time = time.Now()
+	var took time.Duration
+
+	release, err := GetRelease(source, format)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var tiles []VectorMapDistrictTile = release.Tiles
+
+	logger.Log(
+		logger.LVL_APP,
+		fmt.Sprintf("%v [%v] %v\n", jobName, len(tiles), mirrorDir),
+	)
+
+	err = prepFolders()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	// Only download what we don't already have
+	err = doDownloadJob(filterDownloaded(tiles))
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = fileutils.MkDir(mirrorDir)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var manifest []VectorMapDistrictTile
+	for _, tile := range tiles {
+		mirrored, err := mirrorTile(tile, mirrorDir)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		manifest = append(manifest, mirrored)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = os.WriteFile(filepath.Join(mirrorDir, mirrorManifest), b, 0o644)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	took = timeutils.Took(start)
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Done %v [%v]\n", jobName, took),
+	)
+
+	return nil
+}
+
+func mirrorTile(tile VectorMapDistrictTile, mirrorDir string) (VectorMapDistrictTile, error) {
+	var funcName string = "osdata.mirrorTile"
+
+	var src string = fmt.Sprintf("%v/%v", zipDir, tile.FileName)
+	var dst string = filepath.Join(mirrorDir, tile.FileName)
+
+	md5Hash, err := fileutils.GetMD5Hash(src)
+	if err != nil {
+		return tile, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if md5Hash != tile.MD5 {
+		return tile, fmt.Errorf("%v: md5 hash does not match %v [%v:%v]", funcName, tile.FileName, md5Hash, tile.MD5)
+	}
+
+	// Skip the copy if the mirror already holds this exact file
+	existing, err := fileutils.GetMD5Hash(dst)
+	if err != nil || existing != md5Hash {
+		logger.Log(
+			logger.LVL_DEBUG,
+			fmt.Sprintf("Mirroring %v\n", tile.FileName),
+		)
+
+		err = copyFile(src, dst)
+		if err != nil {
+			return tile, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	tile.URL = tile.FileName
+
+	return tile, nil
+}
+
+// getManifestURL returns the location of the tile list for a source, an
+// empty source is the OS Data Hub
+func getManifestURL(source string) string {
+	if source == "" {
+		return osDataAPI
+	}
+
+	return resolveMirrorURL(source, mirrorManifest)
+}
+
+// resolveMirrorURL resolves a manifest entry against the mirror root
+func resolveMirrorURL(source, ref string) string {
+	if strings.Contains(ref, "://") {
+		return ref
+	}
+
+	return fmt.Sprintf("%v/%v", strings.TrimSuffix(source, "/"), ref)
+}
+
+func validateSource(source string) error {
+	if source == "" {
+		return nil
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case schemeFile, schemeHTTP, schemeHTTPS:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported source scheme [%v]", u.Scheme)
+}
+
+// openURL opens either a file:// or http(s):// location for reading
+func openURL(location string) (io.ReadCloser, func(), error) {
+	var funcName string = "osdata.openURL"
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, func() {}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if u.Scheme == schemeFile {
+		f, err := os.Open(filepath.FromSlash(u.Host + u.Path))
+		if err != nil {
+			return nil, func() {}, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		return f, func() {}, nil
+	}
+
+	resp, cancel, err := fileutils.Request(location, http.MethodGet, nil, nil)
+	if err != nil {
+		return nil, cancel, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, cancel, fmt.Errorf("%v: unexpected status %v from %v", funcName, resp.Status, location)
+	}
+
+	return resp.Body, cancel, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var tmpFile string = fmt.Sprintf("%v%v", dst, ".tmp")
+
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, dst)
+}
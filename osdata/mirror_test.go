@@ -0,0 +1,92 @@
+package osdata
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveMirrorURL(t *testing.T) {
+	tests := map[string]struct {
+		source   string
+		ref      string
+		expected string
+	}{
+		"file": {
+			source:   "file:///mnt/osmirror",
+			ref:      "vmdvec_sd.zip",
+			expected: "file:///mnt/osmirror/vmdvec_sd.zip",
+		},
+		"http trailing slash": {
+			source:   "http://internal-mirror/",
+			ref:      "vmdvec_sd.zip",
+			expected: "http://internal-mirror/vmdvec_sd.zip",
+		},
+		"absolute": {
+			source:   "http://internal-mirror/",
+			ref:      "https://api.os.uk/vmdvec_sd.zip",
+			expected: "https://api.os.uk/vmdvec_sd.zip",
+		},
+	}
+
+	for name, tt := range tests {
+		actual := resolveMirrorURL(tt.source, tt.ref)
+		if tt.expected != actual {
+			t.Errorf("%v: expected [%v], got [%v]", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestFileMirror(t *testing.T) {
+	dir := t.TempDir()
+	source := "file://" + filepath.ToSlash(dir)
+
+	manifest := []VectorMapDistrictTile{
+		{
+			MD5:      "0cc175b9c0f1b6a831c399e269772661",
+			Size:     1,
+			URL:      "vmdvec_sd.zip",
+			Format:   DownloadFormats[FormatShapefile],
+			Area:     "SD",
+			FileName: "vmdvec_sd.zip",
+		},
+	}
+
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, mirrorManifest), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vmdvec_sd.zip"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tiles, err := getDownloadList(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tiles) != 1 || tiles[0].URL != source+"/vmdvec_sd.zip" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+
+	r, cancel, err := openURL(tiles[0].URL)
+	defer cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "a" {
+		t.Fatalf("expected [a], got [%v]", string(content))
+	}
+}
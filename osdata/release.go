@@ -0,0 +1,201 @@
+package osdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rockwell-uk/go-logger/logger"
+	"github.com/rockwell-uk/go-utils/fileutils"
+)
+
+const (
+	releaseStateFile = "release.json"
+
+	// The single GeoPackage tile covers every square
+	AreaGB = "GB"
+)
+
+// Release is the set of tiles published by a source for a format, the
+// release of the last successful import is kept in the state folder
+type Release struct {
+	Format string
+	Tiles  []VectorMapDistrictTile
+}
+
+func GetRelease(source, format string) (Release, error) {
+	var funcName string = "osdata.GetRelease"
+
+	if _, ok := DownloadFormats[format]; !ok {
+		return Release{}, fmt.Errorf("%v: unknown download format [%v]", funcName, format)
+	}
+
+	if err := validateSource(source); err != nil {
+		return Release{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	tiles, err := getDownloadList(source)
+	if err != nil {
+		return Release{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return Release{
+		Format: format,
+		Tiles:  filterFormat(tiles, format),
+	}, nil
+}
+
+// LoadRelease returns the release of the last successful import, or an
+// empty release if there has not been one
+func LoadRelease(stateDir string) (Release, error) {
+	var funcName string = "osdata.LoadRelease"
+
+	var release Release
+
+	b, err := os.ReadFile(filepath.Join(stateDir, releaseStateFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Release{}, nil
+		}
+		return Release{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if err := json.Unmarshal(b, &release); err != nil {
+		return Release{}, fmt.Errorf("%v: cannot unmarshal JSON [%v]", funcName, err.Error())
+	}
+
+	return release, nil
+}
+
+func SaveRelease(stateDir string, release Release) error {
+	var funcName string = "osdata.SaveRelease"
+
+	err := fileutils.MkDir(stateDir)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	b, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = os.WriteFile(filepath.Join(stateDir, releaseStateFile), b, 0o644)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	logger.Log(
+		logger.LVL_DEBUG,
+		fmt.Sprintf("Saved release state [%v tiles]\n", len(release.Tiles)),
+	)
+
+	return nil
+}
+
+func (r Release) IsEmpty() bool {
+	return len(r.Tiles) == 0
+}
+
+// Changed lists the areas whose tile is new or has a different md5 to the
+// last release
+func (r Release) Changed(last Release) []string {
+	var changed []string
+
+	var lastMD5s = make(map[string]string)
+	if r.Format == last.Format {
+		for _, tile := range last.Tiles {
+			lastMD5s[tile.Area] = tile.MD5
+		}
+	}
+
+	for _, tile := range r.Tiles {
+		if md5Hash, exists := lastMD5s[tile.Area]; !exists || md5Hash != tile.MD5 {
+			changed = append(changed, tile.Area)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+// Removed lists the areas of the last release that are no longer published,
+// a release in another format replaces every tile so none are removed
+func (r Release) Removed(last Release) []string {
+	var removed []string
+
+	if r.Format != last.Format {
+		return removed
+	}
+
+	var areas = make(map[string]bool)
+	for _, tile := range r.Tiles {
+		areas[tile.Area] = true
+	}
+
+	for _, tile := range last.Tiles {
+		if !areas[tile.Area] {
+			removed = append(removed, tile.Area)
+		}
+	}
+
+	sort.Strings(removed)
+
+	return removed
+}
+
+// Imported is the release as it was imported, the tiles of the areas that
+// were not imported are those of the last release, or are left out, so the
+// next import finds them changed and tries them again
+func (r Release) Imported(last Release, failed []string) Release {
+	var failedAreas = make(map[string]bool)
+	for _, area := range failed {
+		failedAreas[area] = true
+	}
+
+	var lastTiles = make(map[string]VectorMapDistrictTile)
+	if r.Format == last.Format {
+		for _, tile := range last.Tiles {
+			lastTiles[tile.Area] = tile
+		}
+	}
+
+	var imported = Release{
+		Format: r.Format,
+		Tiles:  []VectorMapDistrictTile{},
+	}
+	for _, tile := range r.Tiles {
+		if failedAreas[tile.Area] {
+			lastTile, exists := lastTiles[tile.Area]
+			if !exists {
+				continue
+			}
+			tile = lastTile
+		}
+
+		imported.Tiles = append(imported.Tiles, tile)
+	}
+
+	return imported
+}
+
+// TilesIn returns the tiles of the release for the given areas
+func (r Release) TilesIn(areas []string) []VectorMapDistrictTile {
+	var wanted = make(map[string]bool)
+	for _, area := range areas {
+		wanted[area] = true
+	}
+
+	var tiles = []VectorMapDistrictTile{}
+	for _, tile := range r.Tiles {
+		if wanted[tile.Area] {
+			tiles = append(tiles, tile)
+		}
+	}
+
+	return tiles
+}
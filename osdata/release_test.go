@@ -0,0 +1,151 @@
+package osdata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReleaseChanged(t *testing.T) {
+	last := Release{
+		Format: FormatShapefile,
+		Tiles: []VectorMapDistrictTile{
+			{Area: "SD", MD5: "a"},
+			{Area: "SE", MD5: "b"},
+		},
+	}
+
+	tests := map[string]struct {
+		release  Release
+		expected []string
+	}{
+		"unchanged": {
+			release:  last,
+			expected: nil,
+		},
+		"changed and new": {
+			release: Release{
+				Format: FormatShapefile,
+				Tiles: []VectorMapDistrictTile{
+					{Area: "SD", MD5: "a"},
+					{Area: "SE", MD5: "c"},
+					{Area: "NY", MD5: "d"},
+				},
+			},
+			expected: []string{"NY", "SE"},
+		},
+		"format changed": {
+			release: Release{
+				Format: FormatGeoPackage,
+				Tiles: []VectorMapDistrictTile{
+					{Area: "GB", MD5: "e"},
+				},
+			},
+			expected: []string{"GB"},
+		},
+	}
+
+	for name, tt := range tests {
+		actual := tt.release.Changed(last)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestReleaseRemoved(t *testing.T) {
+	last := Release{
+		Format: FormatShapefile,
+		Tiles: []VectorMapDistrictTile{
+			{Area: "SD", MD5: "a"},
+			{Area: "SE", MD5: "b"},
+		},
+	}
+
+	tests := map[string]struct {
+		release  Release
+		expected []string
+	}{
+		"removed": {
+			release: Release{
+				Format: FormatShapefile,
+				Tiles: []VectorMapDistrictTile{
+					{Area: "SD", MD5: "a"},
+				},
+			},
+			expected: []string{"SE"},
+		},
+		"format changed": {
+			release: Release{
+				Format: FormatGeoPackage,
+				Tiles: []VectorMapDistrictTile{
+					{Area: "GB", MD5: "e"},
+				},
+			},
+			expected: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		actual := tt.release.Removed(last)
+		if !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, actual)
+		}
+	}
+}
+
+func TestReleaseImported(t *testing.T) {
+	last := Release{
+		Format: FormatShapefile,
+		Tiles: []VectorMapDistrictTile{
+			{Area: "SD", MD5: "a"},
+			{Area: "SE", MD5: "b"},
+		},
+	}
+
+	release := Release{
+		Format: FormatShapefile,
+		Tiles: []VectorMapDistrictTile{
+			{Area: "SD", MD5: "c"},
+			{Area: "SE", MD5: "d"},
+			{Area: "NY", MD5: "e"},
+		},
+	}
+
+	tests := map[string]struct {
+		failed   []string
+		expected []VectorMapDistrictTile
+	}{
+		"all imported": {
+			failed:   nil,
+			expected: release.Tiles,
+		},
+		"changed tile failed": {
+			failed: []string{"SE"},
+			expected: []VectorMapDistrictTile{
+				{Area: "SD", MD5: "c"},
+				{Area: "SE", MD5: "b"},
+				{Area: "NY", MD5: "e"},
+			},
+		},
+		"new tile failed": {
+			failed: []string{"NY"},
+			expected: []VectorMapDistrictTile{
+				{Area: "SD", MD5: "c"},
+				{Area: "SE", MD5: "d"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		imported := release.Imported(last, tt.failed)
+		if !reflect.DeepEqual(tt.expected, imported.Tiles) {
+			t.Errorf("%v: expected %v, got %v", name, tt.expected, imported.Tiles)
+		}
+
+		// The failed tiles are changed for the next import
+		changed := release.Changed(imported)
+		if !reflect.DeepEqual(tt.failed, changed) {
+			t.Errorf("%v: expected %v to be changed, got %v", name, tt.failed, changed)
+		}
+	}
+}
@@ -34,7 +34,7 @@ func unzipTile(tile VectorMapDistrictTile) error {
 		}
 	}
 
-	var folderWithinZip string = fmt.Sprintf("OS VectorMap District (ESRI Shape File) %v/data/", tile.Area)
+	var folderWithinZip string = getFolderWithinZip(tile)
 	var zipFile string = fmt.Sprintf("%v/%v", zipDir, tile.FileName)
 	var archive *zip.ReadCloser
 
@@ -147,7 +147,8 @@ func unzip(dst string, zipfile string, folderWithinZip string, j *progress.Job)
 			return fmt.Errorf("invalid file path %v [%v]", filePath, destFolder)
 		}
 
-		err := fileutils.MkDir(destFolder)
+		// GeoPackage zips are unpacked whole so entries can be in subfolders
+		err := fileutils.MkDir(filepath.Dir(destPath))
 		if err != nil {
 			return fmt.Errorf("%v: %v", funcName, err.Error())
 		}
@@ -180,6 +181,15 @@ func unzip(dst string, zipfile string, folderWithinZip string, j *progress.Job)
 	return nil
 }
 
+func getFolderWithinZip(tile VectorMapDistrictTile) string {
+	// The GeoPackage zip is extracted as is, the GeoPackage is found wherever it lands
+	if tile.Format == DownloadFormats[FormatGeoPackage] {
+		return ""
+	}
+
+	return fmt.Sprintf("OS VectorMap District (ESRI Shape File) %v/data/", tile.Area)
+}
+
 func getFileCount(archive *zip.ReadCloser, folderWithinZip string) int {
 	var count int
 
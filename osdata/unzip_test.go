@@ -0,0 +1,91 @@
+package osdata
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnzip(t *testing.T) {
+	tests := map[string]struct {
+		entries         map[string]string
+		folderWithinZip string
+		expected        map[string]string
+	}{
+		"shapefile folder": {
+			entries: map[string]string{
+				"OS VectorMap District (ESRI Shape File) SD/data/SD_Road.shp": "road",
+				"OS VectorMap District (ESRI Shape File) SD/doc/licence.txt":  "licence",
+			},
+			folderWithinZip: "OS VectorMap District (ESRI Shape File) SD/data/",
+			expected: map[string]string{
+				"SD_Road.shp": "road",
+			},
+		},
+		"geopackage subfolders": {
+			entries: map[string]string{
+				"Data/vmdvec_gb.gpkg": "gpkg",
+				"Doc/licence.txt":     "licence",
+			},
+			expected: map[string]string{
+				"Data/vmdvec_gb.gpkg": "gpkg",
+				"Doc/licence.txt":     "licence",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			zipFile := filepath.Join(dir, "tile.zip")
+			dst := filepath.Join(dir, "out")
+
+			writeZip(t, zipFile, tt.entries)
+
+			err := unzip(dst, zipFile, tt.folderWithinZip, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for file, content := range tt.expected {
+				b, err := os.ReadFile(filepath.Join(dst, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != content {
+					t.Errorf("%v: expected [%v], got [%v]", file, content, string(b))
+				}
+			}
+
+			if _, err := os.Stat(filepath.Join(dst, "licence.txt")); tt.folderWithinZip != "" && !os.IsNotExist(err) {
+				t.Errorf("a file outside of the folder was unzipped")
+			}
+		})
+	}
+}
+
+func writeZip(t *testing.T, zipFile string, entries map[string]string) {
+	f, err := os.Create(zipFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
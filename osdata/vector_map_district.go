@@ -16,32 +16,32 @@ const (
 	osDataAPI = "https://api.os.uk/downloads/v1/products/VectorMapDistrict/downloads"
 	zipDir    = "./resources/mapdata-source-files/zip"
 	shpDir    = "./resources/mapdata-source-files/shp"
+
+	FormatShapefile  = "shp"
+	FormatGeoPackage = "gpkg"
 )
 
-func DownloadVectorMapDistrict() error {
-	var funcName string = "osdata.DownloadVectorMapDistrict"
+// The OS Data Hub names for each of the source formats we can import
+var DownloadFormats = map[string]string{
+	FormatShapefile:  "ESRI® Shapefile",
+	FormatGeoPackage: "GeoPackage",
+}
 
-	var tiles []VectorMapDistrictTile
+// DownloadVectorMapDistrict downloads and unzips the tiles of a release for
+// the given areas
+func DownloadVectorMapDistrict(release Release, areas []string) error {
+	var funcName string = "osdata.DownloadVectorMapDistrict"
 
 	logger.Log(
 		logger.LVL_DEBUG,
 		"Starting the download process",
 	)
 
-	// Get the list of VectorMapDistrictTile (download list)
-	tiles, err := getDownloadList()
-	if err != nil {
-		return fmt.Errorf("%v: %v", funcName, err.Error())
-	}
-
 	// Filter the download list
-	tiles = filterDownloadList(tiles)
-	if err != nil {
-		return fmt.Errorf("%v: %v", funcName, err.Error())
-	}
+	tiles := filterDownloaded(release.TilesIn(areas))
 
 	// Make the necessary folders
-	err = prepFolders()
+	err := prepFolders()
 	if err != nil {
 		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
@@ -139,24 +139,25 @@ func doUnzipJob(tiles []VectorMapDistrictTile) error {
 	return nil
 }
 
-func getDownloadList() ([]VectorMapDistrictTile, error) {
+func getDownloadList(source string) ([]VectorMapDistrictTile, error) {
 	var funcName string = "osdata.getDownloadList"
 
 	var tiles = []VectorMapDistrictTile{}
+	var manifestURL string = getManifestURL(source)
 
 	logger.Log(
 		logger.LVL_DEBUG,
-		"Retrieving VectorMapDistrict tiles list",
+		fmt.Sprintf("Retrieving VectorMapDistrict tiles list [%v]", manifestURL),
 	)
 
-	resp, cancel, err := fileutils.Get(osDataAPI)
+	r, cancel, err := openURL(manifestURL)
 	defer cancel()
 	if err != nil {
-		return []VectorMapDistrictTile{}, fmt.Errorf("%v: no response from request to %v [%v]", funcName, osDataAPI, err.Error())
+		return []VectorMapDistrictTile{}, fmt.Errorf("%v: no response from request to %v [%v]", funcName, manifestURL, err.Error())
 	}
-	defer resp.Body.Close()
+	defer r.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(r)
 	if err != nil {
 		return []VectorMapDistrictTile{}, fmt.Errorf("%v: unable to read response body [%v]", funcName, err.Error())
 	}
@@ -165,6 +166,13 @@ func getDownloadList() ([]VectorMapDistrictTile, error) {
 		return []VectorMapDistrictTile{}, fmt.Errorf("%v: cannot unmarshal JSON [%v]", funcName, err.Error())
 	}
 
+	// Mirror manifests hold URLs relative to the mirror root
+	if source != "" {
+		for i := range tiles {
+			tiles[i].URL = resolveMirrorURL(source, tiles[i].URL)
+		}
+	}
+
 	logger.Log(
 		logger.LVL_INTERNAL,
 		fmt.Sprintf("VectorMapDistrictTiles %v\n", tiles),
@@ -173,23 +181,33 @@ func getDownloadList() ([]VectorMapDistrictTile, error) {
 	return tiles, nil
 }
 
-func filterDownloadList(downloadList []VectorMapDistrictTile) []VectorMapDistrictTile {
-	var funcName string = "osdata.filterDownloadList"
-
+func filterFormat(downloadList []VectorMapDistrictTile, format string) []VectorMapDistrictTile {
 	filteredDownloadList := []VectorMapDistrictTile{}
 
-	// Initial filter
 	for _, tile := range downloadList {
-		// We only want ESRI® Shapefiles, and not tile GB
-		if tile.Format == "ESRI® Shapefile" && tile.Area != "GB" {
-			filteredDownloadList = append(filteredDownloadList, tile)
+		if tile.Format != DownloadFormats[format] {
+			continue
+		}
+
+		// We want the ESRI® Shapefiles per tile, and not tile GB
+		if format == FormatShapefile && tile.Area == "GB" {
+			continue
 		}
+
+		filteredDownloadList = append(filteredDownloadList, tile)
 	}
 
+	return filteredDownloadList
+}
+
+// filterDownloaded removes the tiles we already hold a matching zip for
+func filterDownloaded(downloadList []VectorMapDistrictTile) []VectorMapDistrictTile {
+	var funcName string = "osdata.filterDownloaded"
+
 	tilesToDownload := []VectorMapDistrictTile{}
 
 	// Check for existing files and check md5 hash
-	for _, tile := range filteredDownloadList {
+	for _, tile := range downloadList {
 		out := fmt.Sprintf("%v/%v", zipDir, tile.FileName)
 
 		md5Hash, err := fileutils.GetMD5Hash(out)
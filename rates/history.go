@@ -0,0 +1,113 @@
+package rates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rockwell-uk/go-utils/fileutils"
+)
+
+const (
+	historyStateFile = "rates.json"
+)
+
+// LayerHistory is the work done importing a layer in past runs
+type LayerHistory struct {
+	Records  int
+	Rows     int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// RecordsPerSecond is the historical import rate of the layer
+func (l LayerHistory) RecordsPerSecond() float64 {
+	if l.Duration <= 0 {
+		return 0
+	}
+
+	return float64(l.Records) / l.Duration.Seconds()
+}
+
+// RowsPerRecord is how many rows a record generates across the grid squares
+func (l LayerHistory) RowsPerRecord() float64 {
+	if l.Records == 0 {
+		return 0
+	}
+
+	return float64(l.Rows) / float64(l.Records)
+}
+
+// History is the per layer import rates of past runs keyed by layer e.g. road
+type History map[string]LayerHistory
+
+// Add merges the work done importing a source of the layer
+func (h History) Add(layer string, bytes int64, rateInfo RateInfo) {
+	l := h[layer]
+
+	l.Records += rateInfo.Records
+	l.Bytes += bytes
+	l.Duration += rateInfo.Duration
+	for _, rows := range rateInfo.Rows {
+		l.Rows += rows
+	}
+
+	h[layer] = l
+}
+
+// LoadHistory returns the rates saved by past runs, or an empty history if
+// there have not been any
+func LoadHistory(stateDir string) (History, error) {
+	var funcName string = "rates.LoadHistory"
+
+	var history = make(History)
+
+	b, err := os.ReadFile(filepath.Join(stateDir, historyStateFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return history, nil
+		}
+		return history, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if err := json.Unmarshal(b, &history); err != nil {
+		return make(History), fmt.Errorf("%v: cannot unmarshal JSON [%v]", funcName, err.Error())
+	}
+
+	return history, nil
+}
+
+// SaveHistory replaces the saved rates of each layer in the history, the
+// latest run is the best guide to the next one
+func SaveHistory(stateDir string, history History) error {
+	var funcName string = "rates.SaveHistory"
+
+	saved, err := LoadHistory(stateDir)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	for layer, l := range history {
+		saved[layer] = l
+	}
+
+	err = fileutils.MkDir(stateDir)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	b, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	err = os.WriteFile(filepath.Join(stateDir, historyStateFile), b, 0o644)
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
@@ -8,11 +8,15 @@ import (
 	"github.com/rockwell-uk/go-utils/timeutils"
 )
 
+// RateInfo is the import of one source, Merged is set when the features
+// crossing tiles were merged so their rows are only counted against one tile
 type RateInfo struct {
 	ShapeFile string
+	Layer     string
 	Records   int
 	Rows      map[string]int
 	Duration  time.Duration
+	Merged    bool
 }
 
 func (r RateInfo) String() string {
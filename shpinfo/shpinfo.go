@@ -0,0 +1,243 @@
+package shpinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// ref: https://www.esri.com/content/dam/esrisites/sitecore-archive/Files/Pdfs/library/whitepapers/pdfs/shapefile.pdf
+
+const (
+	ExtShp = ".shp"
+	ExtShx = ".shx"
+	ExtDbf = ".dbf"
+	ExtPrj = ".prj"
+
+	fileCode       = 9994
+	fileVersion    = 1000
+	headerSize     = 100
+	shxRecordSize  = 8
+	dbfHeaderSize  = 32
+	dbfFieldSize   = 32
+	dbfTerminator  = 0x0d
+	dbfFieldNameSz = 11
+)
+
+// Header is the main file header shared by the .shp and .shx files
+type Header struct {
+	FileLength int64
+	ShapeType  int32
+	Bbox       [4]float64
+}
+
+// The names of the shape types in the .shp header
+var shapeTypeNames = map[int32]string{
+	0:  "Null",
+	1:  "Point",
+	3:  "PolyLine",
+	5:  "Polygon",
+	8:  "MultiPoint",
+	11: "PointZ",
+	13: "PolyLineZ",
+	15: "PolygonZ",
+	18: "MultiPointZ",
+	21: "PointM",
+	23: "PolyLineM",
+	25: "PolygonM",
+	28: "MultiPointM",
+	31: "MultiPatch",
+}
+
+// ShapeTypeName is the name of the shape type of a header e.g. Polygon
+func (h Header) ShapeTypeName() string {
+	if name, exists := shapeTypeNames[h.ShapeType]; exists {
+		return name
+	}
+
+	return fmt.Sprintf("Unknown(%v)", h.ShapeType)
+}
+
+// Offset is an index record from the .shx file, both values are in bytes
+type Offset struct {
+	Offset int64
+	Length int64
+}
+
+type Field struct {
+	Name     string
+	Type     byte
+	Length   int
+	Decimals int
+}
+
+type DBFHeader struct {
+	Records      int
+	HeaderLength int
+	RecordLength int
+	Fields       []Field
+}
+
+// SiblingPath returns the path of a shapefile component e.g. the .dbf for a .shp
+func SiblingPath(shapeFile, ext string) string {
+	return strings.TrimSuffix(shapeFile, ExtShp) + ext
+}
+
+func ReadHeader(path string) (Header, error) {
+	var funcName string = "shpinfo.ReadHeader"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer f.Close()
+
+	b := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return Header{}, fmt.Errorf("%v: short header in %v [%v]", funcName, path, err.Error())
+	}
+
+	return parseHeader(b)
+}
+
+func parseHeader(b []byte) (Header, error) {
+	var funcName string = "shpinfo.parseHeader"
+
+	if code := binary.BigEndian.Uint32(b[0:4]); code != fileCode {
+		return Header{}, fmt.Errorf("%v: invalid file code %v", funcName, code)
+	}
+
+	if version := binary.LittleEndian.Uint32(b[28:32]); version != fileVersion {
+		return Header{}, fmt.Errorf("%v: invalid version %v", funcName, version)
+	}
+
+	h := Header{
+		// the length is in 16 bit words
+		FileLength: int64(binary.BigEndian.Uint32(b[24:28])) * 2,
+		ShapeType:  int32(binary.LittleEndian.Uint32(b[32:36])),
+	}
+
+	for i := range h.Bbox {
+		h.Bbox[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[36+i*8 : 44+i*8]))
+	}
+
+	return h, nil
+}
+
+// ShxRecordCount derives the number of records from the .shx header alone
+func ShxRecordCount(shxFile string) (int, error) {
+	var funcName string = "shpinfo.ShxRecordCount"
+
+	h, err := ReadHeader(shxFile)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if (h.FileLength-headerSize)%shxRecordSize != 0 {
+		return 0, fmt.Errorf("%v: invalid index length %v", funcName, h.FileLength)
+	}
+
+	return int((h.FileLength - headerSize) / shxRecordSize), nil
+}
+
+// ReadShx returns the byte offset and content length of every record in the .shp
+func ReadShx(shxFile string) ([]Offset, error) {
+	var funcName string = "shpinfo.ReadShx"
+
+	b, err := os.ReadFile(shxFile)
+	if err != nil {
+		return []Offset{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if len(b) < headerSize {
+		return []Offset{}, fmt.Errorf("%v: short header in %v", funcName, shxFile)
+	}
+
+	if _, err := parseHeader(b[:headerSize]); err != nil {
+		return []Offset{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	var n int = (len(b) - headerSize) / shxRecordSize
+	var offsets = make([]Offset, n)
+
+	for i := 0; i < n; i++ {
+		r := b[headerSize+i*shxRecordSize:]
+		offsets[i] = Offset{
+			Offset: int64(binary.BigEndian.Uint32(r[0:4])) * 2,
+			Length: int64(binary.BigEndian.Uint32(r[4:8])) * 2,
+		}
+	}
+
+	return offsets, nil
+}
+
+func ReadDBFHeader(dbfFile string) (DBFHeader, error) {
+	var funcName string = "shpinfo.ReadDBFHeader"
+
+	f, err := os.Open(dbfFile)
+	if err != nil {
+		return DBFHeader{}, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+	defer f.Close()
+
+	b := make([]byte, dbfHeaderSize)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return DBFHeader{}, fmt.Errorf("%v: short header in %v [%v]", funcName, dbfFile, err.Error())
+	}
+
+	h := DBFHeader{
+		Records:      int(binary.LittleEndian.Uint32(b[4:8])),
+		HeaderLength: int(binary.LittleEndian.Uint16(b[8:10])),
+		RecordLength: int(binary.LittleEndian.Uint16(b[10:12])),
+	}
+
+	if h.HeaderLength < dbfHeaderSize+1 {
+		return DBFHeader{}, fmt.Errorf("%v: invalid header length %v", funcName, h.HeaderLength)
+	}
+
+	fields := make([]byte, h.HeaderLength-dbfHeaderSize)
+	if _, err := io.ReadFull(f, fields); err != nil {
+		return DBFHeader{}, fmt.Errorf("%v: short field descriptors in %v [%v]", funcName, dbfFile, err.Error())
+	}
+
+	for i := 0; i+dbfFieldSize <= len(fields) && fields[i] != dbfTerminator; i += dbfFieldSize {
+		d := fields[i : i+dbfFieldSize]
+		name := d[:dbfFieldNameSz]
+		if n := bytes.IndexByte(name, 0); n >= 0 {
+			name = name[:n]
+		}
+
+		h.Fields = append(h.Fields, Field{
+			Name:     strings.TrimSpace(string(name)),
+			Type:     d[11],
+			Length:   int(d[16]),
+			Decimals: int(d[17]),
+		})
+	}
+
+	return h, nil
+}
+
+func ReadPrj(prjFile string) (string, error) {
+	var funcName string = "shpinfo.ReadPrj"
+
+	b, err := os.ReadFile(prjFile)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// IsBritishNationalGrid reports whether a projection WKT describes EPSG:27700
+func IsBritishNationalGrid(prj string) bool {
+	var p string = strings.ToUpper(strings.ReplaceAll(prj, " ", "_"))
+
+	return strings.Contains(p, "BRITISH_NATIONAL_GRID") ||
+		strings.Contains(p, `AUTHORITY["EPSG",27700]`) ||
+		strings.Contains(p, `AUTHORITY["EPSG","27700"]`)
+}
@@ -0,0 +1,66 @@
+package shpinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testShapefile = "../importer/testdata/SD_MotorwayJunction.shp"
+
+func TestReadDBFHeader(t *testing.T) {
+	h, err := ReadDBFHeader(SiblingPath(testShapefile, ExtDbf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Field{
+		{Name: "ID", Type: 'C', Length: 38},
+		{Name: "JUNCTNUM", Type: 'C', Length: 10},
+		{Name: "FEATCODE", Type: 'N', Length: 5},
+	}
+
+	if h.Records != 64 {
+		t.Errorf("expected 64 records, got %v", h.Records)
+	}
+
+	if !reflect.DeepEqual(expected, h.Fields) {
+		t.Errorf("expected %v, got %v", expected, h.Fields)
+	}
+}
+
+func TestReadShx(t *testing.T) {
+	count, err := ShxRecordCount(SiblingPath(testShapefile, ExtShx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offsets, err := ReadShx(SiblingPath(testShapefile, ExtShx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 64 || len(offsets) != count {
+		t.Fatalf("expected 64 records, got %v and %v offsets", count, len(offsets))
+	}
+
+	// The first record follows the 100 byte header
+	if offsets[0].Offset != 100 {
+		t.Errorf("expected first offset 100, got %v", offsets[0].Offset)
+	}
+}
+
+func TestIsBritishNationalGrid(t *testing.T) {
+	tests := map[string]bool{
+		`PROJCS["British_National_Grid",GEOGCS["GCS_OSGB_1936"]]`: true,
+		`PROJCS["OSGB 1936 / British National Grid"]`:             true,
+		`PROJCS["unnamed",AUTHORITY["EPSG","27700"]]`:             true,
+		`GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984"]]`:              false,
+		`PROJCS["WGS_84_Pseudo_Mercator",AUTHORITY["EPSG",3857]]`: false,
+	}
+
+	for prj, expected := range tests {
+		if actual := IsBritishNationalGrid(prj); actual != expected {
+			t.Errorf("%v: expected %v, got %v", prj, expected, actual)
+		}
+	}
+}
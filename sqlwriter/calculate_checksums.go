@@ -6,6 +6,8 @@ import (
 	"github.com/rockwell-uk/go-progress/progress"
 )
 
+// CalculateChecksums logs the hash of each file as it is on disk, compressed
+// files are hashed compressed so the hashes match the archived files
 func CalculateChecksums(sqlFiles []string, logFile io.Writer) error {
 	var funcName string = "sqlwriter.CalculateChecksums"
 	var jobName string = "Calculating checksums"
@@ -0,0 +1,57 @@
+package sqlwriter
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// CompressedExt is added to the .sql files written by a Writer that
+// compresses, the files are gzip
+const CompressedExt = ".gz"
+
+// IsCompressed reports whether a .sql file was written compressed
+func IsCompressed(sqlFile string) bool {
+	return strings.HasSuffix(sqlFile, CompressedExt)
+}
+
+// OpenSQLFile opens a .sql file for reading, a compressed file is decompressed
+// as it is read, a file that was reopened by the writer holds several gzip
+// members which are read as one
+func OpenSQLFile(sqlFile string) (io.ReadCloser, error) {
+	var funcName string = "sqlwriter.OpenSQLFile"
+
+	f, err := os.Open(sqlFile)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if !IsCompressed(sqlFile) {
+		return f, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return &gzipFile{gz, f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Reader.Close()
+	if err != nil {
+		g.f.Close()
+		return err
+	}
+
+	return g.f.Close()
+}
@@ -0,0 +1,148 @@
+package sqlwriter
+
+import (
+	"fmt"
+	"strings"
+
+	"go-uk-maps-import/database/types"
+)
+
+// Dialect writes the insert statements of the .sql files in the SQL of a
+// database, a statement is the insert line followed by the rows separated by
+// commas and then the footer
+type Dialect interface {
+	Name() string
+
+	// Table is the name of a layer table in the statements
+	Table(layerType, table string) string
+
+	// Insert starts the statement of a table
+	Insert(table string, fields []string) string
+
+	// Row is a row of the statement with its geometry as WKB
+	Row(values []interface{}, wkb []byte, srid int) string
+
+	// Footer ends a statement after its last row
+	Footer() string
+
+	// Literal is a value as an SQL literal, strings are valid UTF-8 without
+	// NUL characters and numbers that SQL cannot hold are NULL
+	Literal(value interface{}) string
+}
+
+// GetDialect returns the dialect of a storage engine
+//
+//nolint:ireturn,nolintlint
+func GetDialect(name string) (Dialect, error) {
+	switch name {
+	case types.DialectMySQL:
+		return MySQL{}, nil
+	case types.DialectPostgres:
+		return Postgres{}, nil
+	case types.DialectSQLite:
+		return SQLite{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown SQL dialect %v", name)
+}
+
+// MySQL replaces existing rows
+type MySQL struct{}
+
+func (d MySQL) Name() string {
+	return types.DialectMySQL
+}
+
+func (d MySQL) Table(layerType, table string) string {
+	return fmt.Sprintf("%s.%s", layerType, table)
+}
+
+func (d MySQL) Insert(table string, fields []string) string {
+	return fmt.Sprintf(`REPLACE INTO %s (%vogc_geom) VALUES `, table, joinFields(fields))
+}
+
+func (d MySQL) Row(values []interface{}, wkb []byte, srid int) string {
+	return fmt.Sprintf(`(%vST_GeomFromWKB(%v))`, joinValues(d, values), d.Literal(wkb))
+}
+
+func (d MySQL) Footer() string {
+	return ";"
+}
+
+func (d MySQL) Literal(value interface{}) string {
+	return encodeLiteral(mysqlQuoter{}, value)
+}
+
+// Postgres keeps existing rows, as the storage engine does
+type Postgres struct{}
+
+func (d Postgres) Name() string {
+	return types.DialectPostgres
+}
+
+func (d Postgres) Table(layerType, table string) string {
+	return fmt.Sprintf("%s.%s", layerType, table)
+}
+
+func (d Postgres) Insert(table string, fields []string) string {
+	return fmt.Sprintf(`INSERT INTO %s (%vogc_geom) VALUES `, table, joinFields(fields))
+}
+
+func (d Postgres) Row(values []interface{}, wkb []byte, srid int) string {
+	return fmt.Sprintf(`(%vST_GeomFromWKB(%v, %v))`, joinValues(d, values), d.Literal(wkb), srid)
+}
+
+func (d Postgres) Footer() string {
+	return " ON CONFLICT (ID, GRIDREF) DO NOTHING;"
+}
+
+func (d Postgres) Literal(value interface{}) string {
+	return encodeLiteral(pgsqlQuoter{}, value)
+}
+
+// SQLite replaces existing rows, the tables are in the database of their
+// layer and the geometry needs SpatiaLite
+type SQLite struct{}
+
+func (d SQLite) Name() string {
+	return types.DialectSQLite
+}
+
+func (d SQLite) Table(layerType, table string) string {
+	return table
+}
+
+func (d SQLite) Insert(table string, fields []string) string {
+	return fmt.Sprintf(`INSERT OR REPLACE INTO %s (%vogc_geom) VALUES `, table, joinFields(fields))
+}
+
+func (d SQLite) Row(values []interface{}, wkb []byte, srid int) string {
+	return fmt.Sprintf(`(%vGeomFromWKB(%v, %v))`, joinValues(d, values), d.Literal(wkb), srid)
+}
+
+func (d SQLite) Footer() string {
+	return ";"
+}
+
+func (d SQLite) Literal(value interface{}) string {
+	return encodeLiteral(sqliteQuoter{}, value)
+}
+
+func joinFields(fields []string) string {
+	var s string
+	for _, field := range fields {
+		s += fmt.Sprintf("%v, ", field)
+	}
+
+	return s
+}
+
+func joinValues(d Dialect, values []interface{}) string {
+	var s strings.Builder
+	for _, value := range values {
+		s.WriteString(d.Literal(value))
+		s.WriteString(", ")
+	}
+
+	return s.String()
+}
@@ -0,0 +1,61 @@
+package sqlwriter
+
+import (
+	"testing"
+)
+
+func TestDialects(t *testing.T) {
+	var values = []interface{}{1, "Lord's Cricket Ground", nil, 2.5}
+	var wkb = []byte{1, 2}
+
+	tests := map[string]struct {
+		dialect string
+		insert  string
+		row     string
+		footer  string
+	}{
+		"mysql": {
+			dialect: "mysql",
+			insert:  "REPLACE INTO named_place.tq (GRIDREF, DISTNAME, HEIGHT, ORIENTATIO, ogc_geom) VALUES ",
+			row:     `(1, 'Lord''s Cricket Ground', NULL, 2.5, ST_GeomFromWKB(X'0102'))`,
+			footer:  ";",
+		},
+		"pgsql": {
+			dialect: "pgsql",
+			insert:  "INSERT INTO named_place.tq (GRIDREF, DISTNAME, HEIGHT, ORIENTATIO, ogc_geom) VALUES ",
+			row:     `(1, 'Lord''s Cricket Ground', NULL, 2.5, ST_GeomFromWKB(decode('0102', 'hex'), 27700))`,
+			footer:  " ON CONFLICT (ID, GRIDREF) DO NOTHING;",
+		},
+		"sqlite": {
+			dialect: "sqlite",
+			insert:  "INSERT OR REPLACE INTO tq (GRIDREF, DISTNAME, HEIGHT, ORIENTATIO, ogc_geom) VALUES ",
+			row:     `(1, 'Lord''s Cricket Ground', NULL, 2.5, GeomFromWKB(X'0102', 27700))`,
+			footer:  ";",
+		},
+	}
+
+	for name, tt := range tests {
+		d, err := GetDialect(tt.dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		insert := d.Insert(d.Table("named_place", "tq"), []string{"GRIDREF", "DISTNAME", "HEIGHT", "ORIENTATIO"})
+		if insert != tt.insert {
+			t.Errorf("%v: expected %q, got %q", name, tt.insert, insert)
+		}
+
+		row := d.Row(values, wkb, 27700)
+		if row != tt.row {
+			t.Errorf("%v: expected %q, got %q", name, tt.row, row)
+		}
+
+		if d.Footer() != tt.footer {
+			t.Errorf("%v: expected %q, got %q", name, tt.footer, d.Footer())
+		}
+	}
+
+	if _, err := GetDialect("oracle"); err == nil {
+		t.Error("expected an error for an unknown dialect")
+	}
+}
@@ -0,0 +1,153 @@
+package sqlwriter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// quoter writes the string and binary literals of a dialect, the other
+// literals are the same in each
+type quoter interface {
+	quote(s string) string
+	binary(b []byte) string
+	boolean(b bool) string
+}
+
+// encodeLiteral is a value as an SQL literal, values that SQL cannot hold
+// such as NaN are NULL and values of other types are written as strings
+func encodeLiteral(q quoter, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return q.quote(v)
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+		return q.binary(v)
+	case bool:
+		return q.boolean(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	}
+
+	return q.quote(fmt.Sprint(value))
+}
+
+// formatFloat never uses an exponent so the literal is read the same way by
+// every database
+func formatFloat(f float64, bitSize int) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "NULL"
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, bitSize)
+}
+
+// validUTF8 replaces invalid UTF-8 and, as the databases cannot hold them in
+// text, NUL characters with the replacement character
+func validUTF8(s string) string {
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+
+	return strings.ReplaceAll(s, "\x00", string(utf8.RuneError))
+}
+
+// The MySQL tables are utf8, which is three bytes, so characters outside the
+// basic multilingual plane are replaced, backslashes are escapes
+type mysqlQuoter struct{}
+
+var mysqlEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `''`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x1a", `\Z`,
+)
+
+func (q mysqlQuoter) quote(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r > 0xFFFF {
+			return utf8.RuneError
+		}
+		return r
+	}, validUTF8(s))
+
+	return "'" + mysqlEscaper.Replace(s) + "'"
+}
+
+func (q mysqlQuoter) binary(b []byte) string {
+	return "X'" + hex.EncodeToString(b) + "'"
+}
+
+func (q mysqlQuoter) boolean(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// Postgres strings are standard conforming, backslashes are not escapes
+type pgsqlQuoter struct{}
+
+func (q pgsqlQuoter) quote(s string) string {
+	return "'" + strings.ReplaceAll(validUTF8(s), "'", "''") + "'"
+}
+
+func (q pgsqlQuoter) binary(b []byte) string {
+	return "decode('" + hex.EncodeToString(b) + "', 'hex')"
+}
+
+func (q pgsqlQuoter) boolean(b bool) string {
+	if b {
+		return "TRUE"
+	}
+
+	return "FALSE"
+}
+
+// SQLite strings are standard, booleans are integers
+type sqliteQuoter struct{}
+
+func (q sqliteQuoter) quote(s string) string {
+	return "'" + strings.ReplaceAll(validUTF8(s), "'", "''") + "'"
+}
+
+func (q sqliteQuoter) binary(b []byte) string {
+	return "X'" + hex.EncodeToString(b) + "'"
+}
+
+func (q sqliteQuoter) boolean(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
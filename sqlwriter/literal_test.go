@@ -0,0 +1,246 @@
+package sqlwriter
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go-uk-maps-import/shpinfo"
+)
+
+// Names that have broken generated SQL
+var awkwardValues = []string{
+	"Lord's Cricket Ground",
+	`St. Mary\'s`,
+	`C:\Windows\`,
+	"Ynys Môn",
+	"Llanfair\u00adpwll",
+	"Dún Laoghaire",
+	"Quote '' twice",
+	"New\nline",
+	"Carriage\rreturn",
+	"Ctrl\x1aZ",
+	"Nul\x00byte",
+	"Invalid \xff\xfe UTF-8",
+	"Emoji \U0001F3F4\U000E0067\U000E0062\U000E0077\U000E006C\U000E0073\U000E007F",
+	"'); DROP TABLE road.sd; --",
+	"",
+}
+
+var dialects = map[string]Dialect{
+	"mysql":  MySQL{},
+	"pgsql":  Postgres{},
+	"sqlite": SQLite{},
+}
+
+func TestLiteralStrings(t *testing.T) {
+	var values = append(readDBFValues(t), awkwardValues...)
+
+	for name, d := range dialects {
+		for _, value := range values {
+			err := checkStringLiteral(d, value)
+			if err != nil {
+				t.Errorf("%v: %q %v", name, value, err)
+			}
+		}
+	}
+}
+
+func TestLiteralValues(t *testing.T) {
+	tests := map[string]struct {
+		value    interface{}
+		expected map[string]string
+	}{
+		"null": {
+			value:    nil,
+			expected: map[string]string{"mysql": "NULL", "pgsql": "NULL", "sqlite": "NULL"},
+		},
+		"integer": {
+			value:    int64(25710),
+			expected: map[string]string{"mysql": "25710", "pgsql": "25710", "sqlite": "25710"},
+		},
+		"large float": {
+			value:    1e21,
+			expected: map[string]string{"mysql": "1000000000000000000000", "pgsql": "1000000000000000000000", "sqlite": "1000000000000000000000"},
+		},
+		"nan": {
+			value:    math.NaN(),
+			expected: map[string]string{"mysql": "NULL", "pgsql": "NULL", "sqlite": "NULL"},
+		},
+		"boolean": {
+			value:    true,
+			expected: map[string]string{"mysql": "TRUE", "pgsql": "TRUE", "sqlite": "1"},
+		},
+		"binary": {
+			value:    []byte{0x01, 0x27, 0x5c},
+			expected: map[string]string{"mysql": "X'01275c'", "pgsql": "decode('01275c', 'hex')", "sqlite": "X'01275c'"},
+		},
+	}
+
+	for name, tt := range tests {
+		for dialect, expected := range tt.expected {
+			actual := dialects[dialect].Literal(tt.value)
+			if actual != expected {
+				t.Errorf("%v %v: expected %v, got %v", name, dialect, expected, actual)
+			}
+		}
+	}
+}
+
+func FuzzLiteral(f *testing.F) {
+	for _, value := range awkwardValues {
+		f.Add(value, 0.0)
+	}
+	f.Add("25201.000000000000000", 25201.0)
+	f.Add("District Or London Borough", -0.000001)
+
+	f.Fuzz(func(t *testing.T, s string, n float64) {
+		for name, d := range dialects {
+			err := checkStringLiteral(d, s)
+			if err != nil {
+				t.Errorf("%v: %q %v", name, s, err)
+			}
+
+			literal := d.Literal(n)
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				if literal != "NULL" {
+					t.Errorf("%v: expected NULL for %v, got %v", name, n, literal)
+				}
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(literal, 64)
+			if err != nil || parsed != n {
+				t.Errorf("%v: %v written as %v", name, n, literal)
+			}
+		}
+	})
+}
+
+// checkStringLiteral reads back the literal of a string as the database
+// would, it must be one literal holding the value the database can store
+func checkStringLiteral(d Dialect, value string) error {
+	literal := d.Literal(value)
+
+	if !utf8.ValidString(literal) || strings.ContainsRune(literal, 0) {
+		return fmt.Errorf("literal %q is not valid text", literal)
+	}
+
+	actual, err := unquote(d.Name(), literal)
+	if err != nil {
+		return err
+	}
+
+	expected := validUTF8(value)
+	if d.Name() == "mysql" {
+		expected = strings.Map(func(r rune) rune {
+			if r > 0xFFFF {
+				return utf8.RuneError
+			}
+			return r
+		}, expected)
+	}
+
+	if actual != expected {
+		return fmt.Errorf("read back as %q from %q", actual, literal)
+	}
+
+	return nil
+}
+
+func unquote(dialect, literal string) (string, error) {
+	if len(literal) < 2 || literal[0] != '\'' {
+		return "", fmt.Errorf("%q is not a string literal", literal)
+	}
+
+	var s strings.Builder
+	for i := 1; i < len(literal); i++ {
+		c := literal[i]
+
+		switch {
+		case c == '\'':
+			if i+1 < len(literal) && literal[i+1] == '\'' {
+				s.WriteByte('\'')
+				i++
+				continue
+			}
+			if i != len(literal)-1 {
+				return "", fmt.Errorf("%q ends at %v", literal, i)
+			}
+			return s.String(), nil
+
+		case c == '\\' && dialect == "mysql":
+			i++
+			if i == len(literal) {
+				return "", fmt.Errorf("%q ends with an escape", literal)
+			}
+			switch literal[i] {
+			case '0':
+				s.WriteByte(0)
+			case 'n':
+				s.WriteByte('\n')
+			case 'r':
+				s.WriteByte('\r')
+			case 't':
+				s.WriteByte('\t')
+			case 'b':
+				s.WriteByte('\b')
+			case 'Z':
+				s.WriteByte(0x1a)
+			default:
+				s.WriteByte(literal[i])
+			}
+
+		default:
+			s.WriteByte(c)
+		}
+	}
+
+	return "", fmt.Errorf("%q is not terminated", literal)
+}
+
+// readDBFValues reads the character fields of the test shapefiles
+func readDBFValues(t *testing.T) []string {
+	dbfFiles, err := filepath.Glob("../testdata/*.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	for _, dbfFile := range dbfFiles {
+		header, err := shpinfo.ReadDBFHeader(dbfFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := os.ReadFile(dbfFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for r := 0; r < header.Records; r++ {
+			// Each record starts with its deletion flag
+			offset := header.HeaderLength + r*header.RecordLength + 1
+			for _, field := range header.Fields {
+				if offset+field.Length > len(b) {
+					break
+				}
+				if field.Type == 'C' {
+					values = append(values, strings.TrimSpace(string(b[offset:offset+field.Length])))
+				}
+				offset += field.Length
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		t.Fatal("no DBF values read")
+	}
+
+	return values
+}
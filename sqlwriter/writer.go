@@ -1,12 +1,37 @@
 package sqlwriter
 
 import (
+	"bufio"
+	"compress/gzip"
+	"container/list"
 	"fmt"
-	"io"
+	"hash/fnv"
 	"log"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/rockwell-uk/go-utils/fileutils"
+	"github.com/rockwell-uk/go-utils/osutils"
+)
+
+const (
+	DefaultFolder        = "sql"
+	DefaultFlushInterval = 5 * time.Second
+
+	// Statements are kept well under the MySQL max_allowed_packet default
+	DefaultStatementRows  = 1000
+	DefaultStatementBytes = 1024 * 1024
+
+	// Used when the file handle limit is not known
+	defaultMaxOpenFiles = 256
+
+	// The share of the file handle limit the writer can use, the rest are
+	// left for the shapefiles being read
+	ulimitShare = 4
+
+	bufferSize = 64 * 1024
+	queueSize  = 1000
 )
 
 type SQLLine struct {
@@ -15,75 +40,554 @@ type SQLLine struct {
 	Line   string
 }
 
-var (
-	Folder = "sql"
-	done   chan struct{}
-	lines  chan SQLLine
-)
+// Writer appends rows to the .sql files in its folder as a series of insert
+// statements of at most StatementRows rows and StatementBytes bytes, each
+// ended in the SQL of its dialect as it is written, each layer is written by
+// one of its shards so the rows of a file stay in order, the files are
+// buffered and the least recently used are closed to stay under MaxOpenFiles,
+// with Compress the files are written as .sql.gz
+type Writer struct {
+	Folder         string
+	Dialect        Dialect
+	Compress       bool
+	Shards         int
+	MaxOpenFiles   int
+	FlushInterval  time.Duration
+	StatementRows  int
+	StatementBytes int
+
+	mu      sync.Mutex
+	headers map[string]bool
+	rows    map[string]int
+	err     error
+	wg      sync.WaitGroup
+
+	// Held to read the shards while queueing a line, Start and Stop replace
+	// them and Stop closes their queues
+	shardsMu sync.RWMutex
+	shards   []*shard
+}
+
+// shard owns the open files of the layers hashed to it
+type shard struct {
+	folder         string
+	compress       bool
+	footer         string
+	maxOpenFiles   int
+	statementRows  int
+	statementBytes int
+	lines          chan queuedLine
+	files          map[string]*list.Element
+	lru            *list.List
+	folders        map[string]bool
+	statements     map[string]*statement
+
+	// The first error of the shard, once it is set the rows are dropped
+	err     error
+	onError func(error)
+}
+
+type queuedLine struct {
+	SQLLine
+	header bool
+}
+
+// statement is the insert statement being written to a file
+type statement struct {
+	dbName string
+	table  string
+	header string
+	rows   int
+	bytes  int
+	total  int
+}
+
+type sqlFile struct {
+	path string
+	f    *os.File
+	gz   *gzip.Writer
+	w    *bufio.Writer
+}
+
+func New(folder string) *Writer {
+	if folder == "" {
+		folder = DefaultFolder
+	}
+
+	return &Writer{
+		Folder:  folder,
+		headers: make(map[string]bool),
+	}
+}
+
+func (w *Writer) Start() error {
+	var funcName string = "sqlwriter.Start"
 
-func Start() {
 	log.SetFlags(0)
 
-	done = make(chan struct{})
-	lines = make(chan SQLLine, 1000)
+	err := w.prepOutputFolder()
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	if w.Shards <= 0 {
+		w.Shards = 1
+	}
+	if w.MaxOpenFiles <= 0 {
+		w.MaxOpenFiles = getMaxOpenFiles()
+	}
+	if w.FlushInterval <= 0 {
+		w.FlushInterval = DefaultFlushInterval
+	}
+	if w.StatementRows <= 0 {
+		w.StatementRows = DefaultStatementRows
+	}
+	if w.StatementBytes <= 0 {
+		w.StatementBytes = DefaultStatementBytes
+	}
+	if w.Dialect == nil {
+		w.Dialect = MySQL{}
+	}
+
+	var maxOpenFiles int = w.MaxOpenFiles / w.Shards
+	if maxOpenFiles < 1 {
+		maxOpenFiles = 1
+	}
+
+	w.mu.Lock()
+	w.headers = make(map[string]bool)
+	w.rows = make(map[string]int)
+	w.err = nil
+	w.mu.Unlock()
+
+	var shards = make([]*shard, w.Shards)
+
+	for n := range shards {
+		s := &shard{
+			folder:         w.Folder,
+			compress:       w.Compress,
+			footer:         w.Dialect.Footer(),
+			maxOpenFiles:   maxOpenFiles,
+			statementRows:  w.StatementRows,
+			statementBytes: w.StatementBytes,
+			lines:          make(chan queuedLine, queueSize),
+			files:          make(map[string]*list.Element),
+			lru:            list.New(),
+			folders:        make(map[string]bool),
+			statements:     make(map[string]*statement),
+			onError:        w.setError,
+		}
+		shards[n] = s
+
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			s.monitorLoop(w.FlushInterval)
+		}()
+	}
+
+	w.shardsMu.Lock()
+	w.shards = shards
+	w.shardsMu.Unlock()
+
+	return nil
+}
+
+// Stop waits for the queued rows to be written, ends the open statements and
+// closes the files, it returns the first error of the shards, it is safe to
+// call more than once
+func (w *Writer) Stop() error {
+	var funcName string = "sqlwriter.Stop"
+
+	// No line is being queued once the lock is held
+	w.shardsMu.Lock()
+	shards := w.shards
+	w.shards = nil
+	for _, s := range shards {
+		close(s.lines)
+	}
+	w.shardsMu.Unlock()
+
+	if shards == nil {
+		return w.Err()
+	}
+
+	w.wg.Wait()
+
+	w.mu.Lock()
+	for _, s := range shards {
+		for path, st := range s.statements {
+			w.rows[path] = st.total
+		}
+	}
+	err := w.err
+	w.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("%v: %v", funcName, err.Error())
+	}
+
+	return nil
+}
+
+// Err returns the first error of the shards, the rows queued after it are
+// not written
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+func (w *Writer) setError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// RowCounts returns the rows written to each file by path, once the writer
+// is stopped
+func (w *Writer) RowCounts() map[string]int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var rows = make(map[string]int, len(w.rows))
+	for path, n := range w.rows {
+		rows[path] = n
+	}
+
+	return rows
+}
+
+// Write writes a row of a file, the header of the file must have been written
+func (w *Writer) Write(l SQLLine) {
+	w.queue(queuedLine{SQLLine: l})
+}
+
+// WriteHeader sets the insert line that starts each statement of a file,
+// subsequent calls for the same file are ignored, it returns true if the
+// header was set
+func (w *Writer) WriteHeader(l SQLLine) bool {
+	var key string = getSQLFilePath(w.Folder, l.DBName, l.Table, w.Compress)
+
+	// Held while queueing so rows cannot be queued before the header
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.headers[key] {
+		return false
+	}
+	w.headers[key] = true
+
+	w.queue(queuedLine{SQLLine: l, header: true})
+
+	return true
+}
+
+// queue sends a line to the shard of its layer, lines written when the
+// writer is not started are dropped
+func (w *Writer) queue(l queuedLine) {
+	w.shardsMu.RLock()
+	defer w.shardsMu.RUnlock()
+
+	if len(w.shards) == 0 {
+		return
+	}
+
+	w.getShard(l.DBName).lines <- l
+}
+
+// getShard must be called with the shards lock held
+func (w *Writer) getShard(dbName string) *shard {
+	if len(w.shards) == 1 {
+		return w.shards[0]
+	}
 
-	prepOutputFolder()
+	h := fnv.New32a()
+	h.Write([]byte(dbName))
 
-	go monitorLoop()
+	return w.shards[h.Sum32()%uint32(len(w.shards))]
 }
 
-func Stop() {
-	close(lines)
-	<-done
+func (w *Writer) prepOutputFolder() error {
+	err := fileutils.MkDir(w.Folder)
+	if err != nil {
+		return err
+	}
+
+	return fileutils.EmptyFolder(w.Folder)
+}
+
+// monitorLoop writes the rows of the shard, flushing the open files every
+// interval so the files on disk are never far behind, after an error the
+// queue is drained so the rows being queued are not blocked
+func (s *shard) monitorLoop(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case l, ok := <-s.lines:
+			if !ok {
+				if s.err == nil {
+					if err := s.endAll(); err != nil {
+						s.fail(err)
+					}
+				}
+				if err := s.closeAll(); err != nil {
+					s.fail(err)
+				}
+				return
+			}
+
+			if s.err != nil {
+				continue
+			}
+
+			path := getSQLFilePath(s.folder, l.DBName, l.Table, s.compress)
+			if l.header {
+				s.statements[path] = &statement{dbName: l.DBName, table: l.Table, header: l.Line}
+				continue
+			}
+
+			if err := s.writeRow(path, l.SQLLine); err != nil {
+				s.fail(err)
+			}
+
+		case <-ticker.C:
+			if s.err != nil {
+				continue
+			}
+
+			if err := s.flushAll(); err != nil {
+				s.fail(err)
+			}
+		}
+	}
 }
 
-func Write(l SQLLine) {
-	lines <- l
+// fail records the first error of the shard and closes its files
+func (s *shard) fail(err error) {
+	if s.err != nil {
+		return
+	}
+
+	s.err = err
+	s.onError(err)
+	s.closeAll()
 }
 
-func prepOutputFolder() {
-	err := fileutils.MkDir(Folder)
+// writeRow adds a row to the statement of its file, starting a new statement
+// when the row would take the statement over its size
+func (s *shard) writeRow(path string, l SQLLine) error {
+	var funcName string = "sqlwriter.writeRow"
+
+	st, exists := s.statements[path]
+	if !exists {
+		return fmt.Errorf("%v: no header for %v", funcName, path)
+	}
+
+	f, err := s.getSQLFile(l.DBName, l.Table)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	err = fileutils.EmptyFolder(Folder)
+	if st.rows > 0 && st.bytes+len(l.Line) > s.statementBytes {
+		err := s.end(f, st)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	var prefix string = ",\n"
+	if st.rows == 0 {
+		prefix = st.header + "\n"
+	}
+
+	_, err = f.w.WriteString(prefix + l.Line)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("%v: %v", funcName, err.Error())
 	}
+
+	st.rows++
+	st.total++
+	st.bytes += len(prefix) + len(l.Line)
+
+	if st.rows >= s.statementRows {
+		err := s.end(f, st)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	return nil
 }
 
-func monitorLoop() {
-	for l := range lines {
-		f, _ := getSQLFile(l.DBName, l.Table)
-		if _, err := io.WriteString(f, l.Line+"\n"); err != nil {
-			panic(err)
+// end terminates the statement of a file
+func (s *shard) end(f *sqlFile, st *statement) error {
+	_, err := f.w.WriteString(s.footer + "\n")
+
+	st.rows = 0
+	st.bytes = 0
+
+	return err
+}
+
+// endAll terminates the statements still open when the writer stops
+func (s *shard) endAll() error {
+	var funcName string = "sqlwriter.endAll"
+
+	for _, st := range s.statements {
+		if st.rows == 0 {
+			continue
+		}
+
+		f, err := s.getSQLFile(st.dbName, st.table)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+
+		err = s.end(f, st)
+		if err != nil {
+			return fmt.Errorf("%v: %v", funcName, err.Error())
 		}
-		f.Close()
 	}
 
-	close(done)
+	return nil
 }
 
-func getSQLFile(dbName, tableName string) (*os.File, error) {
+// getSQLFile returns the open file of a table, opening it for appending and
+// closing the least recently used file if the shard is at its limit
+func (s *shard) getSQLFile(dbName, tableName string) (*sqlFile, error) {
 	var funcName string = "sqlwriter.getSQLFile"
 
-	folderPath := fmt.Sprintf("%s/%s", Folder, dbName)
-	err := fileutils.MkDir(folderPath)
-	if err != nil {
-		panic(err)
+	path := getSQLFilePath(s.folder, dbName, tableName, s.compress)
+
+	if e, exists := s.files[path]; exists {
+		s.lru.MoveToFront(e)
+		return e.Value.(*sqlFile), nil
 	}
 
-	sqlFile := getSQLFilePath(dbName, tableName)
-	f, err := fileutils.GetFile(sqlFile)
+	if !s.folders[dbName] {
+		folderPath := fmt.Sprintf("%s/%s", s.folder, dbName)
+		err := fileutils.MkDir(folderPath)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+		s.folders[dbName] = true
+	}
+
+	if s.lru.Len() >= s.maxOpenFiles {
+		err := s.close(s.lru.Back())
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", funcName, err.Error())
+		}
+	}
+
+	f, err := fileutils.GetFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("%v: %v", funcName, err.Error())
 	}
 
-	return f, nil
+	file := &sqlFile{
+		path: path,
+		f:    f,
+	}
+
+	// A reopened file is appended to as a new gzip member
+	if s.compress {
+		file.gz = gzip.NewWriter(f)
+		file.w = bufio.NewWriterSize(file.gz, bufferSize)
+	} else {
+		file.w = bufio.NewWriterSize(f, bufferSize)
+	}
+	s.files[path] = s.lru.PushFront(file)
+
+	return file, nil
+}
+
+func (s *shard) close(e *list.Element) error {
+	file := e.Value.(*sqlFile)
+
+	s.lru.Remove(e)
+	delete(s.files, file.path)
+
+	err := file.w.Flush()
+	if err == nil && file.gz != nil {
+		err = file.gz.Close()
+	}
+	if err != nil {
+		file.f.Close()
+		return err
+	}
+
+	return file.f.Close()
+}
+
+// flush writes the buffered rows through to the file
+func (f *sqlFile) flush() error {
+	err := f.w.Flush()
+	if err != nil || f.gz == nil {
+		return err
+	}
+
+	return f.gz.Flush()
+}
+
+// flushAll writes the buffered rows of the open files through
+func (s *shard) flushAll() error {
+	var funcName string = "sqlwriter.flushAll"
+
+	for e := s.lru.Front(); e != nil; e = e.Next() {
+		err := e.Value.(*sqlFile).flush()
+		if err != nil {
+			return fmt.Errorf("%v: %v %v", funcName, e.Value.(*sqlFile).path, err.Error())
+		}
+	}
+
+	return nil
 }
 
-func getSQLFilePath(dbName, fileName string) string {
-	fPath := fmt.Sprintf("%s/%s", Folder, dbName)
+// closeAll closes every open file, it returns the first error
+func (s *shard) closeAll() error {
+	var funcName string = "sqlwriter.closeAll"
+
+	var first error
+	for s.lru.Len() > 0 {
+		path := s.lru.Back().Value.(*sqlFile).path
+
+		err := s.close(s.lru.Back())
+		if err != nil && first == nil {
+			first = fmt.Errorf("%v: %v %v", funcName, path, err.Error())
+		}
+	}
+
+	return first
+}
+
+// getMaxOpenFiles is the share of the file handle limit the writer can use
+func getMaxOpenFiles() int {
+	ulimit, err := osutils.GetULimit()
+	if err != nil || ulimit <= 0 {
+		return defaultMaxOpenFiles
+	}
+
+	if ulimit/ulimitShare < 1 {
+		return 1
+	}
+
+	return ulimit / ulimitShare
+}
+
+func getSQLFilePath(folder, dbName, fileName string, compress bool) string {
+	fPath := fmt.Sprintf("%s/%s", folder, dbName)
+
+	if compress {
+		return fmt.Sprintf("%s/%s.sql%s", fPath, fileName, CompressedExt)
+	}
+
 	return fmt.Sprintf("%s/%s.sql", fPath, fileName)
 }
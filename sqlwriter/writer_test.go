@@ -0,0 +1,248 @@
+package sqlwriter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriterHeader(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "sql")
+
+	w := New(folder)
+	err := w.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range []string{"(1)", "(2)"} {
+		w.WriteHeader(SQLLine{DBName: "road", Table: "sd01", Line: "REPLACE INTO road.sd VALUES "})
+		w.Write(SQLLine{DBName: "road", Table: "sd01", Line: row})
+	}
+	for n := 0; n < 2; n++ {
+		err = w.Stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.ReadFile(filepath.Join(folder, "road", "sd01.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "REPLACE INTO road.sd VALUES \n(1),\n(2);\n"
+	if expected != string(f) {
+		t.Fatalf("expected %q\nactual %q", expected, f)
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "sql")
+
+	w := New(folder)
+	err := w.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file in place of the folder of the layer
+	err = os.WriteFile(filepath.Join(folder, "road"), nil, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More rows than the queue holds, those after the error are dropped
+	w.WriteHeader(SQLLine{DBName: "road", Table: "sd01", Line: "REPLACE INTO road.sd VALUES "})
+	for row := 0; row < 2*queueSize; row++ {
+		w.Write(SQLLine{DBName: "road", Table: "sd01", Line: "(1)"})
+	}
+
+	for n := 0; n < 2; n++ {
+		err = w.Stop()
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+}
+
+func TestWriterOpenFiles(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "sql")
+
+	w := New(folder)
+	w.Shards = 2
+	w.MaxOpenFiles = 2
+
+	err := w.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More files than handles so files are closed and reopened
+	layers := []string{"road", "building", "woodland"}
+	tables := []string{"sd01", "se01", "tq01"}
+
+	for _, row := range []string{"(1)", "(2)", "(3)"} {
+		for _, layer := range layers {
+			for _, table := range tables {
+				w.WriteHeader(SQLLine{DBName: layer, Table: table, Line: "INSERT"})
+				w.Write(SQLLine{DBName: layer, Table: table, Line: row})
+			}
+		}
+	}
+
+	err = w.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, layer := range layers {
+		for _, table := range tables {
+			f, err := os.ReadFile(filepath.Join(folder, layer, table+".sql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "INSERT\n(1),\n(2),\n(3);\n"
+			if expected != string(f) {
+				t.Errorf("%v/%v: expected %q\nactual %q", layer, table, expected, f)
+			}
+		}
+	}
+}
+
+func TestWriterStatements(t *testing.T) {
+	tests := map[string]struct {
+		rows     int
+		bytes    int
+		expected string
+	}{
+		"one statement": {
+			expected: "INSERT\n(1),\n(2),\n(3),\n(4);\n",
+		},
+		"split by rows": {
+			rows:     3,
+			expected: "INSERT\n(1),\n(2),\n(3);\nINSERT\n(4);\n",
+		},
+		"split by bytes": {
+			bytes:    16,
+			expected: "INSERT\n(1),\n(2);\nINSERT\n(3),\n(4);\n",
+		},
+		"row larger than bytes": {
+			bytes:    1,
+			expected: "INSERT\n(1);\nINSERT\n(2);\nINSERT\n(3);\nINSERT\n(4);\n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			folder := filepath.Join(t.TempDir(), "sql")
+
+			w := New(folder)
+			w.StatementRows = tt.rows
+			w.StatementBytes = tt.bytes
+
+			err := w.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w.WriteHeader(SQLLine{DBName: "road", Table: "sd01", Line: "INSERT"})
+			for _, row := range []string{"(1)", "(2)", "(3)", "(4)"} {
+				w.Write(SQLLine{DBName: "road", Table: "sd01", Line: row})
+			}
+			err = w.Stop()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.ReadFile(filepath.Join(folder, "road", "sd01.sql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.expected != string(f) {
+				t.Errorf("expected %q\nactual %q", tt.expected, f)
+			}
+		})
+	}
+}
+
+func TestWriterCompress(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "sql")
+
+	w := New(folder)
+	w.Compress = true
+	w.MaxOpenFiles = 1
+
+	err := w.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One handle so each file is reopened as several gzip members
+	tables := []string{"sd01", "se01"}
+
+	for _, row := range []string{"(1)", "(2)", "(3)"} {
+		for _, table := range tables {
+			w.WriteHeader(SQLLine{DBName: "road", Table: table, Line: "INSERT"})
+			w.Write(SQLLine{DBName: "road", Table: table, Line: row})
+		}
+	}
+	err = w.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range tables {
+		r, err := OpenSQLFile(filepath.Join(folder, "road", table+".sql.gz"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "INSERT\n(1),\n(2),\n(3);\n"
+		if expected != string(f) {
+			t.Errorf("%v: expected %q\nactual %q", table, expected, f)
+		}
+	}
+}
+
+func TestWriterConcurrentStop(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "sql")
+
+	w := New(folder)
+	w.Shards = 4
+
+	for n := 0; n < 2; n++ {
+		err := w.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for _, dbName := range []string{"road", "building", "woodland", "foreshore"} {
+			wg.Add(1)
+			go func(dbName string) {
+				defer wg.Done()
+				w.WriteHeader(SQLLine{DBName: dbName, Table: "sd01", Line: "REPLACE INTO " + dbName + ".sd VALUES "})
+				for row := 0; row < 1000; row++ {
+					w.Write(SQLLine{DBName: dbName, Table: "sd01", Line: "(1)"})
+				}
+			}(dbName)
+		}
+
+		err = w.Stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+	}
+}